@@ -1,12 +1,22 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	// Embed the time zone database so TZ and -timezone also work without one on the host
+	_ "time/tzdata"
 
 	"github.com/go-logr/zapr"
 	"github.com/runningman84/zfs-snapshot-operator/pkg/config"
 	"github.com/runningman84/zfs-snapshot-operator/pkg/operator"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/zfs"
 	"go.uber.org/zap"
 	"k8s.io/klog/v2"
 )
@@ -20,11 +30,25 @@ func main() {
 	klog.InitFlags(nil)
 
 	// Parse command line flags
-	mode := flag.String("mode", "direct", "Operation mode: test, direct, or chroot")
+	mode := flag.String("mode", "direct", "Operation mode: test, simulate, direct, or chroot")
 	logLevel := flag.String("log-level", "info", "Log level: info or debug")
 	logFormat := flag.String("log-format", "text", "Log format: text or json")
 	dryRun := flag.Bool("dry-run", false, "Enable dry-run mode (no actual snapshot creation or deletion)")
 	showVersion := flag.Bool("version", false, "Show version and exit")
+	configFile := flag.String("config", "", "Path to a YAML/JSON policy file with per-pool and per-dataset retention")
+	explain := flag.Bool("explain", false, "Print the effective settings of every dataset and where they came from, then exit")
+	report := flag.Bool("report", false, "Print the space used and reclaimable per dataset and tier, then exit")
+	hold := flag.String("hold", "", "Place an operator-owned hold on a snapshot (dataset@snapshot) so retention keeps it, then exit")
+	release := flag.String("release", "", "Release an operator-owned hold from a snapshot (dataset@snapshot), then exit")
+	holdName := flag.String("hold-name", "manual", "Name of the hold placed with -hold or released with -release, e.g. replication")
+	check := flag.Bool("check", false, "Only check snapshot freshness, print a JSON report and exit non-zero on violations")
+	daemon := flag.Bool("daemon", false, "Keep running and trigger a run at every period boundary of -interval")
+	metricsAddr := flag.String("metrics-addr", "", "Listen address for the /metrics endpoint in daemon mode, e.g. :9090 (overrides METRICS_ADDR)")
+	interval := flag.String("interval", "", "Daemon schedule as a snapshot frequency or custom tier: frequently, hourly, daily, ... (default: shortest frequency kept by any managed dataset)")
+	simulateRuns := flag.Int("simulate-runs", 24*7, "Number of runs to replay in simulate mode")
+	simulateInterval := flag.Duration("simulate-interval", time.Hour, "Virtual time between runs in simulate mode")
+	timezone := flag.String("timezone", "", "IANA time zone for naming, parsing and bucketing snapshots, e.g. Europe/Berlin (overrides TZ)")
+	simulateStart := flag.String("simulate-start", "", "Virtual start time in RFC3339 format for simulate mode (default: now)")
 	flag.Parse()
 
 	// Show version if requested
@@ -34,8 +58,8 @@ func main() {
 	}
 
 	// Validate mode
-	if *mode != "test" && *mode != "direct" && *mode != "chroot" {
-		klog.Fatalf("Invalid mode: %s. Must be one of: test, direct, chroot", *mode)
+	if *mode != "test" && *mode != "simulate" && *mode != "direct" && *mode != "chroot" {
+		klog.Fatalf("Invalid mode: %s. Must be one of: test, simulate, direct, chroot", *mode)
 	}
 
 	// Validate log level
@@ -71,22 +95,205 @@ func main() {
 	cfg := config.NewConfig(*mode)
 	cfg.LogLevel = *logLevel
 
+	if err := cfg.ValidatePatterns(); err != nil {
+		klog.Fatalf("Invalid configuration: %v", err)
+	}
+	if err := cfg.ValidateSnapshotTimeSource(); err != nil {
+		klog.Fatalf("Invalid configuration: %v", err)
+	}
+	if err := cfg.ValidateAdoptSnapshots(); err != nil {
+		klog.Fatalf("Invalid configuration: %v", err)
+	}
+	if err := cfg.ValidateSpacePressure(); err != nil {
+		klog.Fatalf("Invalid configuration: %v", err)
+	}
+	if err := cfg.ValidateHolds(); err != nil {
+		klog.Fatalf("Invalid configuration: %v", err)
+	}
+
+	tz := cfg.Timezone
+	if *timezone != "" {
+		tz = *timezone
+	}
+	if err := cfg.SetTimezone(tz); err != nil {
+		klog.Fatalf("Invalid configuration: %v", err)
+	}
+	klog.Infof("Using time zone %s", cfg.Location())
+
+	// Load the policy file; env vars keep precedence over it
+	if *configFile != "" {
+		if err := cfg.LoadPolicyFile(*configFile); err != nil {
+			klog.Fatalf("Failed to load policy file: %v", err)
+		}
+	}
+
+	// Settings that depend on the tiers are validated once custom tiers are known
+	if err := cfg.CompileNameTemplate(); err != nil {
+		klog.Fatalf("Invalid configuration: %v", err)
+	}
+	if err := cfg.ValidateRetentionModes(); err != nil {
+		klog.Fatalf("Invalid configuration: %v", err)
+	}
+	if err := cfg.ValidateSkipUnchanged(); err != nil {
+		klog.Fatalf("Invalid configuration: %v", err)
+	}
+
 	// Set klog verbosity based on log level
 	if *logLevel == "debug" {
 		flag.Set("v", "1")
 	}
 
+	if *metricsAddr != "" {
+		cfg.MetricsAddr = *metricsAddr
+	}
+
 	// Override DryRun if specified via flag
 	if *dryRun {
 		cfg.DryRun = true
 		klog.Infof("Dry-run mode enabled via command-line flag")
 	}
 
+	if *mode == "simulate" {
+		runSimulation(cfg, *simulateRuns, *simulateInterval, *simulateStart)
+		klog.Flush()
+		return
+	}
+
 	// Create and run operator
 	op := operator.NewOperator(cfg)
-	if err := op.Run(); err != nil {
-		klog.Fatalf("Operator failed: %v", err)
+
+	if *explain {
+		if err := op.Explain(os.Stdout); err != nil {
+			klog.Fatalf("Failed to explain settings: %v", err)
+		}
+		klog.Flush()
+		return
+	}
+
+	if *report {
+		if err := op.SpaceReport(os.Stdout); err != nil {
+			klog.Fatalf("Failed to write space report: %v", err)
+		}
+		klog.Flush()
+		return
+	}
+
+	if *hold != "" || *release != "" {
+		runHoldCommand(op, *hold, *release, *holdName)
+		return
+	}
+
+	if *check {
+		runFreshnessCheck(op)
+		return
+	}
+
+	if *daemon {
+		// Stop gracefully on SIGTERM (e.g., pod termination) or SIGINT
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+		defer stop()
+
+		if cfg.MetricsAddr != "" {
+			serveMetrics(ctx, cfg.MetricsAddr, op)
+		}
+
+		if err := op.RunDaemon(ctx, *interval); err != nil {
+			klog.Fatalf("Daemon failed: %v", err)
+		}
+		klog.Flush()
+		return
+	}
+
+	runErr := op.Run()
+	if err := op.ExportMetrics(); err != nil {
+		klog.Infof("Failed to export metrics: %v", err)
+	}
+	if runErr != nil {
+		klog.Fatalf("Operator failed: %v", runErr)
 	}
 
 	klog.Flush()
 }
+
+// runSimulation replays runs against an in-memory backend seeded from the test files
+func runSimulation(cfg *config.Config, runs int, interval time.Duration, start string) {
+	startTime := time.Now()
+	if start != "" {
+		parsed, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			klog.Fatalf("Invalid simulate start time %q: %v", start, err)
+		}
+		startTime = parsed
+	}
+
+	// Simulated runs never touch the real system, so they must not contend for the lock
+	cfg.EnableLocking = false
+
+	backend, err := zfs.NewSimulatedBackendFrom(zfs.NewManager(cfg))
+	if err != nil {
+		klog.Fatalf("Failed to seed simulated backend: %v", err)
+	}
+
+	klog.Infof("Simulating %d run(s) every %s starting at %s", runs, interval, startTime.Format(time.RFC3339))
+	if err := operator.Simulate(cfg, backend, startTime, interval, runs, os.Stdout); err != nil {
+		klog.Fatalf("Simulation failed: %v", err)
+	}
+}
+
+// serveMetrics starts the /metrics HTTP endpoint and shuts it down when ctx is cancelled
+func serveMetrics(ctx context.Context, addr string, op *operator.Operator) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", op.Metrics().Handler())
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		klog.Infof("Serving metrics on %s/metrics", addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			klog.Fatalf("Metrics server failed: %v", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+}
+
+// runHoldCommand places or releases an operator-owned hold and exits non-zero on failure
+func runHoldCommand(op *operator.Operator, hold, release, name string) {
+	if hold != "" && release != "" {
+		klog.Fatalf("-hold and -release cannot be used together")
+	}
+
+	var err error
+	if hold != "" {
+		err = op.Hold(hold, name)
+	} else {
+		err = op.Release(release, name)
+	}
+	if err != nil {
+		klog.Fatalf("Hold command failed: %v", err)
+	}
+	klog.Flush()
+}
+
+// runFreshnessCheck prints the freshness report as JSON and exits non-zero if any tier has fallen behind
+func runFreshnessCheck(op *operator.Operator) {
+	report, err := op.CheckFreshness(time.Now())
+	if err != nil {
+		klog.Fatalf("Freshness check failed: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		klog.Fatalf("Failed to write freshness report: %v", err)
+	}
+
+	klog.Flush()
+	if !report.OK {
+		os.Exit(1)
+	}
+}
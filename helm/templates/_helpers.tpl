@@ -60,3 +60,198 @@ Create the name of the service account to use
 {{- default "default" .Values.serviceAccount.name }}
 {{- end }}
 {{- end }}
+
+{{/*
+Arguments of the operator container shared by the CronJob and the Deployment
+*/}}
+{{- define "zfs-snapshot-operator.args" -}}
+- "-mode"
+- {{ .Values.operator.mode | quote }}
+- "-log-level"
+- {{ .Values.operator.logLevel | quote }}
+{{- if .Values.policy }}
+- "-config"
+- "/etc/zfs-snapshot-operator/policy.yaml"
+{{- end }}
+{{- end }}
+
+{{/*
+Environment of the operator container shared by the CronJob and the Deployment
+*/}}
+{{- define "zfs-snapshot-operator.env" -}}
+- name: LOG_LEVEL
+  value: {{ .Values.operator.logLevel | quote }}
+- name: DRY_RUN
+  value: {{ .Values.operator.dryRun | quote }}
+- name: MAX_DELETIONS_PER_RUN
+  value: {{ .Values.operator.maxDeletionsPerRun | quote }}
+- name: DEFER_DESTROY
+  value: {{ .Values.operator.deferDestroy | default false | quote }}
+{{- if .Values.operator.holdMaxAge }}
+- name: HOLD_MAX_AGE
+  value: {{ .Values.operator.holdMaxAge | quote }}
+{{- end }}
+{{- if .Values.operator.holdTagPrefix }}
+- name: HOLD_TAG_PREFIX
+  value: {{ .Values.operator.holdTagPrefix | quote }}
+{{- end }}
+- name: ENABLE_LOCKING
+  value: {{ .Values.operator.enableLocking | quote }}
+- name: LOCK_FILE_PATH
+  value: {{ .Values.operator.lockFilePath | quote }}
+- name: MAX_FREQUENTLY_SNAPSHOTS
+  value: {{ .Values.snapshots.maxFrequently | quote }}
+- name: MAX_HOURLY_SNAPSHOTS
+  value: {{ .Values.snapshots.maxHourly | quote }}
+- name: MAX_DAILY_SNAPSHOTS
+  value: {{ .Values.snapshots.maxDaily | quote }}
+- name: MAX_WEEKLY_SNAPSHOTS
+  value: {{ .Values.snapshots.maxWeekly | quote }}
+- name: MAX_MONTHLY_SNAPSHOTS
+  value: {{ .Values.snapshots.maxMonthly | quote }}
+- name: MAX_YEARLY_SNAPSHOTS
+  value: {{ .Values.snapshots.maxYearly | quote }}
+- name: MIN_KEEP_SNAPSHOTS
+  value: {{ .Values.snapshots.minKeep | default 0 | quote }}
+- name: RETENTION_MODE
+  value: {{ .Values.snapshots.retentionMode | default "window" | quote }}
+{{- if .Values.snapshots.skipUnchanged }}
+- name: SKIP_UNCHANGED
+  value: {{ .Values.snapshots.skipUnchanged | quote }}
+{{- end }}
+{{- if .Values.snapshots.spacePressureThreshold }}
+- name: SPACE_PRESSURE_THRESHOLD
+  value: {{ .Values.snapshots.spacePressureThreshold | quote }}
+- name: SPACE_PRESSURE_TARGET
+  value: {{ .Values.snapshots.spacePressureTarget | default 80 | quote }}
+{{- end }}
+{{- if .Values.pools.whitelist }}
+- name: POOL_WHITELIST
+  value: {{ .Values.pools.whitelist | quote }}
+{{- end }}
+{{- if .Values.pools.blacklist }}
+- name: POOL_BLACKLIST
+  value: {{ .Values.pools.blacklist | quote }}
+{{- end }}
+{{- if .Values.filesystems.whitelist }}
+- name: FILESYSTEM_WHITELIST
+  value: {{ .Values.filesystems.whitelist | quote }}
+{{- end }}
+{{- if .Values.filesystems.blacklist }}
+- name: FILESYSTEM_BLACKLIST
+  value: {{ .Values.filesystems.blacklist | quote }}
+{{- end }}
+{{- if .Values.atomicSnapshotRoots }}
+- name: ATOMIC_SNAPSHOT_ROOTS
+  value: {{ .Values.atomicSnapshotRoots | quote }}
+{{- end }}
+- name: HONOR_USER_PROPERTIES
+  value: {{ .Values.honorUserProperties | quote }}
+- name: SNAPSHOT_PREFIX
+  value: {{ .Values.snapshotPrefix | quote }}
+- name: SNAPSHOT_NAME_TEMPLATE
+  value: {{ .Values.snapshotNameTemplate | quote }}
+{{- if .Values.adoptSnapshots }}
+- name: ADOPT_SNAPSHOTS
+  value: {{ .Values.adoptSnapshots | quote }}
+{{- end }}
+- name: SNAPSHOT_NAME_OFFSET
+  value: {{ .Values.snapshotNameOffset | quote }}
+{{- if .Values.timezone }}
+- name: TZ
+  value: {{ .Values.timezone | quote }}
+{{- end }}
+- name: SNAPSHOT_TIME_SOURCE
+  value: {{ .Values.snapshotTimeSource | quote }}
+- name: SCRUB_AGE_THRESHOLD_DAYS
+  value: {{ .Values.monitoring.scrubAgeThresholdDays | quote }}
+- name: CHECK_FRESHNESS
+  value: {{ .Values.monitoring.checkFreshness | quote }}
+- name: FRESHNESS_MAX_PERIODS
+  value: {{ .Values.monitoring.freshnessMaxPeriods | quote }}
+{{- if and .Values.daemon.enabled .Values.metrics.port }}
+- name: METRICS_ADDR
+  value: {{ printf ":%v" .Values.metrics.port | quote }}
+{{- end }}
+{{- if .Values.metrics.textfilePath }}
+- name: METRICS_TEXTFILE_PATH
+  value: {{ .Values.metrics.textfilePath | quote }}
+{{- end }}
+{{- if .Values.metrics.pushgatewayURL }}
+- name: PUSHGATEWAY_URL
+  value: {{ .Values.metrics.pushgatewayURL | quote }}
+{{- end }}
+{{- if .Values.metrics.pushgatewayJob }}
+- name: PUSHGATEWAY_JOB
+  value: {{ .Values.metrics.pushgatewayJob | quote }}
+{{- end }}
+{{- if eq .Values.operator.mode "chroot" }}
+- name: CHROOT_HOST_PATH
+  value: {{ .Values.operator.chrootHostPath | quote }}
+- name: CHROOT_BIN_PATH
+  value: {{ .Values.operator.chrootBinPath | quote }}
+{{- end }}
+{{- range .Values.filesystemOverrides }}
+{{- $suffix := regexReplaceAll "/" .filesystem "_" | upper }}
+{{- if .maxFrequently }}
+- name: MAX_FREQUENTLY_SNAPSHOTS_{{ $suffix }}
+  value: {{ .maxFrequently | quote }}
+{{- end }}
+{{- if .maxHourly }}
+- name: MAX_HOURLY_SNAPSHOTS_{{ $suffix }}
+  value: {{ .maxHourly | quote }}
+{{- end }}
+{{- if .maxDaily }}
+- name: MAX_DAILY_SNAPSHOTS_{{ $suffix }}
+  value: {{ .maxDaily | quote }}
+{{- end }}
+{{- if .maxWeekly }}
+- name: MAX_WEEKLY_SNAPSHOTS_{{ $suffix }}
+  value: {{ .maxWeekly | quote }}
+{{- end }}
+{{- if .maxMonthly }}
+- name: MAX_MONTHLY_SNAPSHOTS_{{ $suffix }}
+  value: {{ .maxMonthly | quote }}
+{{- end }}
+{{- if .maxYearly }}
+- name: MAX_YEARLY_SNAPSHOTS_{{ $suffix }}
+  value: {{ .maxYearly | quote }}
+{{- end }}
+{{- end }}
+{{- end }}
+
+{{/*
+Volume mounts of the operator container
+*/}}
+{{- define "zfs-snapshot-operator.volumeMounts" -}}
+- mountPath: {{ .Values.cronjob.hostMountPath }}
+  mountPropagation: HostToContainer
+  name: host-dir
+  readOnly: true
+{{- if .Values.policy }}
+- mountPath: /etc/zfs-snapshot-operator
+  name: policy
+  readOnly: true
+{{- end }}
+{{- with .Values.volumeMounts }}
+{{ toYaml . }}
+{{- end }}
+{{- end }}
+
+{{/*
+Volumes of the operator pod
+*/}}
+{{- define "zfs-snapshot-operator.volumes" -}}
+- hostPath:
+    path: {{ .Values.cronjob.hostPath }}
+    type: Directory
+  name: host-dir
+{{- if .Values.policy }}
+- configMap:
+    name: {{ include "zfs-snapshot-operator.fullname" . }}-policy
+  name: policy
+{{- end }}
+{{- with .Values.volumes }}
+{{ toYaml . }}
+{{- end }}
+{{- end }}
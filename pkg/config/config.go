@@ -2,14 +2,18 @@ package config
 
 import (
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/naming"
 )
 
 // Config holds the application configuration
 type Config struct {
-	Mode     string // Operation mode: test, direct, or chroot
+	Mode     string // Operation mode: test, simulate, direct, or chroot
 	LogLevel string // Log level: info or debug
 
 	// Safety features
@@ -17,6 +21,11 @@ type Config struct {
 	MaxDeletionsPerRun int    // Maximum snapshots to delete in one run
 	EnableLocking      bool   // If true, use lock file to prevent concurrent runs (default: true)
 	LockFilePath       string // Path to lock file for preventing concurrent runs
+	DeferDestroy       bool   // If true, destroy with -d so held or cloned snapshots are destroyed once released
+
+	// Operator-managed holds (see HoldTag)
+	HoldTagPrefix string        // Prefix of the hold tags owned by the operator
+	HoldMaxAge    time.Duration // Age after which operator-owned holds are released (0 = never)
 
 	MaxFrequentlySnapshots int
 	MaxHourlySnapshots     int
@@ -25,18 +34,74 @@ type Config struct {
 	MaxMonthlySnapshots    int
 	MaxYearlySnapshots     int
 
+	// Number of snapshots per tier and dataset that retention never goes below (see ResolveMinKeep)
+	MinKeepSnapshots int
+
+	// Tiers that skip creating a snapshot of a dataset nothing was written to since the tier's newest snapshot
+	SkipUnchanged []string
+
+	// Space-pressure pruning (see ValidateSpacePressure)
+	SpacePressureThreshold int // Usage percent of a pool or dataset that starts emergency pruning (0 = disabled)
+	SpacePressureTarget    int // Usage percent emergency pruning frees space down to
+
+	// Custom tiers from the policy file, in addition to the six built-in frequencies
+	CustomTiers []Tier
+
+	// Retention modes (see GetRetentionMode)
+	RetentionMode  string            // Default retention mode of all tiers: window, count or both
+	RetentionModes map[string]string // Per-tier retention modes from the policy file
+
+	// Policy file (-config)
+	PolicyFilePath   string            // Path of the loaded policy file (empty = none)
+	DefaultPolicy    Policy            // Defaults section of the policy file
+	PoolPolicies     map[string]Policy // Per-pool retention policies from the policy file
+	DatasetPolicies  map[string]Policy // Per-dataset retention policies from the policy file
+	SelectorPolicies []SelectorPolicy  // Glob/regex retention policies from the policy file
+
+	// ZFS user properties
+	HonorUserProperties bool // If true, read com.sun:auto-snapshot and retention overrides from dataset user properties
+
 	// Pool filtering
-	PoolWhitelist []string // List of pools to process (empty = all pools)
+	PoolWhitelist []string // Patterns of pools to process (empty = all pools)
+	PoolBlacklist []string // Patterns of pools to skip, wins over the whitelist
+
+	// Filesystem filtering (see MatchPattern for the pattern syntax)
+	FilesystemWhitelist []string // Patterns of filesystems to process (empty = all filesystems)
+	FilesystemBlacklist []string // Patterns of filesystems to skip, wins over the whitelist
 
-	// Filesystem filtering
-	FilesystemWhitelist []string // List of filesystems to process (empty = all filesystems)
+	// Atomic snapshots
+	AtomicSnapshotRoots []string // Patterns of dataset trees whose snapshots are created with a single atomic command
+
+	// Regexes of the "regex:" entries of all pattern lists, keyed by expression (see ValidatePatterns)
+	patternRegexes map[string]*regexp.Regexp
 
 	// Snapshot naming
-	SnapshotPrefix string // Prefix for automatic snapshots (default: autosnap)
+	SnapshotPrefix       string   // Prefix for automatic snapshots (default: autosnap)
+	SnapshotNameTemplate string   // Template of snapshot names, see naming.Template (default: {prefix}_{time}_{frequency})
+	AdoptSnapshots       []string // Third-party tools (e.g. sanoid, zfs-auto-snapshot) whose snapshots are managed like own snapshots
+	SnapshotTimeSource   string   // Time used for retention: "name" (timestamp in the name) or "creation" (creation property)
+	SnapshotNameOffset   bool     // If true, embed the UTC offset in snapshot names (e.g. autosnap_2026-03-29_02:30:00+0200_hourly)
+
+	// Name template compiled once the tiers are known (see CompileNameTemplate)
+	nameTemplate *naming.Template
+
+	// Time zone used for naming, parsing and bucketing snapshots (see SetTimezone)
+	Timezone string // IANA time zone name (empty = local time zone)
+	location *time.Location
 
 	// Scrub monitoring
 	ScrubAgeThresholdDays int // Number of days before warning about old scrubs
 
+	// Freshness check
+	CheckFreshness      bool // If true, run the freshness check as the last phase of every run
+	FreshnessMaxPeriods int  // Number of periods the newest snapshot of a frequency may fall behind
+
+	// Metrics export
+	MetricsAddr         string // Listen address for the /metrics endpoint in daemon mode (empty = disabled)
+	MetricsTextfilePath string // Path of a node_exporter textfile collector file (empty = disabled)
+	PushgatewayURL      string // URL of a Prometheus Pushgateway (empty = disabled)
+	PushgatewayJob      string // Job name used when pushing to the Pushgateway
+
 	// Chroot configuration
 	ChrootHostPath string // Path to host root for chroot mode (default: /host)
 	ChrootBinPath  string // Path to ZFS binaries in chroot mode (default: /usr/local/sbin)
@@ -46,13 +111,18 @@ type Config struct {
 	ZFSListSnapshotsCmd  []string
 	ZFSCreateSnapshotCmd []string
 	ZFSDeleteSnapshotCmd []string
+	ZFSDestroyDryRunCmd  []string
+	ZFSHoldCmd           []string
+	ZFSReleaseCmd        []string
+	ZFSHoldsCmd          []string
 	ZPoolStatusCmd       []string
 	ZPoolVersionCmd      []string
 	ZFSVersionCmd        []string
 }
 
 // NewConfig creates a new configuration with default values
-// mode can be: "test" (use test files), "direct" (no chroot), "chroot" (production with chroot)
+// mode can be: "test" (use test files), "simulate" (seed an in-memory backend from test files),
+// "direct" (no chroot), "chroot" (production with chroot)
 func NewConfig(mode string) *Config {
 	cfg := &Config{
 		Mode:                   mode,
@@ -61,27 +131,54 @@ func NewConfig(mode string) *Config {
 		MaxDeletionsPerRun:     getEnvAsInt("MAX_DELETIONS_PER_RUN", 100),
 		EnableLocking:          getEnvAsBool("ENABLE_LOCKING", true),
 		LockFilePath:           getEnvAsString("LOCK_FILE_PATH", "/tmp/zfs-snapshot-operator.lock"),
+		DeferDestroy:           getEnvAsBool("DEFER_DESTROY", false),
+		HoldTagPrefix:          getEnvAsString("HOLD_TAG_PREFIX", "zfs-snapshot-operator"),
+		HoldMaxAge:             getEnvAsDuration("HOLD_MAX_AGE", 0),
 		MaxFrequentlySnapshots: getEnvAsInt("MAX_FREQUENTLY_SNAPSHOTS", 0),
 		MaxHourlySnapshots:     getEnvAsInt("MAX_HOURLY_SNAPSHOTS", 24),
 		MaxDailySnapshots:      getEnvAsInt("MAX_DAILY_SNAPSHOTS", 7),
 		MaxWeeklySnapshots:     getEnvAsInt("MAX_WEEKLY_SNAPSHOTS", 4),
 		MaxMonthlySnapshots:    getEnvAsInt("MAX_MONTHLY_SNAPSHOTS", 12),
 		MaxYearlySnapshots:     getEnvAsInt("MAX_YEARLY_SNAPSHOTS", 3),
+		MinKeepSnapshots:       getEnvAsInt("MIN_KEEP_SNAPSHOTS", 0),
+		RetentionMode:          getEnvAsString("RETENTION_MODE", RetentionModeWindow),
+		SkipUnchanged:          getEnvAsStringSlice("SKIP_UNCHANGED", []string{}),
+		SpacePressureThreshold: getEnvAsInt("SPACE_PRESSURE_THRESHOLD", 0),
+		SpacePressureTarget:    getEnvAsInt("SPACE_PRESSURE_TARGET", 80),
+		HonorUserProperties:    getEnvAsBool("HONOR_USER_PROPERTIES", true),
 		PoolWhitelist:          getEnvAsStringSlice("POOL_WHITELIST", []string{}),
+		PoolBlacklist:          getEnvAsStringSlice("POOL_BLACKLIST", []string{}),
 		FilesystemWhitelist:    getEnvAsStringSlice("FILESYSTEM_WHITELIST", []string{}),
+		FilesystemBlacklist:    getEnvAsStringSlice("FILESYSTEM_BLACKLIST", []string{}),
+		AtomicSnapshotRoots:    getEnvAsStringSlice("ATOMIC_SNAPSHOT_ROOTS", []string{}),
 		SnapshotPrefix:         getEnvAsString("SNAPSHOT_PREFIX", "autosnap"),
+		SnapshotNameTemplate:   getEnvAsString("SNAPSHOT_NAME_TEMPLATE", naming.DefaultTemplate),
+		AdoptSnapshots:         getEnvAsStringSlice("ADOPT_SNAPSHOTS", []string{}),
+		SnapshotTimeSource:     getEnvAsString("SNAPSHOT_TIME_SOURCE", TimeSourceName),
+		SnapshotNameOffset:     getEnvAsBool("SNAPSHOT_NAME_OFFSET", false),
+		Timezone:               getEnvAsString("TZ", ""),
 		ScrubAgeThresholdDays:  getEnvAsInt("SCRUB_AGE_THRESHOLD_DAYS", 90),
+		CheckFreshness:         getEnvAsBool("CHECK_FRESHNESS", false),
+		FreshnessMaxPeriods:    getEnvAsInt("FRESHNESS_MAX_PERIODS", 1),
+		MetricsAddr:            getEnvAsString("METRICS_ADDR", ""),
+		MetricsTextfilePath:    getEnvAsString("METRICS_TEXTFILE_PATH", ""),
+		PushgatewayURL:         getEnvAsString("PUSHGATEWAY_URL", ""),
+		PushgatewayJob:         getEnvAsString("PUSHGATEWAY_JOB", "zfs-snapshot-operator"),
 		ChrootHostPath:         getEnvAsString("CHROOT_HOST_PATH", "/host"),
 		ChrootBinPath:          getEnvAsString("CHROOT_BIN_PATH", "/usr/local/sbin"),
 	}
 
 	switch mode {
-	case "test":
-		// Use test files for testing
+	case "test", "simulate":
+		// Use test files for testing (simulate mode uses them as the initial state)
 		cfg.ZFSListPoolsCmd = []string{"cat", "test/zfs_list_pools.json"}
 		cfg.ZFSListSnapshotsCmd = []string{"cat", "test/zfs_list_snapshots.json"}
 		cfg.ZFSCreateSnapshotCmd = []string{"true"}
 		cfg.ZFSDeleteSnapshotCmd = []string{"true"}
+		cfg.ZFSDestroyDryRunCmd = []string{"cat", "test/zfs_destroy_dry_run.txt"}
+		cfg.ZFSHoldCmd = []string{"true"}
+		cfg.ZFSReleaseCmd = []string{"true"}
+		cfg.ZFSHoldsCmd = []string{"cat", "test/zfs_holds.txt"}
 		cfg.ZPoolStatusCmd = []string{"cat", "test/zpool_status.json"}
 		cfg.ZPoolVersionCmd = []string{"cat", "test/zpool_version.json"}
 		cfg.ZFSVersionCmd = []string{"cat", "test/zfs_version.json"}
@@ -92,6 +189,10 @@ func NewConfig(mode string) *Config {
 		cfg.ZFSListSnapshotsCmd = []string{"zfs", "list", "-j", "-t", "snapshot"}
 		cfg.ZFSCreateSnapshotCmd = []string{"zfs", "snapshot"}
 		cfg.ZFSDeleteSnapshotCmd = []string{"zfs", "destroy"}
+		cfg.ZFSDestroyDryRunCmd = []string{"zfs", "destroy", "-nvp"}
+		cfg.ZFSHoldCmd = []string{"zfs", "hold"}
+		cfg.ZFSReleaseCmd = []string{"zfs", "release"}
+		cfg.ZFSHoldsCmd = []string{"zfs", "holds", "-H", "-p"}
 		cfg.ZPoolStatusCmd = []string{"zpool", "status", "-j"}
 		cfg.ZPoolVersionCmd = []string{"zpool", "version", "-j"}
 		cfg.ZFSVersionCmd = []string{"zfs", "version", "-j"}
@@ -103,6 +204,10 @@ func NewConfig(mode string) *Config {
 		cfg.ZFSListSnapshotsCmd = append(zfsBin, "list", "-j", "-t", "snapshot")
 		cfg.ZFSCreateSnapshotCmd = append(zfsBin, "snapshot")
 		cfg.ZFSDeleteSnapshotCmd = append(zfsBin, "destroy")
+		cfg.ZFSDestroyDryRunCmd = append(zfsBin, "destroy", "-nvp")
+		cfg.ZFSHoldCmd = append(zfsBin, "hold")
+		cfg.ZFSReleaseCmd = append(zfsBin, "release")
+		cfg.ZFSHoldsCmd = append(zfsBin, "holds", "-H", "-p")
 		cfg.ZPoolStatusCmd = append(zpoolBin, "status", "-j")
 		cfg.ZPoolVersionCmd = append(zpoolBin, "version", "-j")
 		cfg.ZFSVersionCmd = append(zfsBin, "version", "-j")
@@ -112,89 +217,133 @@ func NewConfig(mode string) *Config {
 }
 
 // GetMaxSnapshotsForFrequency returns the maximum number of snapshots to keep for a given frequency
-// If filesystemName is provided, it will check for filesystem-specific overrides first
-// (e.g., MAX_HOURLY_SNAPSHOTS_TANK_PUBLIC for tank/public filesystem)
+// If filesystemName is provided, the value is inherited along the dataset path (see ResolveMaxSnapshots)
 func (c *Config) GetMaxSnapshotsForFrequency(frequency string, filesystemName ...string) int {
-	var envKey string
-	var defaultValue int
-
-	switch frequency {
-	case "frequently":
-		envKey = "MAX_FREQUENTLY_SNAPSHOTS"
-		defaultValue = c.MaxFrequentlySnapshots
-	case "hourly":
-		envKey = "MAX_HOURLY_SNAPSHOTS"
-		defaultValue = c.MaxHourlySnapshots
-	case "daily":
-		envKey = "MAX_DAILY_SNAPSHOTS"
-		defaultValue = c.MaxDailySnapshots
-	case "weekly":
-		envKey = "MAX_WEEKLY_SNAPSHOTS"
-		defaultValue = c.MaxWeeklySnapshots
-	case "monthly":
-		envKey = "MAX_MONTHLY_SNAPSHOTS"
-		defaultValue = c.MaxMonthlySnapshots
-	case "yearly":
-		envKey = "MAX_YEARLY_SNAPSHOTS"
-		defaultValue = c.MaxYearlySnapshots
-	default:
-		return 0
+	name := ""
+	if len(filesystemName) > 0 {
+		name = filesystemName[0]
 	}
 
-	// Check for filesystem-specific override if filesystem name is provided
-	if len(filesystemName) > 0 && filesystemName[0] != "" {
-		if value := getFilesystemSpecificEnvAsInt(envKey, filesystemName[0], -1); value != -1 {
-			return value
+	value, _ := c.ResolveMaxSnapshots(frequency, name)
+	return value
+}
+
+// ResolveMaxSnapshots returns the maximum number of snapshots to keep for a frequency and filesystem,
+// together with a description of where the value came from
+// Like ZFS property inheritance, the dataset path is walked upwards (tank/media/movies, tank/media, tank).
+// On each level a filesystem-specific env var (e.g. MAX_HOURLY_SNAPSHOTS_TANK_MEDIA) wins over a
+// dataset policy, which wins over a matching selector; the pool level also honors pool policies.
+// If no level sets a value, the global value is used
+func (c *Config) ResolveMaxSnapshots(frequency string, filesystemName string) (int, string) {
+	return c.resolveMaxSnapshots(frequency, filesystemName, nil)
+}
+
+// ResolvePoolMaxSnapshots works like ResolveMaxSnapshots, but also honors the user properties of the
+// dataset (see UserProperties). A property wins over the env vars and policies of the level it is set on
+func (c *Config) ResolvePoolMaxSnapshots(frequency string, pool *models.Pool) (int, string) {
+	return c.resolveMaxSnapshots(frequency, pool.FilesystemName, pool)
+}
+
+// GetPoolMaxSnapshots returns the maximum number of snapshots to keep for a frequency and dataset
+func (c *Config) GetPoolMaxSnapshots(frequency string, pool *models.Pool) int {
+	value, _ := c.ResolvePoolMaxSnapshots(frequency, pool)
+	return value
+}
+
+func (c *Config) resolveMaxSnapshots(frequency string, filesystemName string, pool *models.Pool) (int, string) {
+	defaultValue, ok := c.globalMaxSnapshots(frequency)
+	if !ok {
+		return 0, "unknown frequency"
+	}
+	envKey := tierEnvKey(frequency)
+
+	if filesystemName != "" {
+		for _, name := range DatasetAncestors(filesystemName) {
+			if value, source, ok := c.propertyRetention(pool, frequency, name); ok {
+				return value, source
+			}
+			if value := getFilesystemSpecificEnvAsInt(envKey, name, -1); value != -1 {
+				return value, "env " + filesystemEnvKey(envKey, name)
+			}
+			if value, source, ok := c.policyRetention(frequency, name); ok {
+				return value, source
+			}
 		}
 	}
 
-	return defaultValue
+	switch {
+	case os.Getenv(envKey) != "":
+		return defaultValue, "env " + envKey
+	case c.hasPolicyDefault(frequency):
+		return defaultValue, "policy defaults"
+	default:
+		return defaultValue, "global default"
+	}
+}
+
+// DatasetAncestors returns the dataset itself followed by all of its parents up to the pool root
+// For example, "tank/media/movies" yields ["tank/media/movies", "tank/media", "tank"]
+func DatasetAncestors(filesystemName string) []string {
+	ancestors := []string{filesystemName}
+	for i := strings.LastIndex(filesystemName, "/"); i > 0; i = strings.LastIndex(filesystemName, "/") {
+		filesystemName = filesystemName[:i]
+		ancestors = append(ancestors, filesystemName)
+	}
+	return ancestors
 }
 
 // GetMaxSnapshotDate returns the maximum date for a given frequency
 // If filesystemName is provided, it will check for filesystem-specific overrides first
 func (c *Config) GetMaxSnapshotDate(frequency string, now time.Time, filesystemName ...string) time.Time {
-	maxCount := c.GetMaxSnapshotsForFrequency(frequency, filesystemName...)
+	return c.RetentionCutoff(frequency, now, c.GetMaxSnapshotsForFrequency(frequency, filesystemName...))
+}
 
-	switch frequency {
-	case "frequently":
-		return now.Add(-time.Duration(maxCount) * 15 * time.Minute)
-	case "hourly":
-		return now.Add(-time.Duration(maxCount) * time.Hour)
-	case "daily":
-		return now.Add(-time.Duration(maxCount) * 24 * time.Hour)
-	case "weekly":
-		return now.Add(-time.Duration(maxCount) * 7 * 24 * time.Hour)
-	case "monthly":
-		return now.Add(-time.Duration(maxCount*4) * 7 * 24 * time.Hour)
-	case "yearly":
-		return now.Add(-time.Duration(maxCount*52) * 7 * 24 * time.Hour)
-	default:
+// RetentionCutoff returns the oldest date that is kept for a frequency with the given retention count
+// The window covers the last maxCount calendar periods including the current one, so keeping
+// 12 monthly snapshots keeps one snapshot of each of the last 12 distinct months
+func (c *Config) RetentionCutoff(frequency string, now time.Time, maxCount int) time.Time {
+	tier, ok := c.Tier(frequency)
+	if !ok || maxCount <= 0 {
 		return now
 	}
+	return tier.PeriodsBack(now, maxCount-1)
 }
 
-// GetMinSnapshotDate returns the minimum date for a given frequency
+// GetMinSnapshotDate returns the start of the current period of a frequency
+// A snapshot newer than this is recent, no further snapshot is created in the period
 func (c *Config) GetMinSnapshotDate(frequency string, now time.Time) time.Time {
+	tier, ok := c.Tier(frequency)
+	if !ok {
+		return now
+	}
+	return tier.PeriodStart(now)
+}
+
+// globalMaxSnapshots returns the global retention count of a built-in or custom tier
+func (c *Config) globalMaxSnapshots(frequency string) (int, bool) {
 	switch frequency {
 	case "frequently":
-		return now.Add(-15 * time.Minute)
+		return c.MaxFrequentlySnapshots, true
 	case "hourly":
-		return now.Add(-1 * time.Hour)
+		return c.MaxHourlySnapshots, true
 	case "daily":
-		return now.Add(-24 * time.Hour)
+		return c.MaxDailySnapshots, true
 	case "weekly":
-		return now.Add(-7 * 24 * time.Hour)
+		return c.MaxWeeklySnapshots, true
 	case "monthly":
-		return now.Add(-4 * 7 * 24 * time.Hour)
+		return c.MaxMonthlySnapshots, true
 	case "yearly":
-		return now.Add(-52 * 7 * 24 * time.Hour)
-	default:
-		return now
+		return c.MaxYearlySnapshots, true
+	}
+	for _, tier := range c.CustomTiers {
+		if tier.Name == frequency {
+			return tier.Retention, true
+		}
 	}
+	return 0, false
 }
 
-// Frequencies returns the list of supported snapshot frequencies
+// Frequencies returns the six built-in snapshot frequencies (see Config.TierNames for custom tiers)
 func Frequencies() []string {
 	return []string{"frequently", "hourly", "daily", "weekly", "monthly", "yearly"}
 }
@@ -250,21 +399,11 @@ func getEnvAsString(key string, defaultValue string) string {
 	return value
 }
 
-// IsPoolAllowed checks if a pool is in the whitelist (or if whitelist is empty, all pools are allowed)
+// IsPoolAllowed checks if a pool matches the whitelist (or if whitelist is empty, all pools are allowed)
+// and does not match the blacklist
 func (c *Config) IsPoolAllowed(poolName string) bool {
-	// If whitelist is empty, all pools are allowed
-	if len(c.PoolWhitelist) == 0 {
-		return true
-	}
-
-	// Check if pool is in whitelist
-	for _, allowedPool := range c.PoolWhitelist {
-		if allowedPool == poolName {
-			return true
-		}
-	}
-
-	return false
+	allowed, _ := c.FilterPool(poolName)
+	return allowed
 }
 
 // IsDebug returns true if log level is set to debug
@@ -272,21 +411,42 @@ func (c *Config) IsDebug() bool {
 	return c.LogLevel == "debug"
 }
 
-// IsFilesystemAllowed checks if a filesystem is in the whitelist (or if whitelist is empty, all filesystems are allowed)
+// IsFilesystemAllowed checks if a filesystem matches the whitelist (or if whitelist is empty, all filesystems
+// are allowed) and does not match the blacklist
 func (c *Config) IsFilesystemAllowed(filesystemName string) bool {
-	// If whitelist is empty, all filesystems are allowed
-	if len(c.FilesystemWhitelist) == 0 {
-		return true
+	allowed, _ := c.FilterFilesystem(filesystemName)
+	return allowed
+}
+
+// AtomicRoot returns the atomic snapshot root a filesystem belongs to
+// This is the outermost dataset on the path of the filesystem that matches ATOMIC_SNAPSHOT_ROOTS,
+// so nested roots are merged into a single tree
+func (c *Config) AtomicRoot(filesystemName string) (string, bool) {
+	if len(c.AtomicSnapshotRoots) == 0 {
+		return "", false
 	}
 
-	// Check if filesystem is in whitelist
-	for _, allowedFilesystem := range c.FilesystemWhitelist {
-		if allowedFilesystem == filesystemName {
-			return true
+	ancestors := DatasetAncestors(filesystemName)
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		if _, ok := c.matchingPattern(c.AtomicSnapshotRoots, ancestors[i]); ok {
+			return ancestors[i], true
 		}
 	}
+	return "", false
+}
 
-	return false
+// getEnvAsDuration gets an environment variable as a duration (e.g. "168h"),
+// or returns the default value if not set or invalid
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return defaultValue
+	}
+	return duration
 }
 
 // getEnvAsBool gets an environment variable as a boolean
@@ -302,16 +462,18 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	return boolValue
 }
 
+// filesystemEnvKey returns the filesystem-specific env var name for a key
+// Slashes in the filesystem name are replaced with "_" and the result is uppercased
+func filesystemEnvKey(key string, filesystemName string) string {
+	suffix := strings.ToUpper(strings.ReplaceAll(filesystemName, "/", "_"))
+	return key + "_" + suffix
+}
+
 // getFilesystemSpecificEnvAsInt checks for a filesystem-specific environment variable
 // For example, for filesystem "tank/public" and key "MAX_HOURLY_SNAPSHOTS",
 // it will look for "MAX_HOURLY_SNAPSHOTS_TANK_PUBLIC"
 func getFilesystemSpecificEnvAsInt(key string, filesystemName string, defaultValue int) int {
-	// Convert filesystem name to env var suffix
-	// Replace "/" with "_" and convert to uppercase
-	suffix := strings.ToUpper(strings.ReplaceAll(filesystemName, "/", "_"))
-	specificKey := key + "_" + suffix
-
-	valueStr := os.Getenv(specificKey)
+	valueStr := os.Getenv(filesystemEnvKey(key, filesystemName))
 	if valueStr == "" {
 		return defaultValue
 	}
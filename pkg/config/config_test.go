@@ -68,6 +68,12 @@ func TestNewConfig(t *testing.T) {
 			if len(cfg.ZFSDeleteSnapshotCmd) == 0 {
 				t.Error("ZFSDeleteSnapshotCmd is empty")
 			}
+			if len(cfg.ZFSDestroyDryRunCmd) == 0 {
+				t.Error("ZFSDestroyDryRunCmd is empty")
+			}
+			if len(cfg.ZFSHoldCmd) == 0 || len(cfg.ZFSReleaseCmd) == 0 || len(cfg.ZFSHoldsCmd) == 0 {
+				t.Error("hold commands are empty")
+			}
 			if len(cfg.ZPoolStatusCmd) == 0 {
 				t.Error("ZPoolStatusCmd is empty")
 			}
@@ -172,105 +178,97 @@ func TestIsFilesystemAllowed(t *testing.T) {
 
 func TestGetMaxSnapshotDate(t *testing.T) {
 	cfg := NewConfig("test")
-	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
-
+	cfg.MaxHourlySnapshots = 24
+	cfg.MaxDailySnapshots = 7
+	cfg.MaxWeeklySnapshots = 4
+	cfg.MaxMonthlySnapshots = 12
+	cfg.MaxYearlySnapshots = 3
+	now := time.Date(2024, 1, 15, 12, 30, 0, 0, time.UTC)
+
+	// The cutoff is the start of the oldest of the last N calendar periods, including the current one
 	tests := []struct {
 		name      string
 		frequency string
-		wantDiff  time.Duration
+		want      time.Time
 	}{
-		{
-			name:      "hourly",
-			frequency: "hourly",
-			wantDiff:  time.Duration(cfg.MaxHourlySnapshots) * time.Hour,
-		},
-		{
-			name:      "daily",
-			frequency: "daily",
-			wantDiff:  time.Duration(cfg.MaxDailySnapshots) * 24 * time.Hour,
-		},
-		{
-			name:      "weekly",
-			frequency: "weekly",
-			wantDiff:  time.Duration(cfg.MaxWeeklySnapshots) * 7 * 24 * time.Hour,
-		},
-		{
-			name:      "monthly",
-			frequency: "monthly",
-			wantDiff:  time.Duration(cfg.MaxMonthlySnapshots*4) * 7 * 24 * time.Hour,
-		},
-		{
-			name:      "yearly",
-			frequency: "yearly",
-			wantDiff:  time.Duration(cfg.MaxYearlySnapshots*52) * 7 * 24 * time.Hour,
-		},
-		{
-			name:      "invalid frequency",
-			frequency: "invalid",
-			wantDiff:  0,
-		},
+		{"hourly", "hourly", time.Date(2024, 1, 14, 13, 0, 0, 0, time.UTC)},
+		{"daily", "daily", time.Date(2024, 1, 9, 0, 0, 0, 0, time.UTC)},
+		{"weekly", "weekly", time.Date(2023, 12, 25, 0, 0, 0, 0, time.UTC)},
+		{"monthly", "monthly", time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"yearly", "yearly", time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"invalid frequency", "invalid", now},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			result := cfg.GetMaxSnapshotDate(tt.frequency, now)
-			expected := now.Add(-tt.wantDiff)
+			if !result.Equal(tt.want) {
+				t.Errorf("GetMaxSnapshotDate(%s) = %v, want %v", tt.frequency, result, tt.want)
+			}
+		})
+	}
+}
+
+// TestRetentionCutoffCalendarMonths tests that monthly retention covers calendar months, not 4-week blocks
+func TestRetentionCutoffCalendarMonths(t *testing.T) {
+	cfg := NewConfig("test")
+	now := time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC)
 
-			if !result.Equal(expected) {
-				t.Errorf("GetMaxSnapshotDate(%s) = %v, want %v", tt.frequency, result, expected)
+	tests := []struct {
+		name      string
+		frequency string
+		maxCount  int
+		want      time.Time
+	}{
+		// 48 weeks before now would be 2025-04-30 and drop the April snapshot
+		{"12 monthly", "monthly", 12, time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"1 monthly keeps the current month", "monthly", 1, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"across February", "monthly", 2, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"across a leap year", "yearly", 3, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"daily across month end", "daily", 31, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"zero keeps nothing", "monthly", 0, now},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cfg.RetentionCutoff(tt.frequency, now, tt.maxCount); !got.Equal(tt.want) {
+				t.Errorf("RetentionCutoff(%s, %d) = %v, want %v", tt.frequency, tt.maxCount, got, tt.want)
 			}
 		})
 	}
+
+	// A snapshot taken on the first of each of the last 12 months stays inside the window
+	cutoff := cfg.RetentionCutoff("monthly", now, 12)
+	for i := 0; i < 12; i++ {
+		snapshotTime := time.Date(2026, 3-time.Month(i), 1, 0, 5, 0, 0, time.UTC)
+		if snapshotTime.Before(cutoff) {
+			t.Errorf("Monthly snapshot of %s is outside of the 12 month window", snapshotTime.Format("2006-01"))
+		}
+	}
 }
 
 func TestGetMinSnapshotDate(t *testing.T) {
 	cfg := NewConfig("test")
-	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	now := time.Date(2024, 1, 17, 12, 30, 0, 0, time.UTC)
 
 	tests := []struct {
 		name      string
 		frequency string
-		wantDiff  time.Duration
+		want      time.Time
 	}{
-		{
-			name:      "hourly",
-			frequency: "hourly",
-			wantDiff:  1 * time.Hour,
-		},
-		{
-			name:      "daily",
-			frequency: "daily",
-			wantDiff:  24 * time.Hour,
-		},
-		{
-			name:      "weekly",
-			frequency: "weekly",
-			wantDiff:  7 * 24 * time.Hour,
-		},
-		{
-			name:      "monthly",
-			frequency: "monthly",
-			wantDiff:  4 * 7 * 24 * time.Hour,
-		},
-		{
-			name:      "yearly",
-			frequency: "yearly",
-			wantDiff:  52 * 7 * 24 * time.Hour,
-		},
-		{
-			name:      "invalid frequency",
-			frequency: "invalid",
-			wantDiff:  0,
-		},
+		{"hourly", "hourly", time.Date(2024, 1, 17, 12, 0, 0, 0, time.UTC)},
+		{"daily", "daily", time.Date(2024, 1, 17, 0, 0, 0, 0, time.UTC)},
+		{"weekly", "weekly", time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{"monthly", "monthly", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"yearly", "yearly", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"invalid frequency", "invalid", now},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			result := cfg.GetMinSnapshotDate(tt.frequency, now)
-			expected := now.Add(-tt.wantDiff)
-
-			if !result.Equal(expected) {
-				t.Errorf("GetMinSnapshotDate(%s) = %v, want %v", tt.frequency, result, expected)
+			if !result.Equal(tt.want) {
+				t.Errorf("GetMinSnapshotDate(%s) = %v, want %v", tt.frequency, result, tt.want)
 			}
 		})
 	}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// holdTagSeparator separates the operator prefix from the name of an operator-owned hold tag
+const holdTagSeparator = ":"
+
+// ValidateHolds checks the settings of operator-managed holds
+func (c *Config) ValidateHolds() error {
+	var errs []error
+	if c.HoldTagPrefix == "" || strings.ContainsAny(c.HoldTagPrefix, " \t@"+holdTagSeparator) {
+		errs = append(errs, fmt.Errorf("HOLD_TAG_PREFIX: %q must be non-empty and contain no whitespace, '@' or '%s'",
+			c.HoldTagPrefix, holdTagSeparator))
+	}
+	if c.HoldMaxAge < 0 {
+		errs = append(errs, fmt.Errorf("HOLD_MAX_AGE: %s must not be negative", c.HoldMaxAge))
+	}
+	return errors.Join(errs...)
+}
+
+// HoldTag returns the operator-owned hold tag for a hold name, e.g. "zfs-snapshot-operator:replication"
+func (c *Config) HoldTag(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return "", fmt.Errorf("hold name %q must be non-empty and contain no whitespace", name)
+	}
+	return c.HoldTagPrefix + holdTagSeparator + name, nil
+}
+
+// IsOperatorHold checks if a hold tag is owned by the operator
+// Holds of other tools and administrators are never released by the operator
+func (c *Config) IsOperatorHold(tag string) bool {
+	return strings.HasPrefix(tag, c.HoldTagPrefix+holdTagSeparator)
+}
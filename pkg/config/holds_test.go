@@ -0,0 +1,70 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateHolds(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  string
+		maxAge  time.Duration
+		wantErr bool
+	}{
+		{"defaults", "zfs-snapshot-operator", 0, false},
+		{"max age", "zfs-snapshot-operator", 7 * 24 * time.Hour, false},
+		{"empty prefix", "", 0, true},
+		{"prefix with separator", "ops:team", 0, true},
+		{"prefix with space", "my operator", 0, true},
+		{"negative max age", "zfs-snapshot-operator", -time.Hour, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{HoldTagPrefix: tt.prefix, HoldMaxAge: tt.maxAge}
+			err := cfg.ValidateHolds()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateHolds() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHoldTag(t *testing.T) {
+	cfg := &Config{HoldTagPrefix: "zfs-snapshot-operator"}
+
+	tag, err := cfg.HoldTag("replication")
+	if err != nil {
+		t.Fatalf("HoldTag() error = %v", err)
+	}
+	if tag != "zfs-snapshot-operator:replication" {
+		t.Errorf("HoldTag() = %q, want zfs-snapshot-operator:replication", tag)
+	}
+	if !cfg.IsOperatorHold(tag) {
+		t.Errorf("IsOperatorHold(%q) = false, want true", tag)
+	}
+
+	for _, tag := range []string{"zfs-snapshot-operator", "backup", "zfs-snapshot-operator-2:replication"} {
+		if cfg.IsOperatorHold(tag) {
+			t.Errorf("IsOperatorHold(%q) = true, want false", tag)
+		}
+	}
+
+	for _, name := range []string{"", "incident 42"} {
+		if _, err := cfg.HoldTag(name); err == nil {
+			t.Errorf("HoldTag(%q) error = nil, want an error", name)
+		}
+	}
+}
+
+func TestHoldMaxAgeEnv(t *testing.T) {
+	t.Setenv("HOLD_MAX_AGE", "168h")
+	if got := NewConfig("test").HoldMaxAge; got != 168*time.Hour {
+		t.Errorf("HoldMaxAge = %s, want 168h", got)
+	}
+
+	t.Setenv("HOLD_MAX_AGE", "a week")
+	if got := NewConfig("test").HoldMaxAge; got != 0 {
+		t.Errorf("HoldMaxAge = %s, want 0 for an invalid value", got)
+	}
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// minKeepEnvKey is the global min_keep env var, filesystem-specific variants append the dataset name
+const minKeepEnvKey = "MIN_KEEP_SNAPSHOTS"
+
+// ResolveMinKeep returns the number of snapshots per tier that retention never goes below for a filesystem,
+// together with a description of where the value came from
+// The setting is inherited along the dataset path like ResolveMaxSnapshots
+func (c *Config) ResolveMinKeep(filesystemName string) (int, string) {
+	for _, name := range DatasetAncestors(filesystemName) {
+		if value := getFilesystemSpecificEnvAsInt(minKeepEnvKey, name, -1); value >= 0 {
+			return value, "env " + filesystemEnvKey(minKeepEnvKey, name)
+		}
+		if value, source, ok := c.policyMinKeep(name); ok {
+			return value, source
+		}
+	}
+
+	switch {
+	case os.Getenv(minKeepEnvKey) != "":
+		return c.MinKeepSnapshots, "env " + minKeepEnvKey
+	case c.DefaultPolicy.MinKeep != nil:
+		return *c.DefaultPolicy.MinKeep, "policy defaults"
+	default:
+		return c.MinKeepSnapshots, "global default"
+	}
+}
+
+// GetMinKeep returns the min_keep floor of a filesystem
+func (c *Config) GetMinKeep(filesystemName string) int {
+	value, _ := c.ResolveMinKeep(filesystemName)
+	return value
+}
+
+// policyMinKeep looks up the min_keep setting for a single level of the dataset tree
+func (c *Config) policyMinKeep(name string) (int, string, bool) {
+	if policy, ok := c.DatasetPolicies[name]; ok && policy.MinKeep != nil {
+		return *policy.MinKeep, fmt.Sprintf("policy datasets[%s]", name), true
+	}
+
+	for i := range c.SelectorPolicies {
+		selector := &c.SelectorPolicies[i]
+		if selector.MinKeep != nil && selector.Matches(name) {
+			return *selector.MinKeep, fmt.Sprintf("policy selectors[%d] matching %s", i, name), true
+		}
+	}
+
+	if !strings.Contains(name, "/") {
+		if policy, ok := c.PoolPolicies[name]; ok && policy.MinKeep != nil {
+			return *policy.MinKeep, fmt.Sprintf("policy pools[%s]", name), true
+		}
+	}
+
+	return 0, "", false
+}
+
+// validateMinKeep checks that a min_keep setting is not negative
+func validateMinKeep(field string, minKeep *int) error {
+	if minKeep != nil && *minKeep < 0 {
+		return fmt.Errorf("%s: min_keep must not be negative, got %d", field, *minKeep)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+const testMinKeepYAML = `
+defaults:
+  min_keep: 1
+pools:
+  backup:
+    min_keep: 0
+datasets:
+  tank/important:
+    min_keep: 5
+selectors:
+  - glob: "tank/vm/*"
+    min_keep: 3
+`
+
+func TestResolveMinKeep(t *testing.T) {
+	t.Setenv("MIN_KEEP_SNAPSHOTS_TANK_VM_WEB", "4")
+
+	cfg := NewConfig("test")
+	if err := cfg.LoadPolicyFile(writePolicyFile(t, "policy.yaml", testMinKeepYAML)); err != nil {
+		t.Fatalf("LoadPolicyFile() error = %v", err)
+	}
+
+	tests := []struct {
+		filesystem string
+		want       int
+		wantSource string
+	}{
+		{"tank/data", 1, "policy defaults"},
+		{"tank/important/db", 5, "policy datasets[tank/important]"},
+		{"tank/vm/win11", 3, "policy selectors[0] matching tank/vm/win11"},
+		{"tank/vm/web", 4, "env MIN_KEEP_SNAPSHOTS_TANK_VM_WEB"},
+		{"backup/data", 0, "policy pools[backup]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filesystem, func(t *testing.T) {
+			got, source := cfg.ResolveMinKeep(tt.filesystem)
+			if got != tt.want || source != tt.wantSource {
+				t.Errorf("ResolveMinKeep(%s) = %d (%s), want %d (%s)", tt.filesystem, got, source, tt.want, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestResolveMinKeepGlobal(t *testing.T) {
+	t.Setenv("MIN_KEEP_SNAPSHOTS", "2")
+
+	cfg := NewConfig("test")
+	if err := cfg.LoadPolicyFile(writePolicyFile(t, "policy.yaml", testMinKeepYAML)); err != nil {
+		t.Fatalf("LoadPolicyFile() error = %v", err)
+	}
+
+	// The global env var wins over the defaults of the policy file, but not over dataset policies
+	if got, source := cfg.ResolveMinKeep("tank/data"); got != 2 || source != "env MIN_KEEP_SNAPSHOTS" {
+		t.Errorf("ResolveMinKeep(tank/data) = %d (%s), want 2 (env MIN_KEEP_SNAPSHOTS)", got, source)
+	}
+	if got := cfg.GetMinKeep("tank/important"); got != 5 {
+		t.Errorf("GetMinKeep(tank/important) = %d, want 5", got)
+	}
+}
+
+func TestLoadPolicyFileNegativeMinKeep(t *testing.T) {
+	cfg := NewConfig("test")
+	err := cfg.LoadPolicyFile(writePolicyFile(t, "policy.yaml", "datasets:\n  tank/data:\n    min_keep: -1\n"))
+	if err == nil || !strings.Contains(err.Error(), "min_keep must not be negative") {
+		t.Errorf("LoadPolicyFile() error = %v, want a negative min_keep error", err)
+	}
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/naming"
+)
+
+// CompileNameTemplate compiles the snapshot name template once the tiers are known and stores it,
+// so NameTemplate does not compile it again for every run
+func (c *Config) CompileNameTemplate() error {
+	c.nameTemplate = nil
+	t, err := c.NameTemplate()
+	if err != nil {
+		return err
+	}
+	c.nameTemplate = t
+	return nil
+}
+
+// NameTemplate returns the template stored by CompileNameTemplate, or compiles the snapshot name template
+// from SNAPSHOT_NAME_TEMPLATE, SNAPSHOT_PREFIX and SNAPSHOT_NAME_OFFSET and checks that its time layout
+// resolves the period of every tier
+func (c *Config) NameTemplate() (*naming.Template, error) {
+	if c.nameTemplate != nil {
+		return c.nameTemplate, nil
+	}
+
+	template := c.SnapshotNameTemplate
+	if template == "" {
+		template = naming.DefaultTemplate
+	}
+
+	t, err := naming.Compile(template, c.SnapshotPrefix, c.TierNames(), c.SnapshotNameOffset)
+	if err != nil {
+		return nil, fmt.Errorf("SNAPSHOT_NAME_TEMPLATE: %w", err)
+	}
+	for _, tier := range c.Tiers() {
+		if err := t.CheckPeriod(tier.Name, tier.PeriodStart, tier.NextPeriodStart); err != nil {
+			return nil, fmt.Errorf("SNAPSHOT_NAME_TEMPLATE: %w", err)
+		}
+	}
+	return t, nil
+}
+
+// ValidateAdoptSnapshots checks that every entry of ADOPT_SNAPSHOTS is a known snapshot tool
+func (c *Config) ValidateAdoptSnapshots() error {
+	var errs []error
+	for _, name := range c.AdoptSnapshots {
+		if _, err := naming.LookupTool(name); err != nil {
+			errs = append(errs, fmt.Errorf("ADOPT_SNAPSHOTS: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// IsToolAdopted checks if the snapshots of a third-party tool are managed like own snapshots
+func (c *Config) IsToolAdopted(name string) bool {
+	for _, adopted := range c.AdoptSnapshots {
+		if adopted == name {
+			return true
+		}
+	}
+	return false
+}
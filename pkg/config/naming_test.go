@@ -0,0 +1,76 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNameTemplate(t *testing.T) {
+	at := time.Date(2026, 1, 25, 14, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		template string
+		offset   bool
+		want     string
+		wantErr  bool
+	}{
+		{"empty uses default", "", false, "autosnap_2026-01-25_14:00:00_hourly", false},
+		{"default with offset", "{prefix}_{time}_{frequency}", true, "autosnap_2026-01-25_14:00:00+0000_hourly", false},
+		{"custom", "{prefix}-{frequency}-{time:20060102T150405Z}", false, "autosnap-hourly-20260125T140000Z", false},
+		{"invalid", "{prefix}_{time}", false, "", true},
+		{"date only", "{prefix}_{frequency}_{time:2006-01-02}", false, "", true},
+		{"hours only", "{prefix}_{frequency}_{time:2006-01-02_15}", false, "", true},
+		{"minutes", "{prefix}_{frequency}_{time:2006-01-02_1504}", false, "autosnap_hourly_2026-01-25_1400", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{SnapshotPrefix: "autosnap", SnapshotNameTemplate: tt.template, SnapshotNameOffset: tt.offset}
+			template, err := cfg.NameTemplate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NameTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				if got := template.Format("hourly", at); got != tt.want {
+					t.Errorf("Format() = %s, want %s", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCompileNameTemplate(t *testing.T) {
+	cfg := &Config{SnapshotPrefix: "autosnap", SnapshotNameTemplate: "{prefix}_{time}"}
+	if err := cfg.CompileNameTemplate(); err == nil {
+		t.Fatal("CompileNameTemplate() expected an error for an invalid template")
+	}
+
+	cfg.SnapshotNameTemplate = "{prefix}_{time}_{frequency}"
+	if err := cfg.CompileNameTemplate(); err != nil {
+		t.Fatalf("CompileNameTemplate() error = %v", err)
+	}
+	first, err := cfg.NameTemplate()
+	if err != nil {
+		t.Fatalf("NameTemplate() error = %v", err)
+	}
+	second, _ := cfg.NameTemplate()
+	if first != second {
+		t.Error("NameTemplate() compiled the template again after CompileNameTemplate()")
+	}
+}
+
+func TestValidateAdoptSnapshots(t *testing.T) {
+	cfg := &Config{AdoptSnapshots: []string{"sanoid", "zfs-auto-snapshot"}}
+	if err := cfg.ValidateAdoptSnapshots(); err != nil {
+		t.Errorf("ValidateAdoptSnapshots() error = %v", err)
+	}
+	if !cfg.IsToolAdopted("sanoid") || cfg.IsToolAdopted("pyznap") {
+		t.Error("IsToolAdopted() should only report the configured tools")
+	}
+
+	cfg.AdoptSnapshots = []string{"sanoid", "pyznap"}
+	if err := cfg.ValidateAdoptSnapshots(); err == nil {
+		t.Error("ValidateAdoptSnapshots() expected an error for an unknown tool")
+	}
+}
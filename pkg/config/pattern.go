@@ -0,0 +1,158 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// PatternRegexPrefix marks a whitelist or blacklist entry as a regular expression, e.g. "regex:^tank/home/[^/]+$"
+const PatternRegexPrefix = "regex:"
+
+// PatternRecursiveSuffix marks a whitelist or blacklist entry that also matches all descendants,
+// e.g. "tank/home/**" matches tank/home, tank/home/alice and tank/home/alice/docs
+const PatternRecursiveSuffix = "/**"
+
+// MatchPattern checks if a dataset or pool name matches a whitelist or blacklist entry
+// Entries are exact names, globs in path.Match syntax ("*" does not cross "/") or regexes
+// prefixed with "regex:" (unanchored, use ^ and $ for a full match)
+func MatchPattern(pattern, name string) bool {
+	return matchPattern(pattern, name, nil)
+}
+
+// matchPattern is MatchPattern with the regexes compiled by ValidatePatterns; other regexes are compiled on use
+func matchPattern(pattern, name string, regexes map[string]*regexp.Regexp) bool {
+	base, recursive := strings.CutSuffix(pattern, PatternRecursiveSuffix)
+	if !recursive {
+		return matchSinglePattern(pattern, name, regexes)
+	}
+
+	for _, ancestor := range DatasetAncestors(name) {
+		if matchSinglePattern(base, ancestor, regexes) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSinglePattern matches a pattern without the recursive suffix against a single name
+func matchSinglePattern(pattern, name string, regexes map[string]*regexp.Regexp) bool {
+	if expr, ok := strings.CutPrefix(pattern, PatternRegexPrefix); ok {
+		re := regexes[expr]
+		if re == nil {
+			var err error
+			if re, err = regexp.Compile(expr); err != nil {
+				return false
+			}
+		}
+		return re.MatchString(name)
+	}
+	if isGlob(pattern) {
+		matched, err := path.Match(pattern, name)
+		return err == nil && matched
+	}
+	return pattern == name
+}
+
+// isGlob checks if a pattern contains glob meta characters (which are not valid in ZFS names)
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// PatternRoot returns the dataset a whitelist entry is rooted at, if the entry selects a single
+// dataset ("tank/data") or a single tree ("tank/home/**") and can therefore be passed to zfs list
+func PatternRoot(pattern string) (root string, recursive bool, ok bool) {
+	root, recursive = strings.CutSuffix(pattern, PatternRecursiveSuffix)
+	if root == "" || isGlob(root) || strings.HasPrefix(root, PatternRegexPrefix) {
+		return "", false, false
+	}
+	return root, recursive, true
+}
+
+// matchingPattern returns the first entry of patterns that matches name
+func (c *Config) matchingPattern(patterns []string, name string) (string, bool) {
+	for _, pattern := range patterns {
+		if matchPattern(pattern, name, c.patternRegexes) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// compilePattern checks that a glob or regex entry compiles and returns the regex of a regex entry
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	pattern = strings.TrimSuffix(pattern, PatternRecursiveSuffix)
+	if expr, ok := strings.CutPrefix(pattern, PatternRegexPrefix); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q: %w", expr, err)
+		}
+		return re, nil
+	}
+	if isGlob(pattern) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+		}
+	}
+	return nil, nil
+}
+
+// ValidatePatterns checks all entries of the pool and filesystem whitelists and blacklists and the atomic snapshot roots
+// Invalid entries never match, so they should be rejected at startup
+// The regexes are compiled once and kept for matching, instead of being compiled for every name
+func (c *Config) ValidatePatterns() error {
+	lists := []struct {
+		name     string
+		patterns []string
+	}{
+		{"POOL_WHITELIST", c.PoolWhitelist},
+		{"POOL_BLACKLIST", c.PoolBlacklist},
+		{"FILESYSTEM_WHITELIST", c.FilesystemWhitelist},
+		{"FILESYSTEM_BLACKLIST", c.FilesystemBlacklist},
+		{"ATOMIC_SNAPSHOT_ROOTS", c.AtomicSnapshotRoots},
+	}
+
+	var errs []error
+	regexes := make(map[string]*regexp.Regexp)
+	for _, list := range lists {
+		for _, pattern := range list.patterns {
+			re, err := compilePattern(pattern)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", list.name, err))
+			} else if re != nil {
+				regexes[re.String()] = re
+			}
+		}
+	}
+	c.patternRegexes = regexes
+	return errors.Join(errs...)
+}
+
+// FilterPool checks if a pool passes the whitelist and blacklist, together with the reason if it does not
+// The blacklist wins over the whitelist
+func (c *Config) FilterPool(poolName string) (bool, string) {
+	return c.filterName(c.PoolWhitelist, c.PoolBlacklist, poolName)
+}
+
+// FilterFilesystem checks if a filesystem passes the whitelist and blacklist, together with the reason if it does not
+// The blacklist wins over the whitelist
+func (c *Config) FilterFilesystem(filesystemName string) (bool, string) {
+	return c.filterName(c.FilesystemWhitelist, c.FilesystemBlacklist, filesystemName)
+}
+
+func (c *Config) filterName(whitelist, blacklist []string, name string) (bool, string) {
+	if pattern, ok := c.matchingPattern(blacklist, name); ok {
+		return false, fmt.Sprintf("matches blacklist entry %q", pattern)
+	}
+
+	// If whitelist is empty, everything that is not blacklisted is allowed
+	if len(whitelist) == 0 {
+		return true, ""
+	}
+	if _, ok := c.matchingPattern(whitelist, name); ok {
+		return true, ""
+	}
+	return false, "not in whitelist"
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"tank/home", "tank/home", true},
+		{"tank/home", "tank/home/alice", false},
+		{"tank/vm/*", "tank/vm/web", true},
+		{"tank/vm/*", "tank/vm/web/disk0", false},
+		{"tank/vm/*", "tank/vm", false},
+		{"tank/home/**", "tank/home", true},
+		{"tank/home/**", "tank/home/alice/docs", true},
+		{"tank/home/**", "tank/homes", false},
+		{"tank/vm/*/**", "tank/vm/web/disk0", true},
+		{"tank/vm/*/**", "tank/vm", false},
+		{"regex:^tank/home/[^/]+$", "tank/home/alice", true},
+		{"regex:^tank/home/[^/]+$", "tank/home/alice/docs", false},
+		{"regex:media", "backup/media/movies", true},
+		{"regex:^tank$/**", "tank/data", true},
+		{"regex:[", "tank", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			if got := MatchPattern(tt.pattern, tt.name); got != tt.want {
+				t.Errorf("MatchPattern(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterFilesystem(t *testing.T) {
+	tests := []struct {
+		name       string
+		whitelist  []string
+		blacklist  []string
+		filesystem string
+		want       bool
+		wantReason string
+	}{
+		{
+			name:       "empty lists allow everything",
+			filesystem: "tank/data",
+			want:       true,
+		},
+		{
+			name:       "recursive whitelist includes new child datasets",
+			whitelist:  []string{"tank/home/**"},
+			filesystem: "tank/home/bob",
+			want:       true,
+		},
+		{
+			name:       "not in whitelist",
+			whitelist:  []string{"tank/home/**"},
+			filesystem: "tank/data",
+			want:       false,
+			wantReason: "not in whitelist",
+		},
+		{
+			name:       "blacklist wins over whitelist",
+			whitelist:  []string{"tank/home/**"},
+			blacklist:  []string{"tank/home/*/cache"},
+			filesystem: "tank/home/bob/cache",
+			want:       false,
+			wantReason: `matches blacklist entry "tank/home/*/cache"`,
+		},
+		{
+			name:       "blacklist without whitelist",
+			blacklist:  []string{"regex:/tmp$"},
+			filesystem: "tank/tmp",
+			want:       false,
+			wantReason: `matches blacklist entry "regex:/tmp$"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{FilesystemWhitelist: tt.whitelist, FilesystemBlacklist: tt.blacklist}
+
+			got, reason := cfg.FilterFilesystem(tt.filesystem)
+			if got != tt.want || reason != tt.wantReason {
+				t.Errorf("FilterFilesystem(%q) = (%v, %q), want (%v, %q)", tt.filesystem, got, reason, tt.want, tt.wantReason)
+			}
+			if cfg.IsFilesystemAllowed(tt.filesystem) != tt.want {
+				t.Errorf("IsFilesystemAllowed(%q) != %v", tt.filesystem, tt.want)
+			}
+		})
+	}
+}
+
+func TestPoolBlacklist(t *testing.T) {
+	t.Setenv("POOL_WHITELIST", "tank*")
+	t.Setenv("POOL_BLACKLIST", "tank-old")
+	cfg := NewConfig("test")
+
+	if !cfg.IsPoolAllowed("tank") {
+		t.Error("IsPoolAllowed(tank) = false, want true")
+	}
+	if cfg.IsPoolAllowed("tank-old") {
+		t.Error("IsPoolAllowed(tank-old) = true, want false")
+	}
+	if cfg.IsPoolAllowed("backup") {
+		t.Error("IsPoolAllowed(backup) = true, want false")
+	}
+}
+
+func TestValidatePatterns(t *testing.T) {
+	cfg := &Config{
+		PoolWhitelist:       []string{"tank"},
+		FilesystemWhitelist: []string{"tank/[", "tank/home/**"},
+		FilesystemBlacklist: []string{"regex:(unclosed"},
+	}
+
+	err := cfg.ValidatePatterns()
+	if err == nil {
+		t.Fatal("ValidatePatterns() error = nil, want error")
+	}
+	for _, want := range []string{"FILESYSTEM_WHITELIST: invalid glob", "FILESYSTEM_BLACKLIST: invalid regex"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("ValidatePatterns() error = %v, want %q", err, want)
+		}
+	}
+
+	cfg.FilesystemWhitelist = []string{"tank/home/**"}
+	cfg.FilesystemBlacklist = []string{"regex:/cache$"}
+	if err := cfg.ValidatePatterns(); err != nil {
+		t.Errorf("ValidatePatterns() error = %v, want nil", err)
+	}
+
+	// The regexes are compiled once and used for matching
+	if re := cfg.patternRegexes["/cache$"]; re == nil {
+		t.Fatalf("ValidatePatterns() did not keep the compiled regex, got %v", cfg.patternRegexes)
+	}
+	if allowed, _ := cfg.FilterFilesystem("tank/home/alice/cache"); allowed {
+		t.Error("FilterFilesystem(tank/home/alice/cache) = true, want false")
+	}
+	if allowed, _ := cfg.FilterFilesystem("tank/home/alice"); !allowed {
+		t.Error("FilterFilesystem(tank/home/alice) = false, want true")
+	}
+}
+
+func TestAtomicRoot(t *testing.T) {
+	cfg := NewConfig("test")
+	cfg.AtomicSnapshotRoots = []string{"tank/db/*", "tank/db"}
+
+	root, ok := cfg.AtomicRoot("tank/db/pg/wal")
+	if !ok || root != "tank/db" {
+		t.Errorf("AtomicRoot() = (%q, %v), want (tank/db, true)", root, ok)
+	}
+	if _, ok := cfg.AtomicRoot("tank/media"); ok {
+		t.Error("AtomicRoot(tank/media) should not have a root")
+	}
+}
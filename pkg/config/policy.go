@@ -0,0 +1,380 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+// PolicyFile is the structure of the -config policy file (YAML or JSON)
+//
+// Example:
+//
+//	tiers:
+//	  - name: every-5m
+//	    period: 5m
+//	    retention: 12
+//	  - name: quarterly
+//	    unit: month
+//	    every: 3
+//	    retention: 8
+//	retention_modes:
+//	  daily: count
+//	skip_unchanged: [frequently, hourly]
+//	defaults:
+//	  min_keep: 1
+//	  retention:
+//	    hourly: 24
+//	    daily: 7
+//	pools:
+//	  tank:
+//	    retention:
+//	      daily: 14
+//	datasets:
+//	  tank/media-files.v2:
+//	    retention:
+//	      hourly: 0
+//	  tank/scratch:
+//	    enabled: false
+//	selectors:
+//	  - glob: "tank/vm/*"
+//	    retention:
+//	      hourly: 48
+//	  - regex: "^tank/home/[^/]+$"
+//	    retention:
+//	      daily: 30
+type PolicyFile struct {
+	Tiers          []Tier            `yaml:"tiers"`
+	RetentionModes map[string]string `yaml:"retention_modes"`
+	SkipUnchanged  []string          `yaml:"skip_unchanged"`
+	Defaults       Policy            `yaml:"defaults"`
+	Pools          map[string]Policy `yaml:"pools"`
+	Datasets       map[string]Policy `yaml:"datasets"`
+	Selectors      []SelectorPolicy  `yaml:"selectors"`
+}
+
+// Policy holds retention settings; settings that are not set are inherited from the parent dataset
+type Policy struct {
+	Enabled   *bool          `yaml:"enabled"`  // If false, the dataset (and its descendants) are not managed
+	MinKeep   *int           `yaml:"min_keep"` // Number of snapshots per tier that retention never goes below
+	Retention map[string]int `yaml:"retention"`
+}
+
+// SelectorPolicy applies a policy to every dataset matching a glob or regex
+type SelectorPolicy struct {
+	Glob      string         `yaml:"glob"`
+	Regex     string         `yaml:"regex"`
+	Enabled   *bool          `yaml:"enabled"`
+	MinKeep   *int           `yaml:"min_keep"`
+	Retention map[string]int `yaml:"retention"`
+
+	regex *regexp.Regexp
+}
+
+// Matches checks if the selector matches the dataset name
+func (s *SelectorPolicy) Matches(datasetName string) bool {
+	if s.regex != nil {
+		return s.regex.MatchString(datasetName)
+	}
+	matched, err := path.Match(s.Glob, datasetName)
+	return err == nil && matched
+}
+
+// LoadPolicyFile reads a policy file and applies it to the configuration
+// Global retention env vars (e.g. MAX_HOURLY_SNAPSHOTS) keep precedence over the file defaults,
+// and filesystem-specific env vars keep precedence over the policies of the same dataset
+func (c *Config) LoadPolicyFile(filePath string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	policy, err := ParsePolicyFile(data)
+	if err != nil {
+		return fmt.Errorf("invalid policy file %s: %w", filePath, err)
+	}
+
+	c.applyPolicyFile(policy)
+	c.PolicyFilePath = filePath
+	return nil
+}
+
+// ParsePolicyFile parses and validates a YAML or JSON policy file
+func ParsePolicyFile(data []byte) (*PolicyFile, error) {
+	policy := &PolicyFile{}
+
+	// YAML is a superset of JSON, so one decoder handles both formats
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(policy); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse: %w", err)
+	}
+
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// validate checks the policy file and compiles the selector regexes
+func (p *PolicyFile) validate() error {
+	var errs []error
+
+	if err := validateTiers(p.Tiers); err != nil {
+		errs = append(errs, err)
+	}
+	tiers := Frequencies()
+	for _, tier := range p.Tiers {
+		tiers = append(tiers, tier.Name)
+	}
+	validateRetention := func(field string, retention map[string]int) []error {
+		return validateRetention(field, retention, tiers)
+	}
+
+	for frequency, mode := range p.RetentionModes {
+		if !slices.Contains(tiers, frequency) {
+			errs = append(errs, fmt.Errorf("retention_modes: unknown frequency %q (must be one of %v)", frequency, tiers))
+		}
+		if err := validateRetentionMode(mode); err != nil {
+			errs = append(errs, fmt.Errorf("retention_modes.%s: %w", frequency, err))
+		}
+	}
+
+	for _, frequency := range p.SkipUnchanged {
+		if !slices.Contains(tiers, frequency) {
+			errs = append(errs, fmt.Errorf("skip_unchanged: unknown frequency %q (must be one of %v)", frequency, tiers))
+		}
+	}
+
+	errs = append(errs, validateRetention("defaults", p.Defaults.Retention)...)
+	errs = append(errs, validateMinKeep("defaults", p.Defaults.MinKeep))
+
+	for name, policy := range p.Pools {
+		if name == "" || strings.Contains(name, "/") {
+			errs = append(errs, fmt.Errorf("pools: invalid pool name %q", name))
+		}
+		errs = append(errs, validateRetention(fmt.Sprintf("pools.%s", name), policy.Retention)...)
+		errs = append(errs, validateMinKeep(fmt.Sprintf("pools.%s", name), policy.MinKeep))
+	}
+
+	for name, policy := range p.Datasets {
+		if name == "" || strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") {
+			errs = append(errs, fmt.Errorf("datasets: invalid dataset name %q", name))
+		}
+		errs = append(errs, validateRetention(fmt.Sprintf("datasets.%s", name), policy.Retention)...)
+		errs = append(errs, validateMinKeep(fmt.Sprintf("datasets.%s", name), policy.MinKeep))
+	}
+
+	for i := range p.Selectors {
+		selector := &p.Selectors[i]
+		field := fmt.Sprintf("selectors[%d]", i)
+
+		switch {
+		case selector.Glob == "" && selector.Regex == "":
+			errs = append(errs, fmt.Errorf("%s: one of glob or regex is required", field))
+		case selector.Glob != "" && selector.Regex != "":
+			errs = append(errs, fmt.Errorf("%s: glob and regex are mutually exclusive", field))
+		case selector.Glob != "":
+			if _, err := path.Match(selector.Glob, ""); err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid glob %q: %w", field, selector.Glob, err))
+			}
+		default:
+			re, err := regexp.Compile(selector.Regex)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid regex %q: %w", field, selector.Regex, err))
+			}
+			selector.regex = re
+		}
+
+		errs = append(errs, validateRetention(field, selector.Retention)...)
+		errs = append(errs, validateMinKeep(field, selector.MinKeep))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateRetention checks that all frequencies are known tiers and counts are not negative
+func validateRetention(field string, retention map[string]int, tiers []string) []error {
+	var errs []error
+	for frequency, count := range retention {
+		if !slices.Contains(tiers, frequency) {
+			errs = append(errs, fmt.Errorf("%s: unknown frequency %q (must be one of %v)", field, frequency, tiers))
+		}
+		if count < 0 {
+			errs = append(errs, fmt.Errorf("%s: retention for %s must not be negative, got %d", field, frequency, count))
+		}
+	}
+	return errs
+}
+
+// applyPolicyFile merges a parsed policy file into the configuration
+func (c *Config) applyPolicyFile(policy *PolicyFile) {
+	c.CustomTiers = policy.Tiers
+	for i := range c.CustomTiers {
+		// Global env vars override the retention of a custom tier as well
+		if count := getEnvAsInt(tierEnvKey(c.CustomTiers[i].Name), -1); count >= 0 {
+			c.CustomTiers[i].Retention = count
+		}
+	}
+
+	for frequency, count := range policy.Defaults.Retention {
+		// Global env vars override the file defaults
+		if os.Getenv(tierEnvKey(frequency)) != "" {
+			continue
+		}
+		c.setMaxSnapshots(frequency, count)
+	}
+
+	c.RetentionModes = policy.RetentionModes
+	// The SKIP_UNCHANGED env var replaces the list of the file
+	if os.Getenv("SKIP_UNCHANGED") == "" {
+		c.SkipUnchanged = policy.SkipUnchanged
+	}
+	c.DefaultPolicy = policy.Defaults
+	c.PoolPolicies = policy.Pools
+	c.DatasetPolicies = policy.Datasets
+	c.SelectorPolicies = policy.Selectors
+}
+
+// hasPolicyDefault checks if the defaults section of the policy file sets a frequency
+func (c *Config) hasPolicyDefault(frequency string) bool {
+	_, ok := c.DefaultPolicy.Retention[frequency]
+	return ok
+}
+
+// policyRetention looks up the retention count of a frequency for a single level of the dataset tree
+// An exact dataset policy wins over the first matching selector, which wins over the pool policy
+func (c *Config) policyRetention(frequency, name string) (int, string, bool) {
+	if policy, ok := c.DatasetPolicies[name]; ok {
+		if count, ok := policy.Retention[frequency]; ok {
+			return count, fmt.Sprintf("policy datasets[%s]", name), true
+		}
+	}
+
+	for i := range c.SelectorPolicies {
+		selector := &c.SelectorPolicies[i]
+		if !selector.Matches(name) {
+			continue
+		}
+		if count, ok := selector.Retention[frequency]; ok {
+			return count, fmt.Sprintf("policy selectors[%d] matching %s", i, name), true
+		}
+	}
+
+	if !strings.Contains(name, "/") {
+		if policy, ok := c.PoolPolicies[name]; ok {
+			if count, ok := policy.Retention[frequency]; ok {
+				return count, fmt.Sprintf("policy pools[%s]", name), true
+			}
+		}
+	}
+
+	return 0, "", false
+}
+
+// policyEnabled looks up the enabled setting for a single level of the dataset tree
+func (c *Config) policyEnabled(name string) (bool, string, bool) {
+	if policy, ok := c.DatasetPolicies[name]; ok && policy.Enabled != nil {
+		return *policy.Enabled, fmt.Sprintf("policy datasets[%s]", name), true
+	}
+
+	for i := range c.SelectorPolicies {
+		selector := &c.SelectorPolicies[i]
+		if selector.Enabled != nil && selector.Matches(name) {
+			return *selector.Enabled, fmt.Sprintf("policy selectors[%d] matching %s", i, name), true
+		}
+	}
+
+	if !strings.Contains(name, "/") {
+		if policy, ok := c.PoolPolicies[name]; ok && policy.Enabled != nil {
+			return *policy.Enabled, fmt.Sprintf("policy pools[%s]", name), true
+		}
+	}
+
+	return false, "", false
+}
+
+// ResolveEnabled returns whether a filesystem is managed according to the policy file,
+// together with a description of where the value came from
+// The setting is inherited along the dataset path like ResolveMaxSnapshots
+func (c *Config) ResolveEnabled(filesystemName string) (bool, string) {
+	return c.resolveEnabled(filesystemName, nil)
+}
+
+// ResolvePoolEnabled works like ResolveEnabled, but also honors the com.sun:auto-snapshot user property,
+// which wins over the policies of the level it is set on
+func (c *Config) ResolvePoolEnabled(pool *models.Pool) (bool, string) {
+	return c.resolveEnabled(pool.FilesystemName, pool)
+}
+
+// IsPoolEnabled checks if a dataset is managed according to the policy file and its user properties
+func (c *Config) IsPoolEnabled(pool *models.Pool) bool {
+	enabled, _ := c.ResolvePoolEnabled(pool)
+	return enabled
+}
+
+func (c *Config) resolveEnabled(filesystemName string, pool *models.Pool) (bool, string) {
+	for _, name := range DatasetAncestors(filesystemName) {
+		if enabled, source, ok := c.propertyEnabled(pool, name); ok {
+			return enabled, source
+		}
+		if enabled, source, ok := c.policyEnabled(name); ok {
+			return enabled, source
+		}
+	}
+
+	if c.DefaultPolicy.Enabled != nil {
+		return *c.DefaultPolicy.Enabled, "policy defaults"
+	}
+	return true, "global default"
+}
+
+// IsDatasetEnabled checks if a filesystem is managed according to the policy file
+func (c *Config) IsDatasetEnabled(filesystemName string) bool {
+	enabled, _ := c.ResolveEnabled(filesystemName)
+	return enabled
+}
+
+// setMaxSnapshots sets the global retention count of a built-in or custom tier
+func (c *Config) setMaxSnapshots(frequency string, count int) {
+	switch frequency {
+	case "frequently":
+		c.MaxFrequentlySnapshots = count
+	case "hourly":
+		c.MaxHourlySnapshots = count
+	case "daily":
+		c.MaxDailySnapshots = count
+	case "weekly":
+		c.MaxWeeklySnapshots = count
+	case "monthly":
+		c.MaxMonthlySnapshots = count
+	case "yearly":
+		c.MaxYearlySnapshots = count
+	default:
+		for i := range c.CustomTiers {
+			if c.CustomTiers[i].Name == frequency {
+				c.CustomTiers[i].Retention = count
+			}
+		}
+	}
+}
+
+// IsFrequency checks if frequency is one of the six built-in snapshot frequencies (see Config.IsTier)
+func IsFrequency(frequency string) bool {
+	for _, f := range Frequencies() {
+		if f == frequency {
+			return true
+		}
+	}
+	return false
+}
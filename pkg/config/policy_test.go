@@ -0,0 +1,248 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPolicyYAML = `
+defaults:
+  retention:
+    hourly: 12
+    daily: 10
+pools:
+  tank:
+    retention:
+      weekly: 8
+datasets:
+  tank/media-files.v2:
+    retention:
+      hourly: 0
+selectors:
+  - glob: "tank/vm/*"
+    retention:
+      hourly: 48
+  - regex: "^tank/home/[^/]+$"
+    retention:
+      daily: 30
+`
+
+func writePolicyFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write policy file: %v", err)
+	}
+	return path
+}
+
+func TestLoadPolicyFile(t *testing.T) {
+	cfg := NewConfig("test")
+	if err := cfg.LoadPolicyFile(writePolicyFile(t, "policy.yaml", testPolicyYAML)); err != nil {
+		t.Fatalf("LoadPolicyFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		frequency  string
+		filesystem string
+		want       int
+	}{
+		{"file default", "hourly", "backup/data", 12},
+		{"file default daily", "daily", "backup/data", 10},
+		{"built-in default", "monthly", "backup/data", 12},
+		{"pool policy", "weekly", "tank/data", 8},
+		{"dataset policy with special characters", "hourly", "tank/media-files.v2", 0},
+		{"glob selector", "hourly", "tank/vm/win11", 48},
+		{"descendant inherits glob selector", "hourly", "tank/vm/win11/disk0", 48},
+		{"regex selector", "daily", "tank/home/alice", 30},
+		{"selector falls back to pool policy", "weekly", "tank/home/alice", 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cfg.GetMaxSnapshotsForFrequency(tt.frequency, tt.filesystem)
+			if got != tt.want {
+				t.Errorf("GetMaxSnapshotsForFrequency(%s, %s) = %d, want %d", tt.frequency, tt.filesystem, got, tt.want)
+			}
+		})
+	}
+
+	if cfg.PolicyFilePath == "" {
+		t.Error("PolicyFilePath should be set after loading")
+	}
+}
+
+func TestLoadPolicyFileJSON(t *testing.T) {
+	cfg := NewConfig("test")
+	content := `{"defaults": {"retention": {"daily": 3}}, "datasets": {"tank/data": {"retention": {"daily": 5}}}}`
+	if err := cfg.LoadPolicyFile(writePolicyFile(t, "policy.json", content)); err != nil {
+		t.Fatalf("LoadPolicyFile() error = %v", err)
+	}
+
+	if got := cfg.GetMaxSnapshotsForFrequency("daily"); got != 3 {
+		t.Errorf("Global daily retention = %d, want 3", got)
+	}
+	if got := cfg.GetMaxSnapshotsForFrequency("daily", "tank/data"); got != 5 {
+		t.Errorf("tank/data daily retention = %d, want 5", got)
+	}
+}
+
+func TestLoadPolicyFileEnvPrecedence(t *testing.T) {
+	t.Setenv("MAX_HOURLY_SNAPSHOTS", "36")
+	t.Setenv("MAX_HOURLY_SNAPSHOTS_TANK_VM_WIN11", "6")
+
+	cfg := NewConfig("test")
+	if err := cfg.LoadPolicyFile(writePolicyFile(t, "policy.yaml", testPolicyYAML)); err != nil {
+		t.Fatalf("LoadPolicyFile() error = %v", err)
+	}
+
+	if got := cfg.GetMaxSnapshotsForFrequency("hourly", "backup/data"); got != 36 {
+		t.Errorf("Global env var should override file default: got %d, want 36", got)
+	}
+	if got := cfg.GetMaxSnapshotsForFrequency("hourly", "tank/vm/win11"); got != 6 {
+		t.Errorf("Filesystem env var should override selector: got %d, want 6", got)
+	}
+}
+
+func TestLoadPolicyFileInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"unknown field", "defaults:\n  retentoin:\n    hourly: 1\n", "retentoin"},
+		{"unknown frequency", "defaults:\n  retention:\n    biweekly: 1\n", `unknown frequency "biweekly"`},
+		{"negative retention", "pools:\n  tank:\n    retention:\n      daily: -1\n", "must not be negative"},
+		{"pool name with slash", "pools:\n  tank/data:\n    retention:\n      daily: 1\n", "invalid pool name"},
+		{"selector without pattern", "selectors:\n  - retention:\n      daily: 1\n", "one of glob or regex is required"},
+		{"selector with both patterns", "selectors:\n  - glob: a\n    regex: b\n", "mutually exclusive"},
+		{"invalid glob", "selectors:\n  - glob: \"tank/[\"\n", "invalid glob"},
+		{"invalid regex", "selectors:\n  - regex: \"tank/(\"\n", "invalid regex"},
+		{"malformed yaml", "defaults: [", "failed to parse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig("test")
+			err := cfg.LoadPolicyFile(writePolicyFile(t, "policy.yaml", tt.content))
+			if err == nil {
+				t.Fatal("LoadPolicyFile() should fail")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadPolicyFile() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadPolicyFileMissing(t *testing.T) {
+	cfg := NewConfig("test")
+	if err := cfg.LoadPolicyFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("LoadPolicyFile() should fail for a missing file")
+	}
+}
+
+const testInheritancePolicyYAML = `
+pools:
+  tank:
+    retention:
+      daily: 14
+datasets:
+  tank/media:
+    retention:
+      hourly: 6
+  tank/media/movies/archive:
+    enabled: false
+  tank/scratch:
+    enabled: false
+  tank/scratch/keep:
+    enabled: true
+`
+
+func TestPolicyInheritance(t *testing.T) {
+	t.Setenv("MAX_WEEKLY_SNAPSHOTS_TANK_MEDIA", "9")
+
+	cfg := NewConfig("test")
+	if err := cfg.LoadPolicyFile(writePolicyFile(t, "policy.yaml", testInheritancePolicyYAML)); err != nil {
+		t.Fatalf("LoadPolicyFile() error = %v", err)
+	}
+
+	tests := []struct {
+		frequency  string
+		filesystem string
+		want       int
+		wantSource string
+	}{
+		{"hourly", "tank/media/movies", 6, "policy datasets[tank/media]"},
+		{"daily", "tank/media/movies", 14, "policy pools[tank]"},
+		{"weekly", "tank/media/movies", 9, "env MAX_WEEKLY_SNAPSHOTS_TANK_MEDIA"},
+		{"monthly", "tank/media/movies", 12, "global default"},
+		{"hourly", "tank/data", 24, "global default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.frequency+" "+tt.filesystem, func(t *testing.T) {
+			got, source := cfg.ResolveMaxSnapshots(tt.frequency, tt.filesystem)
+			if got != tt.want || source != tt.wantSource {
+				t.Errorf("ResolveMaxSnapshots(%s, %s) = %d from %q, want %d from %q",
+					tt.frequency, tt.filesystem, got, source, tt.want, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestPolicyEnabledInheritance(t *testing.T) {
+	cfg := NewConfig("test")
+	if err := cfg.LoadPolicyFile(writePolicyFile(t, "policy.yaml", testInheritancePolicyYAML)); err != nil {
+		t.Fatalf("LoadPolicyFile() error = %v", err)
+	}
+
+	tests := []struct {
+		filesystem string
+		want       bool
+	}{
+		{"tank/media/movies", true},
+		{"tank/media/movies/archive", false},
+		{"tank/media/movies/archive/2020", false},
+		{"tank/scratch", false},
+		{"tank/scratch/tmp", false},
+		{"tank/scratch/keep", true},
+	}
+
+	for _, tt := range tests {
+		if got := cfg.IsDatasetEnabled(tt.filesystem); got != tt.want {
+			_, source := cfg.ResolveEnabled(tt.filesystem)
+			t.Errorf("IsDatasetEnabled(%s) = %v (from %s), want %v", tt.filesystem, got, source, tt.want)
+		}
+	}
+}
+
+func TestPolicyDefaultsDisabled(t *testing.T) {
+	cfg := NewConfig("test")
+	content := "defaults:\n  enabled: false\ndatasets:\n  tank/data:\n    enabled: true\n"
+	if err := cfg.LoadPolicyFile(writePolicyFile(t, "policy.yaml", content)); err != nil {
+		t.Fatalf("LoadPolicyFile() error = %v", err)
+	}
+
+	if cfg.IsDatasetEnabled("tank/other") {
+		t.Error("Datasets should be disabled by the policy defaults")
+	}
+	if !cfg.IsDatasetEnabled("tank/data/child") {
+		t.Error("Descendants of an enabled dataset should be enabled")
+	}
+}
+
+func TestDatasetAncestors(t *testing.T) {
+	got := DatasetAncestors("tank/media/movies")
+	want := []string{"tank/media/movies", "tank/media", "tank"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("DatasetAncestors() = %v, want %v", got, want)
+	}
+
+	if got := DatasetAncestors("tank"); len(got) != 1 || got[0] != "tank" {
+		t.Errorf("DatasetAncestors(tank) = %v, want [tank]", got)
+	}
+}
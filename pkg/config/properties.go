@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+)
+
+// AutoSnapshotProperty is the zfs-auto-snapshot user property that opts a dataset in or out
+// Per-frequency variants append the label, e.g. com.sun:auto-snapshot:hourly
+const AutoSnapshotProperty = "com.sun:auto-snapshot"
+
+// RetentionPropertyPrefix is the prefix of the user properties that override retention counts,
+// e.g. zfs-snapshot-operator:hourly=48
+const RetentionPropertyPrefix = "zfs-snapshot-operator:"
+
+// UserProperties returns all user properties the operator reads from zfs list
+func (c *Config) UserProperties() []string {
+	properties := []string{AutoSnapshotProperty}
+	for _, frequency := range c.TierNames() {
+		properties = append(properties, autoSnapshotFrequencyProperty(frequency))
+	}
+	for _, frequency := range c.TierNames() {
+		properties = append(properties, RetentionPropertyPrefix+frequency)
+	}
+	return properties
+}
+
+// autoSnapshotFrequencyProperty returns the per-frequency zfs-auto-snapshot property
+// zfs-auto-snapshot uses the label "frequent" for the 15 minute snapshots
+func autoSnapshotFrequencyProperty(frequency string) string {
+	if frequency == "frequently" {
+		return AutoSnapshotProperty + ":frequent"
+	}
+	return AutoSnapshotProperty + ":" + frequency
+}
+
+// propertySource describes a user property value for ResolvePoolMaxSnapshots and ResolvePoolEnabled
+func propertySource(name string, property models.Property) string {
+	return fmt.Sprintf("property %s=%s on %s", name, property.Value, property.Source)
+}
+
+// propertyEnabled looks up com.sun:auto-snapshot for a single level of the dataset tree
+func (c *Config) propertyEnabled(pool *models.Pool, name string) (bool, string, bool) {
+	property, ok := c.userProperty(pool, AutoSnapshotProperty, name)
+	if !ok {
+		return false, "", false
+	}
+	enabled, err := parsePropertyBool(property.Value)
+	if err != nil {
+		return false, "", false
+	}
+	return enabled, propertySource(AutoSnapshotProperty, property), true
+}
+
+// propertyRetention looks up the retention count of a frequency from the user properties for a single
+// level of the dataset tree
+// com.sun:auto-snapshot:<label>=false disables the frequency, which wins over a retention property
+func (c *Config) propertyRetention(pool *models.Pool, frequency, name string) (int, string, bool) {
+	autoSnapshotName := autoSnapshotFrequencyProperty(frequency)
+	if property, ok := c.userProperty(pool, autoSnapshotName, name); ok {
+		if enabled, err := parsePropertyBool(property.Value); err == nil && !enabled {
+			return 0, propertySource(autoSnapshotName, property), true
+		}
+	}
+
+	retentionName := RetentionPropertyPrefix + frequency
+	if property, ok := c.userProperty(pool, retentionName, name); ok {
+		if count, err := parsePropertyCount(property.Value); err == nil {
+			return count, propertySource(retentionName, property), true
+		}
+	}
+
+	return 0, "", false
+}
+
+// userProperty returns a user property of the pool if it is set on the given level of the dataset tree
+func (c *Config) userProperty(pool *models.Pool, propertyName, level string) (models.Property, bool) {
+	if !c.HonorUserProperties || pool == nil {
+		return models.Property{}, false
+	}
+	property, ok := pool.Properties[propertyName]
+	if !ok || property.Source != level {
+		return models.Property{}, false
+	}
+	return property, true
+}
+
+// ValidateUserProperties returns an error for every operator user property of the pool with an invalid value
+// Invalid values are ignored when resolving the settings
+func (c *Config) ValidateUserProperties(pool *models.Pool) []error {
+	if !c.HonorUserProperties {
+		return nil
+	}
+
+	var errs []error
+	for _, name := range c.UserProperties() {
+		property, ok := pool.Properties[name]
+		if !ok {
+			continue
+		}
+		var err error
+		if strings.HasPrefix(name, RetentionPropertyPrefix) {
+			_, err = parsePropertyCount(property.Value)
+		} else {
+			_, err = parsePropertyBool(property.Value)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("property %s on %s: %w", name, property.Source, err))
+		}
+	}
+	return errs
+}
+
+// parsePropertyBool parses a true/false user property value (case-insensitive, like zfs-auto-snapshot)
+func parsePropertyBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid value %q, must be true or false", value)
+	}
+}
+
+// parsePropertyCount parses a retention count user property value
+func parsePropertyCount(value string) (int, error) {
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 0 {
+		return 0, fmt.Errorf("invalid value %q, must be a non-negative integer", value)
+	}
+	return count, nil
+}
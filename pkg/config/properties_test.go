@@ -0,0 +1,200 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+)
+
+func TestResolvePoolMaxSnapshots(t *testing.T) {
+	tests := []struct {
+		name       string
+		dataset    string
+		properties map[string]models.Property
+		env        map[string]string
+		frequency  string
+		want       int
+		wantSource string
+	}{
+		{
+			name:       "no properties",
+			dataset:    "tank/data",
+			frequency:  "hourly",
+			want:       24,
+			wantSource: "global default",
+		},
+		{
+			name:    "local retention property",
+			dataset: "tank/data",
+			properties: map[string]models.Property{
+				"zfs-snapshot-operator:hourly": {Value: "48", Source: "tank/data"},
+			},
+			frequency:  "hourly",
+			want:       48,
+			wantSource: "property zfs-snapshot-operator:hourly=48 on tank/data",
+		},
+		{
+			name:    "local property wins over env var of the same dataset",
+			dataset: "tank/data",
+			properties: map[string]models.Property{
+				"zfs-snapshot-operator:hourly": {Value: "48", Source: "tank/data"},
+			},
+			env:        map[string]string{"MAX_HOURLY_SNAPSHOTS_TANK_DATA": "12"},
+			frequency:  "hourly",
+			want:       48,
+			wantSource: "property zfs-snapshot-operator:hourly=48 on tank/data",
+		},
+		{
+			name:    "env var of the dataset wins over inherited property",
+			dataset: "tank/data",
+			properties: map[string]models.Property{
+				"zfs-snapshot-operator:hourly": {Value: "48", Source: "tank"},
+			},
+			env:        map[string]string{"MAX_HOURLY_SNAPSHOTS_TANK_DATA": "12"},
+			frequency:  "hourly",
+			want:       12,
+			wantSource: "env MAX_HOURLY_SNAPSHOTS_TANK_DATA",
+		},
+		{
+			name:    "per-frequency auto-snapshot disables frequency",
+			dataset: "tank/data",
+			properties: map[string]models.Property{
+				"com.sun:auto-snapshot:daily": {Value: "false", Source: "tank"},
+				"zfs-snapshot-operator:daily": {Value: "30", Source: "tank"},
+			},
+			frequency:  "daily",
+			want:       0,
+			wantSource: "property com.sun:auto-snapshot:daily=false on tank",
+		},
+		{
+			name:    "frequent label for frequently",
+			dataset: "tank/data",
+			properties: map[string]models.Property{
+				"com.sun:auto-snapshot:frequent": {Value: "FALSE", Source: "tank/data"},
+			},
+			env:        map[string]string{"MAX_FREQUENTLY_SNAPSHOTS": "4"},
+			frequency:  "frequently",
+			want:       0,
+			wantSource: "property com.sun:auto-snapshot:frequent=FALSE on tank/data",
+		},
+		{
+			name:    "invalid retention property is ignored",
+			dataset: "tank/data",
+			properties: map[string]models.Property{
+				"zfs-snapshot-operator:hourly": {Value: "many", Source: "tank/data"},
+			},
+			frequency:  "hourly",
+			want:       24,
+			wantSource: "global default",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			cfg := NewConfig("test")
+			pool := &models.Pool{PoolName: "tank", FilesystemName: tt.dataset, Properties: tt.properties}
+
+			got, source := cfg.ResolvePoolMaxSnapshots(tt.frequency, pool)
+			if got != tt.want || source != tt.wantSource {
+				t.Errorf("ResolvePoolMaxSnapshots() = (%d, %q), want (%d, %q)", got, source, tt.want, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestResolvePoolEnabled(t *testing.T) {
+	policy, err := ParsePolicyFile([]byte(`
+datasets:
+  tank/data:
+    enabled: true
+`))
+	if err != nil {
+		t.Fatalf("ParsePolicyFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		dataset    string
+		properties map[string]models.Property
+		honor      bool
+		want       bool
+		wantSource string
+	}{
+		{
+			name:       "no property",
+			dataset:    "tank/other",
+			honor:      true,
+			want:       true,
+			wantSource: "global default",
+		},
+		{
+			name:    "inherited opt-out",
+			dataset: "tank/other/child",
+			properties: map[string]models.Property{
+				"com.sun:auto-snapshot": {Value: "false", Source: "tank/other"},
+			},
+			honor:      true,
+			want:       false,
+			wantSource: "property com.sun:auto-snapshot=false on tank/other",
+		},
+		{
+			name:    "policy of a closer level wins over inherited property",
+			dataset: "tank/data",
+			properties: map[string]models.Property{
+				"com.sun:auto-snapshot": {Value: "false", Source: "tank"},
+			},
+			honor:      true,
+			want:       true,
+			wantSource: "policy datasets[tank/data]",
+		},
+		{
+			name:    "properties ignored when disabled",
+			dataset: "tank/other",
+			properties: map[string]models.Property{
+				"com.sun:auto-snapshot": {Value: "false", Source: "tank/other"},
+			},
+			honor:      false,
+			want:       true,
+			wantSource: "global default",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig("test")
+			cfg.DatasetPolicies = policy.Datasets
+			cfg.HonorUserProperties = tt.honor
+			pool := &models.Pool{PoolName: "tank", FilesystemName: tt.dataset, Properties: tt.properties}
+
+			got, source := cfg.ResolvePoolEnabled(pool)
+			if got != tt.want || source != tt.wantSource {
+				t.Errorf("ResolvePoolEnabled() = (%t, %q), want (%t, %q)", got, source, tt.want, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestValidateUserProperties(t *testing.T) {
+	cfg := NewConfig("test")
+	pool := &models.Pool{
+		PoolName:       "tank",
+		FilesystemName: "tank/data",
+		Properties: map[string]models.Property{
+			"com.sun:auto-snapshot":        {Value: "yes", Source: "tank"},
+			"zfs-snapshot-operator:hourly": {Value: "-1", Source: "tank/data"},
+			"zfs-snapshot-operator:daily":  {Value: "7", Source: "tank/data"},
+		},
+	}
+
+	errs := cfg.ValidateUserProperties(pool)
+	if len(errs) != 2 {
+		t.Fatalf("ValidateUserProperties() returned %d error(s), want 2: %v", len(errs), errs)
+	}
+	if !strings.Contains(errs[0].Error(), "com.sun:auto-snapshot on tank") {
+		t.Errorf("Unexpected error: %v", errs[0])
+	}
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Retention modes (RETENTION_MODE, RETENTION_MODE_<TIER>)
+const (
+	RetentionModeWindow = "window" // Keep the period keepers of the last N calendar periods
+	RetentionModeCount  = "count"  // Keep the N newest period keepers regardless of their age
+	RetentionModeBoth   = "both"   // Keep a snapshot if either of the two modes keeps it
+)
+
+// GetRetentionMode returns the retention mode of a tier
+// A RETENTION_MODE_<TIER> env var wins over the retention_modes of the policy file,
+// which win over the global RETENTION_MODE
+func (c *Config) GetRetentionMode(frequency string) string {
+	if mode := os.Getenv(tierModeEnvKey(frequency)); mode != "" {
+		return mode
+	}
+	if mode, ok := c.RetentionModes[frequency]; ok {
+		return mode
+	}
+	return c.RetentionMode
+}
+
+// ValidateRetentionModes checks that the global and all per-tier retention modes are known
+func (c *Config) ValidateRetentionModes() error {
+	var errs []error
+	if err := validateRetentionMode(c.RetentionMode); err != nil {
+		errs = append(errs, fmt.Errorf("RETENTION_MODE: %w", err))
+	}
+	for frequency := range c.RetentionModes {
+		if !c.IsTier(frequency) {
+			errs = append(errs, fmt.Errorf("retention_modes: unknown frequency %q (must be one of %v)", frequency, c.TierNames()))
+		}
+	}
+	for _, frequency := range c.TierNames() {
+		if err := validateRetentionMode(c.GetRetentionMode(frequency)); err != nil {
+			errs = append(errs, fmt.Errorf("retention mode of %s: %w", frequency, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateRetentionMode checks that mode is one of the retention modes
+func validateRetentionMode(mode string) error {
+	switch mode {
+	case RetentionModeWindow, RetentionModeCount, RetentionModeBoth:
+		return nil
+	}
+	return fmt.Errorf("unknown retention mode %q (must be %q, %q or %q)",
+		mode, RetentionModeWindow, RetentionModeCount, RetentionModeBoth)
+}
+
+// tierModeEnvKey returns the retention mode env var of a tier (e.g. RETENTION_MODE_DAILY)
+func tierModeEnvKey(name string) string {
+	return "RETENTION_MODE_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetRetentionMode(t *testing.T) {
+	t.Setenv("RETENTION_MODE_WEEKLY", "both")
+
+	cfg := NewConfig("test")
+	cfg.RetentionMode = RetentionModeCount
+	cfg.RetentionModes = map[string]string{"daily": RetentionModeWindow, "weekly": RetentionModeWindow}
+
+	tests := []struct {
+		frequency string
+		want      string
+	}{
+		{"hourly", RetentionModeCount},
+		{"daily", RetentionModeWindow},
+		{"weekly", RetentionModeBoth},
+	}
+	for _, tt := range tests {
+		if got := cfg.GetRetentionMode(tt.frequency); got != tt.want {
+			t.Errorf("GetRetentionMode(%s) = %s, want %s", tt.frequency, got, tt.want)
+		}
+	}
+}
+
+func TestValidateRetentionModes(t *testing.T) {
+	tests := []struct {
+		name    string
+		global  string
+		modes   map[string]string
+		env     string
+		wantErr string
+	}{
+		{"default", RetentionModeWindow, nil, "", ""},
+		{"per tier", RetentionModeWindow, map[string]string{"daily": RetentionModeCount}, "", ""},
+		{"unknown global mode", "newest", nil, "", "RETENTION_MODE"},
+		{"unknown tier", RetentionModeWindow, map[string]string{"biweekly": RetentionModeCount}, "", `unknown frequency "biweekly"`},
+		{"unknown tier env mode", RetentionModeWindow, nil, "forever", "retention mode of hourly"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("RETENTION_MODE_HOURLY", tt.env)
+			cfg := NewConfig("test")
+			cfg.RetentionMode = tt.global
+			cfg.RetentionModes = tt.modes
+
+			err := cfg.ValidateRetentionModes()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateRetentionModes() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateRetentionModes() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadPolicyFileRetentionModes(t *testing.T) {
+	cfg := NewConfig("test")
+	content := "tiers:\n  - name: quarterly\n    unit: month\n    every: 3\nretention_modes:\n  daily: count\n  quarterly: both\n"
+	if err := cfg.LoadPolicyFile(writePolicyFile(t, "policy.yaml", content)); err != nil {
+		t.Fatalf("LoadPolicyFile() error = %v", err)
+	}
+	if got := cfg.GetRetentionMode("daily"); got != RetentionModeCount {
+		t.Errorf("GetRetentionMode(daily) = %s, want count", got)
+	}
+	if got := cfg.GetRetentionMode("quarterly"); got != RetentionModeBoth {
+		t.Errorf("GetRetentionMode(quarterly) = %s, want both", got)
+	}
+
+	err := cfg.LoadPolicyFile(writePolicyFile(t, "invalid.yaml", "retention_modes:\n  daily: newest\n"))
+	if err == nil || !strings.Contains(err.Error(), "unknown retention mode") {
+		t.Errorf("LoadPolicyFile() error = %v, want an unknown retention mode error", err)
+	}
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ValidateSpacePressure checks the thresholds of space-pressure pruning
+// The target must lie below the threshold, otherwise every run that starts pruning would prune down to the floors
+func (c *Config) ValidateSpacePressure() error {
+	if c.SpacePressureThreshold == 0 {
+		return nil
+	}
+
+	var errs []error
+	if c.SpacePressureThreshold < 0 || c.SpacePressureThreshold > 100 {
+		errs = append(errs, fmt.Errorf("SPACE_PRESSURE_THRESHOLD: %d is not a percentage between 0 and 100", c.SpacePressureThreshold))
+	}
+	if c.SpacePressureTarget <= 0 || c.SpacePressureTarget >= c.SpacePressureThreshold {
+		errs = append(errs, fmt.Errorf("SPACE_PRESSURE_TARGET: %d must be between 0 and SPACE_PRESSURE_THRESHOLD (%d)",
+			c.SpacePressureTarget, c.SpacePressureThreshold))
+	}
+	return errors.Join(errs...)
+}
+
+// SpacePressureEnabled checks if space-pressure pruning is configured
+func (c *Config) SpacePressureEnabled() bool {
+	return c.SpacePressureThreshold > 0
+}
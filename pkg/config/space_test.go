@@ -0,0 +1,30 @@
+package config
+
+import "testing"
+
+func TestValidateSpacePressure(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int
+		target    int
+		wantErr   bool
+	}{
+		{"disabled", 0, 80, false},
+		{"disabled ignores target", 0, 0, false},
+		{"valid", 90, 80, false},
+		{"threshold above 100", 120, 80, true},
+		{"negative threshold", -1, 80, true},
+		{"target equal to threshold", 90, 90, true},
+		{"target above threshold", 85, 90, true},
+		{"zero target", 90, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{SpacePressureThreshold: tt.threshold, SpacePressureTarget: tt.target}
+			err := cfg.ValidateSpacePressure()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSpacePressure() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
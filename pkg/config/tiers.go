@@ -0,0 +1,304 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Calendar units of a tier period
+const (
+	UnitMinute = "minute"
+	UnitHour   = "hour"
+	UnitDay    = "day"
+	UnitWeek   = "week"
+	UnitMonth  = "month"
+	UnitYear   = "year"
+)
+
+// Tier is a snapshot frequency: how long its periods are and how many snapshots are kept
+// One snapshot is created per period. Periods are calendar periods in the configured time zone:
+// minute and hour periods are aligned to midnight, week periods to ISO weeks, month and year
+// periods to the start of the year
+type Tier struct {
+	Name      string `yaml:"name"`
+	Period    string `yaml:"period"`    // Go duration of at most one day in whole minutes (e.g. 5m, 6h), instead of unit
+	Unit      string `yaml:"unit"`      // Calendar unit: minute, hour, day, week, month or year
+	Every     int    `yaml:"every"`     // Number of units per period (default 1), e.g. unit month every 3 for quarterly
+	Retention int    `yaml:"retention"` // Number of snapshots to keep unless overridden (0 = disabled)
+}
+
+// builtinTiers are the six fixed frequencies, their retention comes from the MAX_*_SNAPSHOTS settings
+var builtinTiers = []Tier{
+	{Name: "frequently", Unit: UnitMinute, Every: 15},
+	{Name: "hourly", Unit: UnitHour, Every: 1},
+	{Name: "daily", Unit: UnitDay, Every: 1},
+	{Name: "weekly", Unit: UnitWeek, Every: 1},
+	{Name: "monthly", Unit: UnitMonth, Every: 1},
+	{Name: "yearly", Unit: UnitYear, Every: 1},
+}
+
+// tierNamePattern restricts tier names to characters that are safe in snapshot names and env var names
+var tierNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// BuiltinTier returns one of the six fixed frequencies by name
+func BuiltinTier(name string) (Tier, bool) {
+	for _, tier := range builtinTiers {
+		if tier.Name == name {
+			return tier, true
+		}
+	}
+	return Tier{}, false
+}
+
+// Tiers returns the built-in and custom tiers, ordered by period length
+func (c *Config) Tiers() []Tier {
+	tiers := append(append([]Tier(nil), builtinTiers...), c.CustomTiers...)
+	sort.SliceStable(tiers, func(i, j int) bool {
+		return tiers[i].ApproximatePeriod() < tiers[j].ApproximatePeriod()
+	})
+	return tiers
+}
+
+// TierNames returns the names of all tiers, ordered by period length
+func (c *Config) TierNames() []string {
+	tiers := c.Tiers()
+	names := make([]string, len(tiers))
+	for i, tier := range tiers {
+		names[i] = tier.Name
+	}
+	return names
+}
+
+// Tier returns a built-in or custom tier by name
+func (c *Config) Tier(name string) (Tier, bool) {
+	if tier, ok := BuiltinTier(name); ok {
+		return tier, true
+	}
+	for _, tier := range c.CustomTiers {
+		if tier.Name == name {
+			return tier, true
+		}
+	}
+	return Tier{}, false
+}
+
+// IsTier checks if name is a built-in or custom tier
+func (c *Config) IsTier(name string) bool {
+	_, ok := c.Tier(name)
+	return ok
+}
+
+// normalize validates a custom tier and converts a period duration into minutes
+func (t *Tier) normalize() error {
+	if !tierNamePattern.MatchString(t.Name) {
+		return fmt.Errorf("invalid tier name %q (must match %s)", t.Name, tierNamePattern)
+	}
+	if _, ok := BuiltinTier(t.Name); ok {
+		return fmt.Errorf("tier %s: name is already used by a built-in frequency", t.Name)
+	}
+	if t.Retention < 0 {
+		return fmt.Errorf("tier %s: retention must not be negative, got %d", t.Name, t.Retention)
+	}
+
+	switch {
+	case t.Period != "" && t.Unit != "":
+		return fmt.Errorf("tier %s: period and unit are mutually exclusive", t.Name)
+	case t.Period != "":
+		period, err := time.ParseDuration(t.Period)
+		if err != nil {
+			return fmt.Errorf("tier %s: invalid period: %w", t.Name, err)
+		}
+		if period <= 0 || period > 24*time.Hour || period%time.Minute != 0 {
+			return fmt.Errorf("tier %s: period must be whole minutes between 1m and 24h, use a unit for longer periods", t.Name)
+		}
+		if t.Every != 0 {
+			return fmt.Errorf("tier %s: every can only be combined with a unit", t.Name)
+		}
+		t.Unit = UnitMinute
+		t.Every = int(period / time.Minute)
+		t.Period = ""
+	case t.Unit != "":
+		switch t.Unit {
+		case UnitMinute, UnitHour, UnitDay, UnitWeek, UnitMonth, UnitYear:
+		default:
+			return fmt.Errorf("tier %s: unknown unit %q", t.Name, t.Unit)
+		}
+		if t.Every == 0 {
+			t.Every = 1
+		}
+		if t.Every < 0 {
+			return fmt.Errorf("tier %s: every must be positive, got %d", t.Name, t.Every)
+		}
+		if (t.Unit == UnitMinute && t.Every > 24*60) || (t.Unit == UnitHour && t.Every > 24) {
+			return fmt.Errorf("tier %s: %s periods must not be longer than a day, use the day unit", t.Name, t.Unit)
+		}
+	default:
+		return fmt.Errorf("tier %s: one of period or unit is required", t.Name)
+	}
+	return nil
+}
+
+// validateTiers checks custom tiers and normalizes their periods
+func validateTiers(tiers []Tier) error {
+	var errs []error
+	seen := make(map[string]bool)
+	for i := range tiers {
+		if err := tiers[i].normalize(); err != nil {
+			errs = append(errs, fmt.Errorf("tiers[%d]: %w", i, err))
+			continue
+		}
+		if seen[tiers[i].Name] {
+			errs = append(errs, fmt.Errorf("tiers[%d]: duplicate tier %s", i, tiers[i].Name))
+		}
+		seen[tiers[i].Name] = true
+	}
+	return errors.Join(errs...)
+}
+
+// ApproximatePeriod returns the nominal length of one period, used for ordering tiers
+// Months count as four weeks and years as 52 weeks; retention windows use PeriodsBack instead
+func (t Tier) ApproximatePeriod() time.Duration {
+	every := time.Duration(t.Every)
+	switch t.Unit {
+	case UnitMinute:
+		return every * time.Minute
+	case UnitHour:
+		return every * time.Hour
+	case UnitDay:
+		return every * 24 * time.Hour
+	case UnitWeek:
+		return every * 7 * 24 * time.Hour
+	case UnitMonth:
+		return every * 4 * 7 * 24 * time.Hour
+	case UnitYear:
+		return every * 52 * 7 * 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// PeriodStart returns the start of the period containing t, in the location of t
+func (t Tier) PeriodStart(at time.Time) time.Time {
+	year, month, day := at.Date()
+	loc := at.Location()
+
+	switch t.Unit {
+	case UnitMinute:
+		minute := (at.Hour()*60 + at.Minute()) / t.Every * t.Every
+		return time.Date(year, month, day, minute/60, minute%60, 0, 0, loc)
+	case UnitHour:
+		return time.Date(year, month, day, at.Hour()/t.Every*t.Every, 0, 0, 0, loc)
+	case UnitDay:
+		yearDay := (at.YearDay()-1)/t.Every*t.Every + 1
+		return time.Date(year, 1, yearDay, 0, 0, 0, 0, loc)
+	case UnitWeek:
+		isoYear, week := at.ISOWeek()
+		week = (week-1)/t.Every*t.Every + 1
+		return isoWeekStart(isoYear, loc).AddDate(0, 0, (week-1)*7)
+	case UnitMonth:
+		return time.Date(year, (month-1)/time.Month(t.Every)*time.Month(t.Every)+1, 1, 0, 0, 0, 0, loc)
+	case UnitYear:
+		return time.Date(year/t.Every*t.Every, 1, 1, 0, 0, 0, 0, loc)
+	default:
+		return at.Truncate(time.Second)
+	}
+}
+
+// NextPeriodStart returns the start of the period following the one containing t
+// Periods that do not divide the enclosing day, ISO year or year evenly end early at its boundary
+func (t Tier) NextPeriodStart(at time.Time) time.Time {
+	start := t.PeriodStart(at)
+	year, month, day := start.Date()
+	loc := start.Location()
+
+	var next, boundary time.Time
+	switch t.Unit {
+	case UnitMinute:
+		next = start.Add(time.Duration(t.Every) * time.Minute)
+		boundary = time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+	case UnitHour:
+		next = start.Add(time.Duration(t.Every) * time.Hour)
+		boundary = time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+	case UnitDay:
+		next = time.Date(year, month, day+t.Every, 0, 0, 0, 0, loc)
+		boundary = time.Date(year+1, 1, 1, 0, 0, 0, 0, loc)
+	case UnitWeek:
+		next = time.Date(year, month, day+7*t.Every, 0, 0, 0, 0, loc)
+		isoYear, _ := at.ISOWeek()
+		boundary = isoWeekStart(isoYear+1, loc)
+	case UnitMonth:
+		next = time.Date(year, month+time.Month(t.Every), 1, 0, 0, 0, 0, loc)
+		boundary = time.Date(year+1, 1, 1, 0, 0, 0, 0, loc)
+	case UnitYear:
+		return time.Date(year+t.Every, 1, 1, 0, 0, 0, 0, loc)
+	default:
+		return at.Add(time.Second).Truncate(time.Second)
+	}
+
+	if next.After(boundary) {
+		return boundary
+	}
+	return next
+}
+
+// PeriodsBack returns the start of the period n periods before the one containing at
+// Day, week, month and year periods are stepped back with AddDate, so the result follows the calendar
+// (e.g. twelve monthly periods are twelve calendar months, regardless of their length)
+func (t Tier) PeriodsBack(at time.Time, n int) time.Time {
+	start := t.PeriodStart(at)
+	for i := 0; i < n; i++ {
+		var previous time.Time
+		switch t.Unit {
+		case UnitDay:
+			previous = start.AddDate(0, 0, -1)
+		case UnitWeek:
+			previous = start.AddDate(0, 0, -7)
+		case UnitMonth:
+			previous = start.AddDate(0, -1, 0)
+		case UnitYear:
+			previous = start.AddDate(-1, 0, 0)
+		default:
+			previous = start.Add(-time.Nanosecond)
+		}
+		start = t.PeriodStart(previous)
+	}
+	return start
+}
+
+// PeriodKey returns a unique key for the period containing t, e.g. "2026-01-25 14" for an hourly tier
+func (t Tier) PeriodKey(at time.Time) string {
+	start := t.PeriodStart(at)
+	switch t.Unit {
+	case UnitMinute:
+		return start.Format("2006-01-02 15:04")
+	case UnitHour:
+		return start.Format("2006-01-02 15")
+	case UnitDay:
+		return start.Format("2006-01-02")
+	case UnitWeek:
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case UnitMonth:
+		return start.Format("2006-01")
+	case UnitYear:
+		return start.Format("2006")
+	default:
+		return at.Format("2006-01-02 15:04:05")
+	}
+}
+
+// isoWeekStart returns the Monday of ISO week 1 of an ISO year
+func isoWeekStart(isoYear int, loc *time.Location) time.Time {
+	// January 4th is always in week 1
+	jan4 := time.Date(isoYear, 1, 4, 0, 0, 0, 0, loc)
+	return jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+}
+
+// tierEnvKey returns the global retention env var of a tier (e.g. MAX_HOURLY_SNAPSHOTS, MAX_EVERY_5M_SNAPSHOTS)
+func tierEnvKey(name string) string {
+	return "MAX_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_SNAPSHOTS"
+}
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTierNormalize(t *testing.T) {
+	tests := []struct {
+		name      string
+		tier      Tier
+		wantUnit  string
+		wantEvery int
+		wantErr   string
+	}{
+		{"period in minutes", Tier{Name: "every-5m", Period: "5m"}, UnitMinute, 5, ""},
+		{"period in hours", Tier{Name: "every-6h", Period: "6h"}, UnitMinute, 360, ""},
+		{"unit defaults to every 1", Tier{Name: "quarterly", Unit: UnitMonth, Every: 3}, UnitMonth, 3, ""},
+		{"unit without every", Tier{Name: "biweekly", Unit: UnitWeek}, UnitWeek, 1, ""},
+		{"invalid name", Tier{Name: "Every 5m", Period: "5m"}, "", 0, "invalid tier name"},
+		{"built-in name", Tier{Name: "hourly", Period: "1h"}, "", 0, "built-in frequency"},
+		{"missing period", Tier{Name: "x"}, "", 0, "one of period or unit is required"},
+		{"period and unit", Tier{Name: "x", Period: "5m", Unit: UnitMinute}, "", 0, "mutually exclusive"},
+		{"invalid period", Tier{Name: "x", Period: "five"}, "", 0, "invalid period"},
+		{"period longer than a day", Tier{Name: "x", Period: "48h"}, "", 0, "use a unit"},
+		{"period with seconds", Tier{Name: "x", Period: "90s"}, "", 0, "whole minutes"},
+		{"every with period", Tier{Name: "x", Period: "5m", Every: 2}, "", 0, "every can only be combined"},
+		{"unknown unit", Tier{Name: "x", Unit: "fortnight"}, "", 0, "unknown unit"},
+		{"negative every", Tier{Name: "x", Unit: UnitDay, Every: -1}, "", 0, "must be positive"},
+		{"hours longer than a day", Tier{Name: "x", Unit: UnitHour, Every: 36}, "", 0, "use the day unit"},
+		{"negative retention", Tier{Name: "x", Period: "5m", Retention: -1}, "", 0, "must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tier := tt.tier
+			err := tier.normalize()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("normalize() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalize() error = %v", err)
+			}
+			if tier.Unit != tt.wantUnit || tier.Every != tt.wantEvery {
+				t.Errorf("normalize() = %s x%d, want %s x%d", tier.Unit, tier.Every, tt.wantUnit, tt.wantEvery)
+			}
+		})
+	}
+}
+
+func TestTierPeriods(t *testing.T) {
+	tests := []struct {
+		name     string
+		tier     Tier
+		at       time.Time
+		wantKey  string
+		wantNext time.Time
+	}{
+		{"every 5 minutes", Tier{Unit: UnitMinute, Every: 5},
+			time.Date(2026, 1, 25, 14, 37, 10, 0, time.UTC), "2026-01-25 14:35",
+			time.Date(2026, 1, 25, 14, 40, 0, 0, time.UTC)},
+		{"every 6 hours", Tier{Unit: UnitHour, Every: 6},
+			time.Date(2026, 1, 25, 14, 37, 0, 0, time.UTC), "2026-01-25 12",
+			time.Date(2026, 1, 25, 18, 0, 0, 0, time.UTC)},
+		{"every 7 hours ends at midnight", Tier{Unit: UnitHour, Every: 7},
+			time.Date(2026, 1, 25, 22, 0, 0, 0, time.UTC), "2026-01-25 21",
+			time.Date(2026, 1, 26, 0, 0, 0, 0, time.UTC)},
+		{"quarterly", Tier{Unit: UnitMonth, Every: 3},
+			time.Date(2026, 5, 17, 9, 0, 0, 0, time.UTC), "2026-04",
+			time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"last quarter ends at new year", Tier{Unit: UnitMonth, Every: 3},
+			time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC), "2026-10",
+			time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"every 2 weeks", Tier{Unit: UnitWeek, Every: 2},
+			time.Date(2026, 1, 14, 0, 0, 0, 0, time.UTC), "2026-W03",
+			time.Date(2026, 1, 26, 0, 0, 0, 0, time.UTC)},
+		{"built-in hourly", Tier{Unit: UnitHour, Every: 1},
+			time.Date(2026, 1, 25, 14, 37, 0, 0, time.UTC), "2026-01-25 14",
+			time.Date(2026, 1, 25, 15, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tier.PeriodKey(tt.at); got != tt.wantKey {
+				t.Errorf("PeriodKey() = %s, want %s", got, tt.wantKey)
+			}
+			next := tt.tier.NextPeriodStart(tt.at)
+			if !next.Equal(tt.wantNext) {
+				t.Errorf("NextPeriodStart() = %v, want %v", next, tt.wantNext)
+			}
+			if tt.tier.PeriodKey(next) == tt.wantKey {
+				t.Errorf("PeriodKey(NextPeriodStart()) should start a new period")
+			}
+		})
+	}
+}
+
+const testTiersYAML = `
+tiers:
+  - name: every-5m
+    period: 5m
+    retention: 12
+  - name: quarterly
+    unit: month
+    every: 3
+    retention: 8
+datasets:
+  tank/data:
+    retention:
+      quarterly: 4
+`
+
+func TestLoadPolicyFileTiers(t *testing.T) {
+	t.Setenv("MAX_EVERY_5M_SNAPSHOTS", "24")
+
+	cfg := NewConfig("test")
+	if err := cfg.LoadPolicyFile(writePolicyFile(t, "policy.yaml", testTiersYAML)); err != nil {
+		t.Fatalf("LoadPolicyFile() error = %v", err)
+	}
+
+	wantNames := []string{"every-5m", "frequently", "hourly", "daily", "weekly", "monthly", "quarterly", "yearly"}
+	if got := cfg.TierNames(); strings.Join(got, ",") != strings.Join(wantNames, ",") {
+		t.Errorf("TierNames() = %v, want %v", got, wantNames)
+	}
+
+	tier, ok := cfg.Tier("every-5m")
+	if !ok || tier.Unit != UnitMinute || tier.Every != 5 {
+		t.Errorf("Tier(every-5m) = %+v, %t, want a normalized 5 minute tier", tier, ok)
+	}
+
+	tests := []struct {
+		frequency  string
+		filesystem string
+		want       int
+	}{
+		{"every-5m", "backup/data", 24},
+		{"quarterly", "backup/data", 8},
+		{"quarterly", "tank/data", 4},
+		{"biweekly", "backup/data", 0},
+	}
+	for _, tt := range tests {
+		if got := cfg.GetMaxSnapshotsForFrequency(tt.frequency, tt.filesystem); got != tt.want {
+			t.Errorf("GetMaxSnapshotsForFrequency(%s, %s) = %d, want %d", tt.frequency, tt.filesystem, got, tt.want)
+		}
+	}
+
+	if cfg.IsTier("biweekly") || !cfg.IsTier("quarterly") {
+		t.Error("IsTier() should only know built-in and configured tiers")
+	}
+}
+
+func TestLoadPolicyFileTiersInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"duplicate tier", "tiers:\n  - name: a\n    period: 5m\n  - name: a\n    period: 10m\n", "duplicate tier a"},
+		{"invalid tier", "tiers:\n  - name: a\n", "one of period or unit is required"},
+		{"retention for unknown tier", "tiers:\n  - name: a\n    period: 5m\ndefaults:\n  retention:\n    b: 1\n", `unknown frequency "b"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig("test")
+			err := cfg.LoadPolicyFile(writePolicyFile(t, "policy.yaml", tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadPolicyFile() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTierPeriodsBack(t *testing.T) {
+	at := time.Date(2026, 5, 31, 14, 37, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		tier Tier
+		n    int
+		want time.Time
+	}{
+		{"current period", Tier{Unit: UnitMonth, Every: 1}, 0, time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"months across year end", Tier{Unit: UnitMonth, Every: 1}, 6, time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"quarters", Tier{Unit: UnitMonth, Every: 3}, 2, time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)},
+		{"every 5 minutes across midnight", Tier{Unit: UnitMinute, Every: 5}, 12 * 15, time.Date(2026, 5, 30, 23, 35, 0, 0, time.UTC)},
+		{"every 7 hours with a short last period", Tier{Unit: UnitHour, Every: 7}, 3, time.Date(2026, 5, 30, 21, 0, 0, 0, time.UTC)},
+		{"weeks", Tier{Unit: UnitWeek, Every: 1}, 2, time.Date(2026, 5, 11, 0, 0, 0, 0, time.UTC)},
+		{"years", Tier{Unit: UnitYear, Every: 1}, 4, time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tier.PeriodsBack(at, tt.n); !got.Equal(tt.want) {
+				t.Errorf("PeriodsBack(%d) = %v, want %v", tt.n, got, tt.want)
+			}
+		})
+	}
+}
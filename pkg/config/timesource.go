@@ -0,0 +1,19 @@
+package config
+
+import "fmt"
+
+// Snapshot time sources (SNAPSHOT_TIME_SOURCE)
+const (
+	TimeSourceName     = "name"     // Timestamp embedded in the snapshot name
+	TimeSourceCreation = "creation" // ZFS creation property of the snapshot
+)
+
+// ValidateSnapshotTimeSource checks that SNAPSHOT_TIME_SOURCE is a known time source
+func (c *Config) ValidateSnapshotTimeSource() error {
+	switch c.SnapshotTimeSource {
+	case TimeSourceName, TimeSourceCreation:
+		return nil
+	}
+	return fmt.Errorf("SNAPSHOT_TIME_SOURCE: unknown time source %q (must be %q or %q)",
+		c.SnapshotTimeSource, TimeSourceName, TimeSourceCreation)
+}
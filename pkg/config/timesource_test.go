@@ -0,0 +1,31 @@
+package config
+
+import "testing"
+
+func TestValidateSnapshotTimeSource(t *testing.T) {
+	tests := []struct {
+		source  string
+		wantErr bool
+	}{
+		{TimeSourceName, false},
+		{TimeSourceCreation, false},
+		{"mtime", true},
+		{"", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			cfg := &Config{SnapshotTimeSource: tt.source}
+			if err := cfg.ValidateSnapshotTimeSource(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSnapshotTimeSource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSnapshotTimeSourceDefault(t *testing.T) {
+	t.Setenv("SNAPSHOT_TIME_SOURCE", "")
+	if cfg := NewConfig("test"); cfg.SnapshotTimeSource != TimeSourceName {
+		t.Errorf("SnapshotTimeSource = %q, want %q", cfg.SnapshotTimeSource, TimeSourceName)
+	}
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// SetTimezone sets the time zone used for naming, parsing and bucketing snapshots
+// An empty name selects the local time zone of the process
+func (c *Config) SetTimezone(name string) error {
+	if name == "" {
+		c.Timezone = ""
+		c.location = nil
+		return nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("invalid time zone %q: %w", name, err)
+	}
+	c.Timezone = name
+	c.location = loc
+	return nil
+}
+
+// Location returns the configured time zone, or the local time zone if none is set
+func (c *Config) Location() *time.Location {
+	if c.location != nil {
+		return c.location
+	}
+	return time.Local
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetTimezone(t *testing.T) {
+	cfg := &Config{}
+	if cfg.Location() != time.Local {
+		t.Errorf("Location() = %v, want the local time zone by default", cfg.Location())
+	}
+
+	if err := cfg.SetTimezone("Europe/Berlin"); err != nil {
+		t.Fatalf("SetTimezone(Europe/Berlin) error = %v", err)
+	}
+	if cfg.Location().String() != "Europe/Berlin" || cfg.Timezone != "Europe/Berlin" {
+		t.Errorf("Location() = %v, Timezone = %q, want Europe/Berlin", cfg.Location(), cfg.Timezone)
+	}
+
+	if err := cfg.SetTimezone("Mars/Olympus_Mons"); err == nil {
+		t.Error("SetTimezone(Mars/Olympus_Mons) expected an error")
+	}
+	if cfg.Location().String() != "Europe/Berlin" {
+		t.Errorf("Location() = %v after an invalid time zone, want Europe/Berlin", cfg.Location())
+	}
+
+	if err := cfg.SetTimezone(""); err != nil {
+		t.Fatalf("SetTimezone(\"\") error = %v", err)
+	}
+	if cfg.Location() != time.Local {
+		t.Errorf("Location() = %v, want the local time zone", cfg.Location())
+	}
+}
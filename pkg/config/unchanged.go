@@ -0,0 +1,24 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// IsSkipUnchanged checks if a tier skips creating snapshots of datasets that have not changed
+// since the newest snapshot of the tier (SKIP_UNCHANGED or skip_unchanged in the policy file)
+func (c *Config) IsSkipUnchanged(frequency string) bool {
+	return slices.Contains(c.SkipUnchanged, frequency)
+}
+
+// ValidateSkipUnchanged checks that SKIP_UNCHANGED only names known tiers
+func (c *Config) ValidateSkipUnchanged() error {
+	var errs []error
+	for _, frequency := range c.SkipUnchanged {
+		if !c.IsTier(frequency) {
+			errs = append(errs, fmt.Errorf("SKIP_UNCHANGED: unknown frequency %q (must be one of %v)", frequency, c.TierNames()))
+		}
+	}
+	return errors.Join(errs...)
+}
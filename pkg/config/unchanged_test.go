@@ -0,0 +1,44 @@
+package config
+
+import "testing"
+
+func TestSkipUnchanged(t *testing.T) {
+	const policyYAML = `
+tiers:
+  - name: every-5m
+    period: 5m
+    retention: 12
+skip_unchanged: [every-5m, hourly]
+`
+	cfg := NewConfig("test")
+	if err := cfg.LoadPolicyFile(writePolicyFile(t, "policy.yaml", policyYAML)); err != nil {
+		t.Fatalf("LoadPolicyFile() error = %v", err)
+	}
+	if err := cfg.ValidateSkipUnchanged(); err != nil {
+		t.Errorf("ValidateSkipUnchanged() error = %v", err)
+	}
+	if !cfg.IsSkipUnchanged("every-5m") || !cfg.IsSkipUnchanged("hourly") || cfg.IsSkipUnchanged("daily") {
+		t.Errorf("IsSkipUnchanged() does not match the policy file list %v", cfg.SkipUnchanged)
+	}
+
+	// The env var replaces the list of the file
+	t.Setenv("SKIP_UNCHANGED", "daily")
+	cfg = NewConfig("test")
+	if err := cfg.LoadPolicyFile(writePolicyFile(t, "policy.yaml", policyYAML)); err != nil {
+		t.Fatalf("LoadPolicyFile() error = %v", err)
+	}
+	if cfg.IsSkipUnchanged("hourly") || !cfg.IsSkipUnchanged("daily") {
+		t.Errorf("SKIP_UNCHANGED should replace the policy file list, got %v", cfg.SkipUnchanged)
+	}
+}
+
+func TestValidateSkipUnchanged(t *testing.T) {
+	cfg := &Config{SkipUnchanged: []string{"hourly", "every-5m"}}
+	if err := cfg.ValidateSkipUnchanged(); err == nil {
+		t.Error("ValidateSkipUnchanged() expected an error for an unknown tier")
+	}
+
+	if _, err := ParsePolicyFile([]byte("skip_unchanged: [biweekly]\n")); err == nil {
+		t.Error("expected an error for an unknown tier in skip_unchanged")
+	}
+}
@@ -0,0 +1,352 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+)
+
+// namespace is the prefix for all exported metric names
+const namespace = "zfs_snapshot_operator"
+
+// Collector holds the metrics of the operator and renders them in the Prometheus text format
+// Gauges describing the current state (snapshots, pools) are collected during a run and replace
+// the previous ones when it succeeds, counters accumulate over the lifetime of the process
+type Collector struct {
+	mu sync.Mutex
+
+	// Per-run state
+	current *gaugeState // Gauges of the last run, served until the next run is recorded
+	next    *gaugeState // Gauges of the run in progress (nil outside of a run)
+
+	// Run outcome
+	lastRunTimestamp float64
+	lastRunSuccess   float64
+	runsTotal        map[string]float64 // keyed by result: success or failure
+
+	// Cumulative counters
+	createdTotal        float64
+	deletedTotal        float64
+	createFailuresTotal float64
+	deleteFailuresTotal float64
+}
+
+type snapshotKey struct {
+	dataset   string
+	frequency string
+}
+
+// gaugeState holds the gauges describing the snapshots and pools seen by one run
+type gaugeState struct {
+	snapshotCount  map[snapshotKey]int
+	newestSnapshot map[snapshotKey]float64 // Unix timestamp
+	pools          map[string]*models.PoolStatus
+	lastScrub      map[string]float64 // Unix timestamp
+}
+
+func newGaugeState() *gaugeState {
+	return &gaugeState{
+		snapshotCount:  make(map[snapshotKey]int),
+		newestSnapshot: make(map[snapshotKey]float64),
+		pools:          make(map[string]*models.PoolStatus),
+		lastScrub:      make(map[string]float64),
+	}
+}
+
+// merge updates the series of s with the series recorded in other and keeps all others
+func (s *gaugeState) merge(other *gaugeState) {
+	for key, count := range other.snapshotCount {
+		s.snapshotCount[key] = count
+		if timestamp, ok := other.newestSnapshot[key]; ok {
+			s.newestSnapshot[key] = timestamp
+		} else {
+			delete(s.newestSnapshot, key)
+		}
+	}
+	for name, status := range other.pools {
+		s.pools[name] = status
+		if timestamp, ok := other.lastScrub[name]; ok {
+			s.lastScrub[name] = timestamp
+		} else {
+			delete(s.lastScrub, name)
+		}
+	}
+}
+
+// NewCollector creates an empty metrics collector
+func NewCollector() *Collector {
+	return &Collector{
+		current:   newGaugeState(),
+		runsTotal: map[string]float64{"success": 0, "failure": 0},
+	}
+}
+
+// BeginRun starts collecting the gauges of a new run
+// The gauges of the previous run are served until the run is recorded with RecordRun, so removed
+// datasets and pools disappear from the output once a run succeeds without them
+func (c *Collector) BeginRun() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.next = newGaugeState()
+}
+
+// pending returns the gauges of the run in progress
+func (c *Collector) pending() *gaugeState {
+	if c.next == nil {
+		c.next = newGaugeState()
+	}
+	return c.next
+}
+
+// SetSnapshotStats records the number of snapshots and the time of the newest snapshot
+// for a dataset and frequency
+func (c *Collector) SetSnapshotStats(dataset, frequency string, count int, newest time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state := c.pending()
+	key := snapshotKey{dataset: dataset, frequency: frequency}
+	state.snapshotCount[key] = count
+	if count > 0 && !newest.IsZero() {
+		state.newestSnapshot[key] = float64(newest.Unix())
+	} else {
+		delete(state.newestSnapshot, key)
+	}
+}
+
+// SetPoolStatus records the state, error counters and last scrub time of a pool
+func (c *Collector) SetPoolStatus(poolName string, status *models.PoolStatus) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state := c.pending()
+	state.pools[poolName] = status
+	if status.LastScrubTime > 0 {
+		state.lastScrub[poolName] = float64(status.LastScrubTime)
+	} else {
+		delete(state.lastScrub, poolName)
+	}
+}
+
+// RecordRun records the outcome of a run and adds its counts to the cumulative counters
+// The gauges of a successful run replace those of the previous run; a failed run only updates the
+// series it recorded, so e.g. the newest snapshot timestamp stops advancing while the operator is broken
+func (c *Collector) RecordRun(now time.Time, success bool, created, deleted, createFailures, deleteFailures int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.next != nil {
+		if success {
+			c.current = c.next
+		} else {
+			c.current.merge(c.next)
+		}
+		c.next = nil
+	}
+
+	c.lastRunTimestamp = float64(now.Unix())
+	if success {
+		c.lastRunSuccess = 1
+		c.runsTotal["success"]++
+	} else {
+		c.lastRunSuccess = 0
+		c.runsTotal["failure"]++
+	}
+	c.createdTotal += float64(created)
+	c.deletedTotal += float64(deleted)
+	c.createFailuresTotal += float64(createFailures)
+	c.deleteFailuresTotal += float64(deleteFailures)
+}
+
+// WriteTo writes all metrics in the Prometheus text exposition format
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var buf bytes.Buffer
+	state := c.current
+
+	snapshotKeys := make([]snapshotKey, 0, len(state.snapshotCount))
+	for key := range state.snapshotCount {
+		snapshotKeys = append(snapshotKeys, key)
+	}
+	sort.Slice(snapshotKeys, func(i, j int) bool {
+		if snapshotKeys[i].dataset != snapshotKeys[j].dataset {
+			return snapshotKeys[i].dataset < snapshotKeys[j].dataset
+		}
+		return snapshotKeys[i].frequency < snapshotKeys[j].frequency
+	})
+
+	writeHeader(&buf, "snapshots", "gauge", "Number of managed snapshots per dataset and frequency")
+	for _, key := range snapshotKeys {
+		writeSample(&buf, "snapshots", float64(state.snapshotCount[key]), "dataset", key.dataset, "frequency", key.frequency)
+	}
+
+	writeHeader(&buf, "newest_snapshot_timestamp_seconds", "gauge", "Unix timestamp of the newest snapshot per dataset and frequency")
+	for _, key := range snapshotKeys {
+		if timestamp, ok := state.newestSnapshot[key]; ok {
+			writeSample(&buf, "newest_snapshot_timestamp_seconds", timestamp, "dataset", key.dataset, "frequency", key.frequency)
+		}
+	}
+
+	writeHeader(&buf, "snapshots_created_total", "counter", "Total number of snapshots created")
+	writeSample(&buf, "snapshots_created_total", c.createdTotal)
+	writeHeader(&buf, "snapshots_deleted_total", "counter", "Total number of snapshots deleted")
+	writeSample(&buf, "snapshots_deleted_total", c.deletedTotal)
+	writeHeader(&buf, "snapshot_create_failures_total", "counter", "Total number of failed snapshot creations")
+	writeSample(&buf, "snapshot_create_failures_total", c.createFailuresTotal)
+	writeHeader(&buf, "snapshot_delete_failures_total", "counter", "Total number of failed snapshot deletions")
+	writeSample(&buf, "snapshot_delete_failures_total", c.deleteFailuresTotal)
+
+	writeHeader(&buf, "runs_total", "counter", "Total number of runs by result")
+	writeSample(&buf, "runs_total", c.runsTotal["failure"], "result", "failure")
+	writeSample(&buf, "runs_total", c.runsTotal["success"], "result", "success")
+	writeHeader(&buf, "last_run_success", "gauge", "Whether the last run completed without errors (1) or not (0)")
+	writeSample(&buf, "last_run_success", c.lastRunSuccess)
+	writeHeader(&buf, "last_run_timestamp_seconds", "gauge", "Unix timestamp of the last run")
+	writeSample(&buf, "last_run_timestamp_seconds", c.lastRunTimestamp)
+
+	poolNames := make([]string, 0, len(state.pools))
+	for name := range state.pools {
+		poolNames = append(poolNames, name)
+	}
+	sort.Strings(poolNames)
+
+	writeHeader(&buf, "pool_state", "gauge", "Pool state from zpool status (1 for the current state)")
+	for _, name := range poolNames {
+		writeSample(&buf, "pool_state", 1, "pool", name, "state", state.pools[name].State)
+	}
+	writeHeader(&buf, "pool_read_errors", "gauge", "Read errors of the pool root vdev")
+	for _, name := range poolNames {
+		writeSample(&buf, "pool_read_errors", parseCount(state.pools[name].ReadErrors), "pool", name)
+	}
+	writeHeader(&buf, "pool_write_errors", "gauge", "Write errors of the pool root vdev")
+	for _, name := range poolNames {
+		writeSample(&buf, "pool_write_errors", parseCount(state.pools[name].WriteErrors), "pool", name)
+	}
+	writeHeader(&buf, "pool_checksum_errors", "gauge", "Checksum errors of the pool root vdev")
+	for _, name := range poolNames {
+		writeSample(&buf, "pool_checksum_errors", parseCount(state.pools[name].ChecksumErrors), "pool", name)
+	}
+	writeHeader(&buf, "pool_last_scrub_timestamp_seconds", "gauge", "Unix timestamp of the end of the last scrub of the pool")
+	for _, name := range poolNames {
+		if timestamp, ok := state.lastScrub[name]; ok {
+			writeSample(&buf, "pool_last_scrub_timestamp_seconds", timestamp, "pool", name)
+		}
+	}
+
+	return buf.WriteTo(w)
+}
+
+// Handler returns an HTTP handler serving the metrics (for the /metrics endpoint)
+func (c *Collector) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if _, err := c.WriteTo(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// WriteTextfile writes the metrics to a file for the node_exporter textfile collector
+// The file is written to a temporary file first and renamed, so the collector never reads a partial file
+func (c *Collector) WriteTextfile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary metrics file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := c.WriteTo(tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close metrics file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to set metrics file permissions: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to rename metrics file: %w", err)
+	}
+
+	return nil
+}
+
+// Push sends the metrics to a Prometheus Pushgateway, replacing all metrics of the given job
+func (c *Collector) Push(gatewayURL, job string) error {
+	var buf bytes.Buffer
+	if _, err := c.WriteTo(&buf); err != nil {
+		return fmt.Errorf("failed to render metrics: %w", err)
+	}
+
+	pushURL := strings.TrimRight(gatewayURL, "/") + "/metrics/job/" + url.PathEscape(job)
+	req, err := http.NewRequest(http.MethodPut, pushURL, &buf)
+	if err != nil {
+		return fmt.Errorf("failed to create push request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("pushgateway returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric
+func writeHeader(buf *bytes.Buffer, name, metricType, help string) {
+	fmt.Fprintf(buf, "# HELP %s_%s %s\n", namespace, name, help)
+	fmt.Fprintf(buf, "# TYPE %s_%s %s\n", namespace, name, metricType)
+}
+
+// writeSample writes a single sample with the given label name/value pairs
+func writeSample(buf *bytes.Buffer, name string, value float64, labels ...string) {
+	buf.WriteString(namespace + "_" + name)
+	if len(labels) > 0 {
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			fmt.Fprintf(buf, "%s=\"%s\"", labels[i], labelValueEscaper.Replace(labels[i+1]))
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	buf.WriteByte('\n')
+}
+
+// labelValueEscaper escapes label values as required by the text exposition format
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// parseCount converts an error count string from zpool status to a number (0 if empty or invalid)
+func parseCount(value string) float64 {
+	count, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return count
+}
@@ -0,0 +1,231 @@
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+)
+
+func newTestCollector(now time.Time) *Collector {
+	c := NewCollector()
+	c.SetSnapshotStats("tank/data", "hourly", 3, now.Add(-30*time.Minute))
+	c.SetSnapshotStats("tank/data", "daily", 0, time.Time{})
+	c.SetPoolStatus("tank", &models.PoolStatus{
+		Name:           "tank",
+		State:          "DEGRADED",
+		ReadErrors:     "2",
+		WriteErrors:    "0",
+		ChecksumErrors: "5",
+		LastScrubTime:  now.Add(-10 * 24 * time.Hour).Unix(),
+	})
+	c.RecordRun(now, true, 2, 1, 0, 1)
+	return c
+}
+
+func TestWriteTo(t *testing.T) {
+	now := time.Date(2026, 1, 25, 12, 0, 0, 0, time.UTC)
+	c := newTestCollector(now)
+
+	var buf bytes.Buffer
+	if _, err := c.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	output := buf.String()
+
+	expected := []string{
+		`# TYPE zfs_snapshot_operator_snapshots gauge`,
+		`zfs_snapshot_operator_snapshots{dataset="tank/data",frequency="hourly"} 3`,
+		`zfs_snapshot_operator_snapshots{dataset="tank/data",frequency="daily"} 0`,
+		`zfs_snapshot_operator_newest_snapshot_timestamp_seconds{dataset="tank/data",frequency="hourly"} 1.7693406e+09`,
+		`zfs_snapshot_operator_snapshots_created_total 2`,
+		`zfs_snapshot_operator_snapshots_deleted_total 1`,
+		`zfs_snapshot_operator_snapshot_delete_failures_total 1`,
+		`zfs_snapshot_operator_runs_total{result="success"} 1`,
+		`zfs_snapshot_operator_last_run_success 1`,
+		`zfs_snapshot_operator_pool_state{pool="tank",state="DEGRADED"} 1`,
+		`zfs_snapshot_operator_pool_read_errors{pool="tank"} 2`,
+		`zfs_snapshot_operator_pool_checksum_errors{pool="tank"} 5`,
+		`zfs_snapshot_operator_pool_last_scrub_timestamp_seconds{pool="tank"} 1.7684784e+09`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("Output missing line %q\n%s", line, output)
+		}
+	}
+
+	if strings.Contains(output, `newest_snapshot_timestamp_seconds{dataset="tank/data",frequency="daily"}`) {
+		t.Error("Newest snapshot timestamp should not be reported for a frequency without snapshots")
+	}
+}
+
+func TestSuccessfulRunReplacesState(t *testing.T) {
+	now := time.Date(2026, 1, 25, 12, 0, 0, 0, time.UTC)
+	c := newTestCollector(now)
+	c.BeginRun()
+	c.SetSnapshotStats("tank/other", "hourly", 1, now)
+
+	// Scrapes during a run still see the gauges of the previous run
+	var buf bytes.Buffer
+	c.WriteTo(&buf)
+	if !strings.Contains(buf.String(), `dataset="tank/data"`) || strings.Contains(buf.String(), `dataset="tank/other"`) {
+		t.Errorf("Gauges of the run in progress should not be served yet\n%s", buf.String())
+	}
+
+	c.RecordRun(now, true, 0, 0, 0, 0)
+	buf.Reset()
+	c.WriteTo(&buf)
+	output := buf.String()
+
+	// Datasets and pools that were not seen by the successful run disappear
+	if strings.Contains(output, `dataset="tank/data"`) || strings.Contains(output, `pool="tank"`) {
+		t.Errorf("A successful run should replace the per-run gauges\n%s", output)
+	}
+	if !strings.Contains(output, `zfs_snapshot_operator_snapshots{dataset="tank/other",frequency="hourly"} 1`+"\n") {
+		t.Errorf("Output missing the gauges of the last run\n%s", output)
+	}
+	// Counters accumulate across runs
+	if !strings.Contains(output, "zfs_snapshot_operator_snapshots_created_total 2\n") {
+		t.Errorf("Counters should survive BeginRun()\n%s", output)
+	}
+}
+
+func TestFailedRunKeepsState(t *testing.T) {
+	now := time.Date(2026, 1, 25, 12, 0, 0, 0, time.UTC)
+	c := newTestCollector(now)
+
+	// The run fails after reading the pool status, e.g. because zfs list fails
+	later := now.Add(time.Hour)
+	c.BeginRun()
+	c.SetPoolStatus("tank", &models.PoolStatus{Name: "tank", State: "FAULTED"})
+	c.RecordRun(later, false, 0, 0, 1, 0)
+
+	var buf bytes.Buffer
+	c.WriteTo(&buf)
+	output := buf.String()
+
+	expected := []string{
+		`zfs_snapshot_operator_snapshots{dataset="tank/data",frequency="hourly"} 3`,
+		`zfs_snapshot_operator_newest_snapshot_timestamp_seconds{dataset="tank/data",frequency="hourly"} 1.7693406e+09`,
+		`zfs_snapshot_operator_pool_state{pool="tank",state="FAULTED"} 1`,
+		`zfs_snapshot_operator_last_run_success 0`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("Output missing line %q\n%s", line, output)
+		}
+	}
+	if strings.Contains(output, `pool_last_scrub_timestamp_seconds{pool="tank"}`) {
+		t.Errorf("Scrub timestamp should be updated with the pool status of the failed run\n%s", output)
+	}
+}
+
+func TestLabelEscaping(t *testing.T) {
+	var buf bytes.Buffer
+	writeSample(&buf, "test", 1, "dataset", `a"b\c`)
+
+	want := `zfs_snapshot_operator_test{dataset="a\"b\\c"} 1` + "\n"
+	if buf.String() != want {
+		t.Errorf("writeSample() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	c := newTestCollector(time.Now())
+	recorder := httptest.NewRecorder()
+	c.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Handler() status = %d, want 200", recorder.Code)
+	}
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("Content-Type = %s, want text/plain", recorder.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(recorder.Body.String(), "zfs_snapshot_operator_runs_total") {
+		t.Error("Handler() response should contain metrics")
+	}
+}
+
+func TestWriteTextfile(t *testing.T) {
+	c := newTestCollector(time.Now())
+	path := filepath.Join(t.TempDir(), "zfs_snapshot_operator.prom")
+
+	if err := c.WriteTextfile(path); err != nil {
+		t.Fatalf("WriteTextfile() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read textfile: %v", err)
+	}
+	if !strings.Contains(string(data), "zfs_snapshot_operator_snapshots_created_total 2") {
+		t.Errorf("Textfile missing metrics:\n%s", data)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("Temporary files should be cleaned up, found %d entries", len(entries))
+	}
+}
+
+func TestPush(t *testing.T) {
+	var gotMethod, gotPath, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotPath = r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := newTestCollector(time.Now())
+	if err := c.Push(server.URL+"/", "zfs-snapshot-operator"); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+
+	if gotMethod != http.MethodPut {
+		t.Errorf("Push() method = %s, want PUT", gotMethod)
+	}
+	if gotPath != "/metrics/job/zfs-snapshot-operator" {
+		t.Errorf("Push() path = %s, want /metrics/job/zfs-snapshot-operator", gotPath)
+	}
+	if !strings.Contains(gotBody, "zfs_snapshot_operator_runs_total") {
+		t.Error("Push() body should contain metrics")
+	}
+}
+
+// TestPushEscapesJob tests that a job name is a single path segment of the Pushgateway URL
+func TestPushEscapesJob(t *testing.T) {
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	if err := NewCollector().Push(server.URL, "backup/nightly run"); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	if want := "/metrics/job/backup%2Fnightly%20run"; gotPath != want {
+		t.Errorf("Push() path = %s, want %s", gotPath, want)
+	}
+}
+
+func TestPushError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad metrics", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	if err := NewCollector().Push(server.URL, "job"); err == nil {
+		t.Error("Push() should fail when the Pushgateway rejects the request")
+	}
+}
@@ -4,11 +4,31 @@ import "time"
 
 // Snapshot represents a ZFS snapshot
 type Snapshot struct {
-	PoolName       string
+	PoolName          string
+	FilesystemName    string
+	SnapshotName      string
+	DateTime          time.Time // Effective time used for retention, chosen by SNAPSHOT_TIME_SOURCE
+	Frequency         string
+	NameTime          time.Time // Time embedded in the snapshot name (zero if the name has none)
+	CreationTime      time.Time // Value of the creation property (zero if unknown)
+	CreateTxg         uint64    // Transaction group the snapshot was created in (0 if unknown)
+	Used              uint64    // Space only referenced by this snapshot in bytes (0 if unknown)
+	Referenced        uint64    // Space of all data the snapshot references in bytes (0 if unknown)
+	LogicalReferenced uint64    // Referenced space before compression in bytes (0 if unknown)
+	Written           uint64    // Bytes written to the dataset between the previous snapshot and this one
+	WrittenKnown      bool      // If false, the written property was not reported and Written is 0
+	Tool              string    // Third-party tool that created the snapshot (e.g. sanoid), empty for own snapshots
+	UserRefs          uint64    // Number of zfs hold tags on the snapshot
+	Clones            []string  // Datasets cloned from the snapshot
+	DeferDestroy      bool      // If true, the snapshot is marked for deferred destruction (zfs destroy -d)
+}
+
+// Hold represents a zfs hold tag on a snapshot
+type Hold struct {
 	FilesystemName string
 	SnapshotName   string
-	DateTime       time.Time
-	Frequency      string
+	Tag            string
+	Time           time.Time // Time the hold was placed (zero if unknown)
 }
 
 // Pool represents a ZFS pool/filesystem
@@ -17,7 +37,15 @@ type Pool struct {
 	FilesystemName string
 	Used           string
 	Avail          string
+	Written        string // Space written since the newest snapshot of the dataset (empty if unknown)
 	Mountpoint     string
+	Properties     map[string]Property // User properties that are set locally or inherited (e.g. com.sun:auto-snapshot)
+}
+
+// Property represents the value of a ZFS user property
+type Property struct {
+	Value  string
+	Source string // Dataset the value is set on (the dataset itself or the parent it is inherited from)
 }
 
 // PoolStatus represents the health status of a ZFS pool
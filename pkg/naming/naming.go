@@ -0,0 +1,250 @@
+package naming
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultTemplate is the snapshot name template used unless SNAPSHOT_NAME_TEMPLATE is set
+// It produces names like autosnap_2026-01-25_14:00:00_hourly
+const DefaultTemplate = "{prefix}_{time}_{frequency}"
+
+// Layouts of the {time} placeholder without an explicit layout
+const (
+	TimeLayout       = "2006-01-02_15:04:05"      // e.g. 2026-03-29_02:30:00
+	TimeOffsetLayout = "2006-01-02_15:04:05-0700" // e.g. 2026-03-29_02:30:00+0200 (SNAPSHOT_NAME_OFFSET)
+)
+
+// Placeholders of a name template
+const (
+	placeholderPrefix    = "prefix"
+	placeholderFrequency = "frequency"
+	placeholderTime      = "time"
+)
+
+// Template builds snapshot names from a frequency and a time and parses them back
+//
+// A template is literal text with the placeholders {prefix}, {frequency} and {time} or {time:LAYOUT},
+// where LAYOUT is a Go time layout (e.g. {prefix}-{frequency}-{time:20060102T150405Z})
+// {frequency} and {time} must each appear exactly once
+type Template struct {
+	template     string
+	prefix       string
+	formatLayout string   // Layout used for new names
+	parseLayouts []string // Layouts tried when parsing, formatLayout first
+	pattern      *regexp.Regexp
+}
+
+// validationTime is the time used to check that a template round-trips
+var validationTime = time.Date(2026, 3, 29, 2, 30, 45, 0, time.FixedZone("", 2*60*60))
+
+// Compile parses a name template
+// frequencies are the names {frequency} can take; offset selects TimeOffsetLayout for {time}
+// Compile checks that every frequency formats to a valid snapshot name that parses back to the same
+// frequency and time, so a template can not create snapshots the operator would not recognize
+func Compile(template, prefix string, frequencies []string, offset bool) (*Template, error) {
+	if len(frequencies) == 0 {
+		return nil, errors.New("no frequencies")
+	}
+
+	t := &Template{
+		template:     template,
+		prefix:       prefix,
+		formatLayout: TimeLayout,
+		parseLayouts: []string{TimeLayout, TimeOffsetLayout},
+	}
+	if offset {
+		t.parseLayouts = []string{TimeOffsetLayout, TimeLayout}
+		t.formatLayout = TimeOffsetLayout
+	}
+
+	quoted := make([]string, len(frequencies))
+	for i, frequency := range frequencies {
+		quoted[i] = regexp.QuoteMeta(frequency)
+	}
+
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	frequencyCount, timeCount := 0, 0
+	rest := template
+	for rest != "" {
+		start := strings.IndexAny(rest, "{}")
+		if start < 0 {
+			pattern.WriteString(regexp.QuoteMeta(rest))
+			break
+		}
+		if rest[start] == '}' {
+			return nil, fmt.Errorf("unexpected } in template %q", template)
+		}
+		pattern.WriteString(regexp.QuoteMeta(rest[:start]))
+
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated placeholder in template %q", template)
+		}
+		name := rest[start+1 : start+end]
+		rest = rest[start+end+1:]
+
+		switch {
+		case name == placeholderPrefix:
+			pattern.WriteString(regexp.QuoteMeta(prefix))
+		case name == placeholderFrequency:
+			frequencyCount++
+			pattern.WriteString("(?P<frequency>" + strings.Join(quoted, "|") + ")")
+		case name == placeholderTime:
+			timeCount++
+			pattern.WriteString("(?P<time>.+?)")
+		case strings.HasPrefix(name, placeholderTime+":"):
+			layout := strings.TrimPrefix(name, placeholderTime+":")
+			if layout == "" {
+				return nil, fmt.Errorf("empty time layout in template %q", template)
+			}
+			timeCount++
+			t.formatLayout = layout
+			t.parseLayouts = []string{layout}
+			pattern.WriteString("(?P<time>.+?)")
+		default:
+			return nil, fmt.Errorf("unknown placeholder {%s} in template %q", name, template)
+		}
+	}
+	pattern.WriteString("$")
+
+	if frequencyCount != 1 || timeCount != 1 {
+		return nil, fmt.Errorf("template %q must contain {frequency} and {time} exactly once", template)
+	}
+
+	re, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %q: %w", template, err)
+	}
+	t.pattern = re
+
+	if err := t.validate(frequencies); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// MustCompile is like Compile but panics if the template is invalid
+func MustCompile(template, prefix string, frequencies []string, offset bool) *Template {
+	t, err := Compile(template, prefix, frequencies, offset)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// validate checks that every frequency produces a valid name that parses back to itself
+func (t *Template) validate(frequencies []string) error {
+	later := validationTime.AddDate(1, 1, 1).Add(time.Hour + time.Minute + time.Second)
+	if t.Format(frequencies[0], validationTime) == t.Format(frequencies[0], later) {
+		return fmt.Errorf("template %q: time layout %q does not contain any date or time", t.template, t.formatLayout)
+	}
+
+	for _, frequency := range frequencies {
+		name := t.Format(frequency, validationTime)
+		if err := validateSnapshotName(name); err != nil {
+			return fmt.Errorf("template %q produces invalid name %q: %w", t.template, name, err)
+		}
+
+		parsedFrequency, parsedTime := t.Parse(name, validationTime.Location())
+		if parsedFrequency != frequency {
+			return fmt.Errorf("template %q: name %q is read back as frequency %q, want %q",
+				t.template, name, parsedFrequency, frequency)
+		}
+		if parsedTime.IsZero() {
+			return fmt.Errorf("template %q: time of name %q can not be parsed back", t.template, name)
+		}
+		if again := t.Format(frequency, parsedTime); again != name {
+			return fmt.Errorf("template %q: name %q is read back as %q", t.template, name, again)
+		}
+	}
+	return nil
+}
+
+// CheckPeriod checks that names keep the period of a frequency: the start of a period and the start of the
+// next one format to different names, and the last second of a period is read back into the same period
+// This rejects layouts coarser than the frequency, e.g. {time:2006-01-02} for an hourly frequency
+func (t *Template) CheckPeriod(frequency string, periodStart, nextPeriodStart func(time.Time) time.Time) error {
+	start := periodStart(validationTime)
+	next := nextPeriodStart(validationTime)
+	if t.Format(frequency, start) == t.Format(frequency, next) {
+		return fmt.Errorf("template %q: time layout %q is coarser than the %s period, consecutive snapshots get the same name",
+			t.template, t.formatLayout, frequency)
+	}
+
+	last := next.Add(-time.Second)
+	name := t.Format(frequency, last)
+	if _, parsed := t.Parse(name, last.Location()); !periodStart(parsed).Equal(start) {
+		return fmt.Errorf("template %q: time layout %q is coarser than the %s period, name %q is read back outside its period",
+			t.template, t.formatLayout, frequency, name)
+	}
+	return nil
+}
+
+// validateSnapshotName checks the part after the @ of a snapshot name for characters ZFS rejects
+func validateSnapshotName(name string) error {
+	if name == "" {
+		return errors.New("empty name")
+	}
+	if i := strings.IndexAny(name, "@/#%"); i >= 0 {
+		return fmt.Errorf("invalid character %q", name[i])
+	}
+	return nil
+}
+
+// Format returns the snapshot name of a frequency at the given time
+// The time is formatted in its own location, so callers convert it to the configured time zone first
+func (t *Template) Format(frequency string, at time.Time) string {
+	var name strings.Builder
+	rest := t.template
+	for rest != "" {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			name.WriteString(rest)
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		name.WriteString(rest[:start])
+		placeholder := rest[start+1 : start+end]
+		rest = rest[start+end+1:]
+
+		switch {
+		case placeholder == placeholderPrefix:
+			name.WriteString(t.prefix)
+		case placeholder == placeholderFrequency:
+			name.WriteString(frequency)
+		default:
+			name.WriteString(at.Format(t.formatLayout))
+		}
+	}
+	return name.String()
+}
+
+// Parse reads the frequency and time from a snapshot name
+// The frequency is empty if the name does not match the template; the time is zero if it does not parse
+// A time with an explicit UTC offset denotes an exact instant, one without is wall clock time in loc;
+// the result is always returned in loc
+func (t *Template) Parse(name string, loc *time.Location) (string, time.Time) {
+	matches := t.pattern.FindStringSubmatch(name)
+	if matches == nil {
+		return "", time.Time{}
+	}
+
+	frequency := matches[t.pattern.SubexpIndex(placeholderFrequency)]
+	value := matches[t.pattern.SubexpIndex(placeholderTime)]
+	for _, layout := range t.parseLayouts {
+		if parsed, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return frequency, parsed.In(loc)
+		}
+	}
+	return frequency, time.Time{}
+}
+
+// String returns the template text
+func (t *Template) String() string {
+	return t.template
+}
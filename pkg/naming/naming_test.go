@@ -0,0 +1,138 @@
+package naming
+
+import (
+	"testing"
+	"time"
+)
+
+var frequencies = []string{"frequently", "hourly", "daily", "weekly", "monthly", "yearly"}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+	}{
+		{"missing frequency", "{prefix}_{time}"},
+		{"missing time", "{prefix}_{frequency}"},
+		{"duplicate frequency", "{prefix}_{frequency}_{time}_{frequency}"},
+		{"duplicate time", "{time}_{frequency}_{time:2006}"},
+		{"unknown placeholder", "{prefix}_{time}_{frequency}_{host}"},
+		{"unterminated placeholder", "{prefix}_{time}_{frequency"},
+		{"stray brace", "{prefix}_{time}}_{frequency}"},
+		{"empty layout", "{prefix}_{time:}_{frequency}"},
+		{"invalid character", "{prefix}/{time}_{frequency}"},
+		// A layout without any time element can not be read back
+		{"constant layout", "{prefix}_{time:snap}_{frequency}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Compile(tt.template, "autosnap", frequencies, false); err == nil {
+				t.Errorf("Compile(%q) expected an error", tt.template)
+			}
+		})
+	}
+}
+
+func TestFormatAndParse(t *testing.T) {
+	at := time.Date(2026, 1, 25, 14, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		template string
+		prefix   string
+		offset   bool
+		want     string
+	}{
+		{"default", DefaultTemplate, "autosnap", false, "autosnap_2026-01-25_14:00:00_hourly"},
+		{"default with offset", DefaultTemplate, "autosnap", true, "autosnap_2026-01-25_14:00:00+0000_hourly"},
+		{"compact", "{prefix}-{frequency}-{time:20060102T150405Z}", "autosnap", false, "autosnap-hourly-20260125T140000Z"},
+		{"zfs-auto-snapshot", "{prefix}_{frequency}-{time:2006-01-02-1504}", "zfs-auto-snap", false, "zfs-auto-snap_hourly-2026-01-25-1400"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, err := Compile(tt.template, tt.prefix, frequencies, tt.offset)
+			if err != nil {
+				t.Fatalf("Compile(%q) error = %v", tt.template, err)
+			}
+
+			name := template.Format("hourly", at)
+			if name != tt.want {
+				t.Errorf("Format() = %s, want %s", name, tt.want)
+			}
+
+			frequency, parsed := template.Parse(name, time.UTC)
+			if frequency != "hourly" || !parsed.Equal(at) {
+				t.Errorf("Parse(%s) = %s, %v, want hourly, %v", name, frequency, parsed, at)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+	template := MustCompile(DefaultTemplate, "autosnap", frequencies, false)
+
+	tests := []struct {
+		name          string
+		wantFrequency string
+		wantTime      time.Time
+	}{
+		{"autosnap_2026-01-25_12:00:00_hourly", "hourly", time.Date(2026, 1, 25, 12, 0, 0, 0, berlin)},
+		// An embedded offset wins over the location, so names written in another zone keep their instant
+		{"autosnap_2026-03-29_02:30:00+0000_hourly", "hourly", time.Date(2026, 3, 29, 2, 30, 0, 0, time.UTC)},
+		{"autosnap_2026-10-25_02:30:00+0200_hourly", "hourly", time.Date(2026, 10, 25, 0, 30, 0, 0, time.UTC)},
+		{"autosnap_2026-10-25_02:30:00+0100_hourly", "hourly", time.Date(2026, 10, 25, 1, 30, 0, 0, time.UTC)},
+		// A matching name with an unreadable time keeps its frequency but has no time
+		{"autosnap_notadate_yearly", "yearly", time.Time{}},
+		{"autosnap_2026-01-25_12:00:00_minutely", "", time.Time{}},
+		{"other_2026-01-25_12:00:00_hourly", "", time.Time{}},
+		{"manual-snapshot", "", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frequency, got := template.Parse(tt.name, berlin)
+			if frequency != tt.wantFrequency {
+				t.Errorf("Parse(%q) frequency = %q, want %q", tt.name, frequency, tt.wantFrequency)
+			}
+			if !got.Equal(tt.wantTime) {
+				t.Errorf("Parse(%q) time = %v, want %v", tt.name, got, tt.wantTime)
+			}
+			if !got.IsZero() && got.Location() != berlin {
+				t.Errorf("Parse(%q) location = %v, want Europe/Berlin", tt.name, got.Location())
+			}
+		})
+	}
+}
+
+func TestCheckPeriod(t *testing.T) {
+	halfHourStart := func(at time.Time) time.Time { return at.Truncate(30 * time.Minute) }
+	halfHourNext := func(at time.Time) time.Time { return at.Truncate(30 * time.Minute).Add(30 * time.Minute) }
+
+	tests := []struct {
+		name     string
+		template string
+		wantErr  bool
+	}{
+		{"default", DefaultTemplate, false},
+		{"minutes", "{prefix}_{frequency}_{time:2006-01-02_1504}", false},
+		// Every half hour period gets the same name
+		{"date only", "{prefix}_{frequency}_{time:2006-01-02}", true},
+		// The second half of an hour gets its own name, but is read back into the first half
+		{"hours only", "{prefix}_{frequency}_{time:2006-01-02_15}", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := MustCompile(tt.template, "autosnap", frequencies, false)
+			if err := template.CheckPeriod("frequently", halfHourStart, halfHourNext); (err != nil) != tt.wantErr {
+				t.Errorf("CheckPeriod() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package naming
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Tool recognizes the snapshots of a third-party snapshot tool and maps their labels to frequencies
+type Tool struct {
+	Name     string
+	template *Template
+	labels   map[string]string // Label in the snapshot name -> frequency
+}
+
+// tools are the known third-party snapshot tools, keyed by name
+var tools = map[string]*Tool{
+	// sanoid: autosnap_2026-01-25_14:00:00_hourly
+	"sanoid": newTool("sanoid", "{prefix}_{time:2006-01-02_15:04:05}_{frequency}", "autosnap", map[string]string{
+		"frequently": "frequently",
+		"hourly":     "hourly",
+		"daily":      "daily",
+		"weekly":     "weekly",
+		"monthly":    "monthly",
+		"yearly":     "yearly",
+	}),
+	// zfs-auto-snapshot: zfs-auto-snap_hourly-2026-01-25-1400
+	"zfs-auto-snapshot": newTool("zfs-auto-snapshot", "{prefix}_{frequency}-{time:2006-01-02-1504}", "zfs-auto-snap", map[string]string{
+		"frequent": "frequently",
+		"hourly":   "hourly",
+		"daily":    "daily",
+		"weekly":   "weekly",
+		"monthly":  "monthly",
+		"yearly":   "yearly",
+	}),
+}
+
+func newTool(name, template, prefix string, labels map[string]string) *Tool {
+	names := make([]string, 0, len(labels))
+	for label := range labels {
+		names = append(names, label)
+	}
+	sort.Strings(names)
+
+	return &Tool{
+		Name:     name,
+		template: MustCompile(template, prefix, names, false),
+		labels:   labels,
+	}
+}
+
+// LookupTool returns the recognizer of a third-party snapshot tool by name
+func LookupTool(name string) (*Tool, error) {
+	tool, ok := tools[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown snapshot tool %q (must be one of %v)", name, ToolNames())
+	}
+	return tool, nil
+}
+
+// Tools returns the recognizers of all known third-party snapshot tools, sorted by name
+func Tools() []*Tool {
+	result := make([]*Tool, 0, len(tools))
+	for _, name := range ToolNames() {
+		result = append(result, tools[name])
+	}
+	return result
+}
+
+// ToolNames returns the names of all known third-party snapshot tools, sorted
+func ToolNames() []string {
+	names := make([]string, 0, len(tools))
+	for name := range tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse reads the frequency and time from a snapshot name of the tool
+// The frequency is empty if the name was not created by the tool
+func (t *Tool) Parse(name string, loc *time.Location) (string, time.Time) {
+	label, at := t.template.Parse(name, loc)
+	return t.labels[label], at
+}
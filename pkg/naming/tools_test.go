@@ -0,0 +1,49 @@
+package naming
+
+import (
+	"testing"
+	"time"
+)
+
+func TestToolParse(t *testing.T) {
+	tests := []struct {
+		tool          string
+		name          string
+		wantFrequency string
+		wantTime      time.Time
+	}{
+		{"sanoid", "autosnap_2026-01-25_14:00:00_hourly", "hourly", time.Date(2026, 1, 25, 14, 0, 0, 0, time.UTC)},
+		{"sanoid", "autosnap_2026-01-25_14:15:00_frequently", "frequently", time.Date(2026, 1, 25, 14, 15, 0, 0, time.UTC)},
+		{"sanoid", "zfs-auto-snap_hourly-2026-01-25-1400", "", time.Time{}},
+		{"zfs-auto-snapshot", "zfs-auto-snap_hourly-2026-01-25-1400", "hourly", time.Date(2026, 1, 25, 14, 0, 0, 0, time.UTC)},
+		{"zfs-auto-snapshot", "zfs-auto-snap_frequent-2026-01-25-1415", "frequently", time.Date(2026, 1, 25, 14, 15, 0, 0, time.UTC)},
+		{"zfs-auto-snapshot", "zfs-auto-snap_monthly-2026-01-01-0000", "monthly", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"zfs-auto-snapshot", "autosnap_2026-01-25_14:00:00_hourly", "", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tool+"/"+tt.name, func(t *testing.T) {
+			tool, err := LookupTool(tt.tool)
+			if err != nil {
+				t.Fatalf("LookupTool(%s) error = %v", tt.tool, err)
+			}
+
+			frequency, at := tool.Parse(tt.name, time.UTC)
+			if frequency != tt.wantFrequency {
+				t.Errorf("Parse() frequency = %q, want %q", frequency, tt.wantFrequency)
+			}
+			if !at.Equal(tt.wantTime) {
+				t.Errorf("Parse() time = %v, want %v", at, tt.wantTime)
+			}
+		})
+	}
+}
+
+func TestLookupToolUnknown(t *testing.T) {
+	if _, err := LookupTool("timeshift"); err == nil {
+		t.Error("LookupTool(timeshift) expected an error")
+	}
+	if len(Tools()) != len(ToolNames()) {
+		t.Errorf("Tools() returned %d tools, want %d", len(Tools()), len(ToolNames()))
+	}
+}
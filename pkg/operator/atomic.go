@@ -0,0 +1,117 @@
+package operator
+
+import (
+	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/zfs"
+	"k8s.io/klog/v2"
+)
+
+// atomicKey identifies the atomic snapshot of a root for a frequency
+func atomicKey(root, frequency string) string {
+	return root + "@" + frequency
+}
+
+// createAtomicSnapshots creates the snapshots of every atomic snapshot root (see ATOMIC_SNAPSHOT_ROOTS)
+// before the datasets are processed one by one. All datasets of a tree that need a snapshot of a
+// frequency get it with a single zfs snapshot command, so they share the same point in time.
+// Failures are remembered in atomicErrors, so processFrequency keeps the old snapshots of those datasets
+func (o *Operator) createAtomicSnapshots(pools []*models.Pool, poolStatus map[string]*models.PoolStatus, now time.Time) {
+	o.atomicErrors = make(map[string]error)
+	if len(o.config.AtomicSnapshotRoots) == 0 {
+		return
+	}
+
+	// Group the managed datasets by their atomic root, keeping the order of the pool list
+	var roots []string
+	members := make(map[string][]*models.Pool)
+	for _, pool := range pools {
+		if !o.isManaged(pool) || !zfs.IsPoolHealthy(pool.PoolName, poolStatus) {
+			continue
+		}
+		root, ok := o.config.AtomicRoot(pool.FilesystemName)
+		if !ok {
+			continue
+		}
+		if _, exists := members[root]; !exists {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], pool)
+	}
+
+	for _, root := range roots {
+		for _, frequency := range o.config.TierNames() {
+			if err := o.createAtomicSnapshot(root, members[root], frequency, now); err != nil {
+				klog.Infof("Failed to create atomic %s snapshot of %s: %v", frequency, root, err)
+				o.atomicErrors[atomicKey(root, frequency)] = err
+			}
+		}
+	}
+}
+
+// createAtomicSnapshot creates a snapshot of a frequency for all datasets of a tree that do not have a recent one
+// A tier that skips unchanged datasets only skips the tree if none of these datasets changed, otherwise
+// the unchanged ones are snapshotted as well to keep the snapshots of the tree at one point in time
+func (o *Operator) createAtomicSnapshot(root string, pools []*models.Pool, frequency string, now time.Time) error {
+	tier, _ := o.config.Tier(frequency)
+	skipUnchanged := o.config.IsSkipUnchanged(frequency)
+	unchanged := true
+	var newSnapshots []*models.Snapshot
+	for _, pool := range pools {
+		if o.config.GetPoolMaxSnapshots(frequency, pool) == 0 {
+			continue
+		}
+
+		snapshots := o.inventory.Get(pool.FilesystemName, frequency)
+		if findRecentSnapshot(snapshots, tier, now) != nil {
+			continue
+		}
+
+		if unchanged && skipUnchanged {
+			var newest *models.Snapshot
+			for _, snapshot := range snapshots {
+				if newest == nil || snapshot.DateTime.After(newest.DateTime) {
+					newest = snapshot
+				}
+			}
+			unchanged = newest != nil && isUnchangedSince(pool, o.inventory, newest)
+		}
+
+		snapshot, err := o.newSnapshot(pool, frequency, now)
+		if err != nil {
+			return err
+		}
+		newSnapshots = append(newSnapshots, snapshot)
+	}
+
+	if len(newSnapshots) == 0 {
+		return nil
+	}
+	if skipUnchanged && unchanged {
+		klog.Infof("Skipping atomic %s snapshot of %s, nothing was written to its %d dataset(s)", frequency, root, len(newSnapshots))
+		return nil
+	}
+
+	klog.Infof("Creating atomic %s snapshot of %s (%d dataset(s))", frequency, root, len(newSnapshots))
+
+	if o.config.DryRun {
+		for _, snapshot := range newSnapshots {
+			klog.Infof("[DRY-RUN] Would create snapshot %s@%s", snapshot.FilesystemName, snapshot.SnapshotName)
+		}
+		o.creationCount += len(newSnapshots)
+		return nil
+	}
+
+	if err := zfs.CreateSnapshots(o.backend, newSnapshots); err != nil {
+		o.creationFailures += len(newSnapshots)
+		return err
+	}
+
+	o.creationCount += len(newSnapshots)
+	for _, snapshot := range newSnapshots {
+		o.inventory.Add(snapshot)
+	}
+	klog.Infof("Successfully created %d snapshot(s) of %s", len(newSnapshots), root)
+	return nil
+}
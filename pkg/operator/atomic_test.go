@@ -0,0 +1,158 @@
+package operator
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/zfs"
+)
+
+func atomicMock() *mockZFSManager {
+	return &mockZFSManager{
+		pools: []*models.Pool{
+			{PoolName: "tank"},
+			{PoolName: "tank", FilesystemName: "tank/db"},
+			{PoolName: "tank", FilesystemName: "tank/db/data"},
+			{PoolName: "tank", FilesystemName: "tank/db/wal"},
+			{PoolName: "tank", FilesystemName: "tank/media"},
+		},
+		poolStatus: map[string]*models.PoolStatus{
+			"tank": {Name: "tank", State: "ONLINE", ErrorCount: "0"},
+		},
+	}
+}
+
+// TestRunAtomicSnapshots tests that datasets below an atomic root are snapshotted with one command per frequency
+func TestRunAtomicSnapshots(t *testing.T) {
+	cfg := hourlyOnlyConfig()
+	cfg.AtomicSnapshotRoots = []string{"tank/db"}
+	mock := atomicMock()
+	op := newMockOperator(cfg, mock)
+
+	now := time.Date(2026, 1, 25, 12, 5, 0, 0, time.UTC)
+	if err := op.RunAt(now); err != nil {
+		t.Fatalf("RunAt() error = %v", err)
+	}
+
+	if len(mock.createdBatches) != 1 {
+		t.Fatalf("Created %d atomic batch(es), want 1", len(mock.createdBatches))
+	}
+	batch := mock.createdBatches[0]
+	if len(batch) != 3 {
+		t.Fatalf("Atomic batch has %d snapshot(s), want 3", len(batch))
+	}
+	for _, snapshot := range batch {
+		if snapshot.SnapshotName != batch[0].SnapshotName {
+			t.Errorf("Snapshot names differ: %s vs %s", snapshot.SnapshotName, batch[0].SnapshotName)
+		}
+	}
+
+	// tank/media is created individually, the tree is not snapshotted twice
+	if len(mock.createdSnapshots) != 4 {
+		t.Errorf("Created %d snapshot(s), want 4", len(mock.createdSnapshots))
+	}
+
+	// A second run in the same period creates nothing
+	mock.createdBatches = nil
+	mock.createdSnapshots = nil
+	if err := op.RunAt(now.Add(10 * time.Minute)); err != nil {
+		t.Fatalf("RunAt() error = %v", err)
+	}
+	if len(mock.createdSnapshots) != 0 {
+		t.Errorf("Second run created %d snapshot(s), want 0", len(mock.createdSnapshots))
+	}
+}
+
+// TestRunAtomicSnapshotFailureKeepsSnapshots tests that a failed atomic snapshot skips the deletions of the tree
+func TestRunAtomicSnapshotFailureKeepsSnapshots(t *testing.T) {
+	cfg := hourlyOnlyConfig()
+	cfg.AtomicSnapshotRoots = []string{"tank/db"}
+	mock := atomicMock()
+	old := hourlySnapshot("tank/data", time.Date(2026, 1, 20, 12, 0, 0, 0, time.UTC))
+	old.FilesystemName = "tank/db/wal"
+	mock.snapshots = []*models.Snapshot{old}
+	mock.createError = errors.New("out of space")
+	op := newMockOperator(cfg, mock)
+
+	if err := op.RunAt(time.Date(2026, 1, 25, 12, 5, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("RunAt() error = %v", err)
+	}
+
+	if len(mock.deletedSnapshots) != 0 {
+		t.Errorf("Deleted %d snapshot(s) after a failed atomic snapshot, want 0", len(mock.deletedSnapshots))
+	}
+	if op.creationFailures != 4 {
+		t.Errorf("creationFailures = %d, want 4", op.creationFailures)
+	}
+}
+
+// TestRunAtomicSnapshotSkipUnchanged tests that an atomic snapshot is only skipped if no dataset of the tree changed
+func TestRunAtomicSnapshotSkipUnchanged(t *testing.T) {
+	tests := []struct {
+		name        string
+		walWritten  string
+		wantBatches int
+	}{
+		{name: "tree unchanged", walWritten: "0"},
+		{name: "one dataset changed", walWritten: "12K", wantBatches: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := hourlyOnlyConfig()
+			cfg.AtomicSnapshotRoots = []string{"tank/db"}
+			cfg.SkipUnchanged = []string{"hourly"}
+			mock := atomicMock()
+			mock.pools = mock.pools[1:4]
+			for _, pool := range mock.pools {
+				pool.Written = "0"
+				old := hourlySnapshot(pool.FilesystemName, time.Date(2026, 1, 25, 8, 0, 0, 0, time.UTC))
+				old.WrittenKnown = true
+				mock.snapshots = append(mock.snapshots, old)
+			}
+			mock.pools[2].Written = tt.walWritten
+			op := newMockOperator(cfg, mock)
+
+			if err := op.RunAt(time.Date(2026, 1, 25, 12, 5, 0, 0, time.UTC)); err != nil {
+				t.Fatalf("RunAt() error = %v", err)
+			}
+
+			if len(mock.createdBatches) != tt.wantBatches {
+				t.Fatalf("Created %d atomic batch(es), want %d", len(mock.createdBatches), tt.wantBatches)
+			}
+			if tt.wantBatches > 0 && len(mock.createdBatches[0]) != 3 {
+				t.Errorf("Atomic batch has %d snapshot(s), want 3", len(mock.createdBatches[0]))
+			}
+			if op.creationFailures != 0 {
+				t.Errorf("creationFailures = %d, want 0", op.creationFailures)
+			}
+		})
+	}
+}
+
+// TestRunAtomicSnapshotsWithoutBatchCreator tests that a backend without atomic snapshots gets one snapshot per dataset
+func TestRunAtomicSnapshotsWithoutBatchCreator(t *testing.T) {
+	cfg := hourlyOnlyConfig()
+	cfg.AtomicSnapshotRoots = []string{"tank/db"}
+	cfg.EnableLocking = false
+	mock := atomicMock()
+	mock.poolStatus = nil
+	// Only the core methods of the mock are visible to the operator
+	op := NewOperatorWithBackend(cfg, struct{ zfs.Backend }{mock})
+
+	if err := op.RunAt(time.Date(2026, 1, 25, 12, 5, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("RunAt() error = %v", err)
+	}
+
+	if len(mock.createdBatches) != 0 {
+		t.Errorf("Created %d atomic batch(es) without a BatchCreator, want 0", len(mock.createdBatches))
+	}
+	if len(mock.createdSnapshots) != 4 {
+		t.Errorf("Created %d snapshot(s), want 4", len(mock.createdSnapshots))
+	}
+	if op.creationFailures != 0 {
+		t.Errorf("creationFailures = %d, want 0", op.creationFailures)
+	}
+}
@@ -0,0 +1,132 @@
+package operator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/config"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/zfs"
+	"k8s.io/klog/v2"
+)
+
+// FreshnessViolation describes a dataset whose newest snapshot of a frequency is too old
+type FreshnessViolation struct {
+	Dataset        string     `json:"dataset"`
+	Frequency      string     `json:"frequency"`
+	NewestSnapshot string     `json:"newest_snapshot,omitempty"`
+	NewestTime     *time.Time `json:"newest_time,omitempty"`
+	PeriodsBehind  int        `json:"periods_behind"` // capped at MaxPeriods+1, -1 if there is no snapshot at all
+	MaxPeriods     int        `json:"max_periods"`
+}
+
+// FreshnessReport is the structured result of a freshness check
+type FreshnessReport struct {
+	CheckedAt       time.Time            `json:"checked_at"`
+	DatasetsChecked int                  `json:"datasets_checked"`
+	OK              bool                 `json:"ok"`
+	Violations      []FreshnessViolation `json:"violations"`
+}
+
+// CheckFreshness verifies that every managed dataset has a snapshot for each enabled frequency
+// that is at most FreshnessMaxPeriods periods behind now
+func (o *Operator) CheckFreshness(now time.Time) (*FreshnessReport, error) {
+	now = now.In(o.config.Location())
+
+	pools, err := o.backend.GetPools()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pools: %w", err)
+	}
+
+	inventory, err := zfs.LoadInventory(o.backend)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.checkFreshness(pools, inventory, now), nil
+}
+
+func (o *Operator) checkFreshness(pools []*models.Pool, inventory *zfs.Inventory, now time.Time) *FreshnessReport {
+	report := &FreshnessReport{
+		CheckedAt:  now,
+		Violations: []FreshnessViolation{},
+	}
+	maxPeriods := o.config.FreshnessMaxPeriods
+
+	for _, pool := range pools {
+		if !o.isManaged(pool) {
+			continue
+		}
+		report.DatasetsChecked++
+
+		for _, frequency := range o.config.TierNames() {
+			if o.config.GetPoolMaxSnapshots(frequency, pool) == 0 {
+				continue
+			}
+
+			var newest *models.Snapshot
+			for _, snapshot := range inventory.Get(pool.FilesystemName, frequency) {
+				if newest == nil || snapshot.DateTime.After(newest.DateTime) {
+					newest = snapshot
+				}
+			}
+
+			if newest == nil {
+				report.Violations = append(report.Violations, FreshnessViolation{
+					Dataset:       pool.FilesystemName,
+					Frequency:     frequency,
+					PeriodsBehind: -1,
+					MaxPeriods:    maxPeriods,
+				})
+				continue
+			}
+
+			tier, _ := o.config.Tier(frequency)
+			behind := periodsBetween(newest.DateTime, now, tier, maxPeriods+1)
+			// A tier that skips unchanged datasets is not behind as long as nothing was written
+			if behind > maxPeriods && o.config.IsSkipUnchanged(frequency) && isUnchangedSince(pool, inventory, newest) {
+				klog.V(1).Infof("Freshness check: %s snapshot %s of %s still covers the unchanged dataset",
+					frequency, newest.SnapshotName, pool.FilesystemName)
+				continue
+			}
+			if behind > maxPeriods {
+				newestTime := newest.DateTime
+				report.Violations = append(report.Violations, FreshnessViolation{
+					Dataset:        pool.FilesystemName,
+					Frequency:      frequency,
+					NewestSnapshot: newest.SnapshotName,
+					NewestTime:     &newestTime,
+					PeriodsBehind:  behind,
+					MaxPeriods:     maxPeriods,
+				})
+			}
+		}
+	}
+
+	report.OK = len(report.Violations) == 0
+	return report
+}
+
+// logFreshnessReport logs every violation of a freshness report
+func logFreshnessReport(report *FreshnessReport) {
+	for _, v := range report.Violations {
+		if v.PeriodsBehind < 0 {
+			klog.Warningf(" Freshness check: %s has no %s snapshot", v.Dataset, v.Frequency)
+		} else {
+			klog.Warningf(" Freshness check: newest %s snapshot of %s (%s) is more than %d period(s) behind",
+				v.Frequency, v.Dataset, v.NewestSnapshot, v.MaxPeriods)
+		}
+	}
+}
+
+// periodsBetween counts the period boundaries of the given tier between from and to
+// Counting stops at limit, so a very old snapshot does not cause a long loop
+// Periods are counted in the time zone of to
+func periodsBetween(from, to time.Time, tier config.Tier, limit int) int {
+	from = from.In(to.Location())
+	count := 0
+	for t := tier.NextPeriodStart(from); !t.After(to) && count < limit; t = tier.NextPeriodStart(t) {
+		count++
+	}
+	return count
+}
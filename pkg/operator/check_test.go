@@ -0,0 +1,137 @@
+package operator
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/config"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+)
+
+func hourlyOnlyConfig() *config.Config {
+	cfg := config.NewConfig("test")
+	cfg.MaxHourlySnapshots = 24
+	cfg.MaxDailySnapshots = 0
+	cfg.MaxWeeklySnapshots = 0
+	cfg.MaxMonthlySnapshots = 0
+	cfg.MaxYearlySnapshots = 0
+	cfg.FreshnessMaxPeriods = 1
+	return cfg
+}
+
+// hourlySnapshot returns an hourly snapshot of a dataset of the pool tank
+func hourlySnapshot(filesystemName string, dateTime time.Time) *models.Snapshot {
+	return &models.Snapshot{
+		PoolName:       "tank",
+		FilesystemName: filesystemName,
+		SnapshotName:   "autosnap_" + dateTime.Format("2006-01-02_15:04:05") + "_hourly",
+		DateTime:       dateTime,
+		Frequency:      "hourly",
+	}
+}
+
+func TestCheckFreshness(t *testing.T) {
+	now := time.Date(2026, 1, 25, 12, 5, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		snapshots     []*models.Snapshot
+		wantOK        bool
+		wantBehind    int
+		wantDatasets  int
+		extraPools    []*models.Pool
+		filesystemsWL []string
+	}{
+		{
+			name:         "snapshot in current period",
+			snapshots:    []*models.Snapshot{hourlySnapshot("tank/data", time.Date(2026, 1, 25, 12, 0, 0, 0, time.UTC))},
+			wantOK:       true,
+			wantDatasets: 1,
+		},
+		{
+			name:         "snapshot in previous period is tolerated",
+			snapshots:    []*models.Snapshot{hourlySnapshot("tank/data", time.Date(2026, 1, 25, 11, 0, 0, 0, time.UTC))},
+			wantOK:       true,
+			wantDatasets: 1,
+		},
+		{
+			name:         "snapshot two periods behind",
+			snapshots:    []*models.Snapshot{hourlySnapshot("tank/data", time.Date(2026, 1, 25, 10, 0, 0, 0, time.UTC))},
+			wantOK:       false,
+			wantBehind:   2,
+			wantDatasets: 1,
+		},
+		{
+			name:         "no snapshot at all",
+			wantOK:       false,
+			wantBehind:   -1,
+			wantDatasets: 1,
+		},
+		{
+			name:          "datasets outside the whitelist are ignored",
+			extraPools:    []*models.Pool{{PoolName: "tank", FilesystemName: "tank/scratch"}},
+			snapshots:     []*models.Snapshot{hourlySnapshot("tank/data", time.Date(2026, 1, 25, 12, 0, 0, 0, time.UTC))},
+			filesystemsWL: []string{"tank/data"},
+			wantOK:        true,
+			wantDatasets:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := hourlyOnlyConfig()
+			cfg.FilesystemWhitelist = tt.filesystemsWL
+			mock := &mockZFSManager{
+				pools:     append([]*models.Pool{{PoolName: "tank"}, {PoolName: "tank", FilesystemName: "tank/data"}}, tt.extraPools...),
+				snapshots: tt.snapshots,
+			}
+			op := newMockOperator(cfg, mock)
+
+			report, err := op.CheckFreshness(now)
+			if err != nil {
+				t.Fatalf("CheckFreshness() error = %v", err)
+			}
+
+			if report.OK != tt.wantOK {
+				t.Errorf("report.OK = %v, want %v (violations: %+v)", report.OK, tt.wantOK, report.Violations)
+			}
+			if report.DatasetsChecked != tt.wantDatasets {
+				t.Errorf("report.DatasetsChecked = %d, want %d", report.DatasetsChecked, tt.wantDatasets)
+			}
+			if !tt.wantOK {
+				if len(report.Violations) != 1 {
+					t.Fatalf("Got %d violation(s), want 1", len(report.Violations))
+				}
+				v := report.Violations[0]
+				if v.Dataset != "tank/data" || v.Frequency != "hourly" || v.PeriodsBehind != tt.wantBehind {
+					t.Errorf("Violation = %+v, want tank/data hourly %d periods behind", v, tt.wantBehind)
+				}
+			}
+		})
+	}
+}
+
+func TestRunWithFreshnessPhase(t *testing.T) {
+	cfg := hourlyOnlyConfig()
+	cfg.CheckFreshness = true
+	mock := &mockZFSManager{
+		pools: []*models.Pool{{PoolName: "tank", FilesystemName: "tank/data"}},
+		poolStatus: map[string]*models.PoolStatus{
+			"tank": {Name: "tank", State: "ONLINE", ErrorCount: "0"},
+		},
+	}
+	op := newMockOperator(cfg, mock)
+
+	// The run creates the missing snapshot, so the freshness phase passes
+	if err := op.Run(); err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+
+	// If creation fails, the freshness phase reports the gap
+	mock.snapshots = nil
+	mock.createError = fmt.Errorf("out of space")
+	if err := op.Run(); err == nil {
+		t.Error("Run() should fail when the freshness phase finds violations")
+	}
+}
@@ -0,0 +1,85 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// DefaultDaemonInterval returns the frequency the daemon should be scheduled on
+// This is the tier with the shortest period among frequently, hourly and the custom tiers that any
+// managed dataset keeps, with its effective retention from the policy file, environment or user
+// properties, so every enabled tier of every dataset gets a run in each of its periods
+func (o *Operator) DefaultDaemonInterval() (string, error) {
+	pools, err := o.backend.GetPools()
+	if err != nil {
+		return "", fmt.Errorf("failed to get pools: %w", err)
+	}
+
+	for _, tier := range o.config.Tiers() {
+		if tier.Name == "hourly" {
+			break
+		}
+		for _, pool := range pools {
+			if o.isManaged(pool) && o.config.GetPoolMaxSnapshots(tier.Name, pool) > 0 {
+				return tier.Name, nil
+			}
+		}
+	}
+	return "hourly", nil
+}
+
+// RunDaemon keeps the operator alive and triggers a run at every period boundary of the given frequency
+// The first run happens immediately. A failed run is logged and the daemon carries on with the
+// next period. Without an interval it is derived with DefaultDaemonInterval after every run, so
+// retention changed in user properties is picked up without a restart
+// RunDaemon returns when ctx is cancelled; a run in progress is allowed to finish first
+func (o *Operator) RunDaemon(ctx context.Context, interval string) error {
+	if interval != "" {
+		if _, ok := o.config.Tier(interval); !ok {
+			return fmt.Errorf("invalid interval %q, must be one of: %v", interval, o.config.TierNames())
+		}
+		klog.Infof("Starting daemon with %s interval", interval)
+	} else {
+		klog.Infof("Starting daemon with the shortest interval of the managed datasets")
+	}
+
+	current := interval
+	for {
+		if err := o.Run(); err != nil {
+			klog.Infof("Run failed: %v", err)
+		}
+		if err := o.ExportMetrics(); err != nil {
+			klog.Infof("Failed to export metrics: %v", err)
+		}
+
+		if interval == "" {
+			derived, err := o.DefaultDaemonInterval()
+			switch {
+			case err != nil && current == "":
+				klog.Warningf(" Failed to derive the daemon interval, using hourly: %v", err)
+				current = "hourly"
+			case err != nil:
+				klog.Warningf(" Failed to derive the daemon interval, keeping %s: %v", current, err)
+			case derived != current:
+				klog.Infof("Daemon interval is now %s", derived)
+				current = derived
+			}
+		}
+
+		tier, _ := o.config.Tier(current)
+		next := tier.NextPeriodStart(time.Now().In(o.config.Location()))
+		klog.Infof("Next run scheduled at %s", next.Format("2006-01-02 15:04:05"))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			klog.Infof("Daemon stopped: %v", ctx.Err())
+			return nil
+		case <-timer.C:
+		}
+	}
+}
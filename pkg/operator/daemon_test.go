@@ -0,0 +1,140 @@
+package operator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/config"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+)
+
+func TestRunDaemonStopsOnCancel(t *testing.T) {
+	cfg := config.NewConfig("test")
+	mock := &mockZFSManager{
+		pools: []*models.Pool{{PoolName: "tank", FilesystemName: "tank/data"}},
+		poolStatus: map[string]*models.PoolStatus{
+			"tank": {Name: "tank", State: "ONLINE", ErrorCount: "0"},
+		},
+	}
+	op := newMockOperator(cfg, mock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := op.RunDaemon(ctx, "hourly"); err != nil {
+		t.Fatalf("RunDaemon() error = %v", err)
+	}
+
+	// The first run happens immediately, then the cancelled context stops the daemon
+	if len(mock.createdSnapshots) == 0 {
+		t.Error("RunDaemon() should run once before stopping")
+	}
+}
+
+func TestRunDaemonContinuesAfterFailedRun(t *testing.T) {
+	cfg := config.NewConfig("test")
+	mock := &mockZFSManager{
+		pools: []*models.Pool{{PoolName: "tank", FilesystemName: "tank/data"}},
+		poolStatus: map[string]*models.PoolStatus{
+			"tank": {Name: "tank", State: "DEGRADED"},
+		},
+	}
+	op := newMockOperator(cfg, mock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// An unhealthy pool fails the run, but the daemon itself must not return an error
+	if err := op.RunDaemon(ctx, "hourly"); err != nil {
+		t.Errorf("RunDaemon() error = %v, want nil", err)
+	}
+}
+
+func TestRunDaemonDerivedInterval(t *testing.T) {
+	mock := &mockZFSManager{
+		pools: []*models.Pool{{PoolName: "tank", FilesystemName: "tank/data"}},
+		poolStatus: map[string]*models.PoolStatus{
+			"tank": {Name: "tank", State: "ONLINE", ErrorCount: "0"},
+		},
+	}
+	op := newMockOperator(config.NewConfig("test"), mock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Without an interval the daemon derives it from the datasets after the first run
+	if err := op.RunDaemon(ctx, ""); err != nil {
+		t.Fatalf("RunDaemon() error = %v", err)
+	}
+	if len(mock.createdSnapshots) == 0 {
+		t.Error("RunDaemon() should run once before stopping")
+	}
+}
+
+func TestRunDaemonInvalidInterval(t *testing.T) {
+	op := newMockOperator(config.NewConfig("test"), &mockZFSManager{})
+
+	if err := op.RunDaemon(context.Background(), "fortnightly"); err == nil {
+		t.Error("RunDaemon() should reject an unknown interval")
+	}
+}
+
+// TestDefaultDaemonInterval tests that the interval follows the effective retention of the managed datasets
+func TestDefaultDaemonInterval(t *testing.T) {
+	tests := []struct {
+		name       string
+		frequently int
+		policy     map[string]config.Policy
+		properties map[string]models.Property
+		want       string
+	}{
+		{
+			name: "frequently disabled",
+			want: "hourly",
+		},
+		{
+			name:       "frequently enabled globally",
+			frequently: 4,
+			want:       "frequently",
+		},
+		{
+			name:   "frequently enabled by a dataset policy",
+			policy: map[string]config.Policy{"tank/db": {Retention: map[string]int{"frequently": 4}}},
+			want:   "frequently",
+		},
+		{
+			name: "frequently enabled by a user property",
+			properties: map[string]models.Property{
+				"zfs-snapshot-operator:frequently": {Value: "4", Source: "tank/db"},
+			},
+			want: "frequently",
+		},
+		{
+			name:       "frequently disabled on the only dataset",
+			frequently: 4,
+			policy:     map[string]config.Policy{"tank/db": {Retention: map[string]int{"frequently": 0}}},
+			want:       "hourly",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewConfig("test")
+			cfg.MaxFrequentlySnapshots = tt.frequently
+			cfg.DatasetPolicies = tt.policy
+			cfg.HonorUserProperties = true
+			mock := &mockZFSManager{
+				pools: []*models.Pool{{PoolName: "tank", FilesystemName: "tank/db", Properties: tt.properties}},
+			}
+			op := newMockOperator(cfg, mock)
+
+			got, err := op.DefaultDaemonInterval()
+			if err != nil {
+				t.Fatalf("DefaultDaemonInterval() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("DefaultDaemonInterval() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
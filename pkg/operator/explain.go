@@ -0,0 +1,81 @@
+package operator
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/zfs"
+)
+
+// isManaged checks if a filesystem passes the whitelists and is enabled by the policy file and its user properties
+func (o *Operator) isManaged(pool *models.Pool) bool {
+	return pool.FilesystemName != "" &&
+		o.config.IsPoolAllowed(pool.PoolName) &&
+		o.config.IsFilesystemAllowed(pool.FilesystemName) &&
+		o.config.IsPoolEnabled(pool)
+}
+
+// healthyPools returns which pools of the status map are whitelisted and healthy
+func (o *Operator) healthyPools(poolStatus map[string]*models.PoolStatus) map[string]bool {
+	healthy := make(map[string]bool)
+	for name := range poolStatus {
+		healthy[name] = o.config.IsPoolAllowed(name) && zfs.IsPoolHealthy(name, poolStatus)
+	}
+	return healthy
+}
+
+// managedFilesystems returns the managed filesystems on healthy pools, whose snapshots may be pruned
+// for space and whose stale holds may be released
+func (o *Operator) managedFilesystems(pools []*models.Pool, healthy map[string]bool) map[string]bool {
+	managed := make(map[string]bool)
+	for _, pool := range pools {
+		if healthy[pool.PoolName] && o.isManaged(pool) {
+			managed[pool.FilesystemName] = true
+		}
+	}
+	return managed
+}
+
+// Explain writes the effective settings of every filesystem and where each value came from
+func (o *Operator) Explain(w io.Writer) error {
+	pools, err := o.backend.GetPools()
+	if err != nil {
+		return fmt.Errorf("failed to get pools: %w", err)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATASET\tSETTING\tVALUE\tSOURCE")
+
+	for _, pool := range pools {
+		if pool.FilesystemName == "" {
+			continue
+		}
+
+		if allowed, reason := o.config.FilterPool(pool.PoolName); !allowed {
+			fmt.Fprintf(tw, "%s\tmanaged\tfalse\tpool %s\n", pool.FilesystemName, reason)
+			continue
+		}
+		if allowed, reason := o.config.FilterFilesystem(pool.FilesystemName); !allowed {
+			fmt.Fprintf(tw, "%s\tmanaged\tfalse\tfilesystem %s\n", pool.FilesystemName, reason)
+			continue
+		}
+
+		enabled, source := o.config.ResolvePoolEnabled(pool)
+		fmt.Fprintf(tw, "%s\tmanaged\t%t\t%s\n", pool.FilesystemName, enabled, source)
+		if !enabled {
+			continue
+		}
+
+		for _, frequency := range o.config.TierNames() {
+			value, source := o.config.ResolvePoolMaxSnapshots(frequency, pool)
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", pool.FilesystemName, frequency, value, source)
+		}
+
+		minKeep, source := o.config.ResolveMinKeep(pool.FilesystemName)
+		fmt.Fprintf(tw, "%s\tmin_keep\t%d\t%s\n", pool.FilesystemName, minKeep, source)
+	}
+
+	return tw.Flush()
+}
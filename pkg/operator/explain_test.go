@@ -0,0 +1,95 @@
+package operator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/config"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+)
+
+func disabledScratchConfig(t *testing.T) *config.Config {
+	t.Helper()
+	policy, err := config.ParsePolicyFile([]byte(`
+pools:
+  tank:
+    retention:
+      daily: 14
+datasets:
+  tank/scratch:
+    enabled: false
+`))
+	if err != nil {
+		t.Fatalf("ParsePolicyFile() error = %v", err)
+	}
+	cfg := config.NewConfig("test")
+	cfg.PoolPolicies = policy.Pools
+	cfg.DatasetPolicies = policy.Datasets
+	return cfg
+}
+
+func scratchPools() []*models.Pool {
+	return []*models.Pool{
+		{PoolName: "tank"},
+		{PoolName: "tank", FilesystemName: "tank/data"},
+		{PoolName: "tank", FilesystemName: "tank/scratch"},
+		{PoolName: "tank", FilesystemName: "tank/scratch/tmp"},
+	}
+}
+
+// TestRunSkipsDisabledDatasets tests that disabled datasets and their descendants are not snapshotted
+func TestRunSkipsDisabledDatasets(t *testing.T) {
+	mock := &mockZFSManager{
+		pools: scratchPools(),
+		poolStatus: map[string]*models.PoolStatus{
+			"tank": {Name: "tank", State: "ONLINE", ErrorCount: "0"},
+		},
+	}
+	op := newMockOperator(disabledScratchConfig(t), mock)
+
+	if err := op.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	for _, snapshot := range mock.createdSnapshots {
+		if strings.HasPrefix(snapshot.FilesystemName, "tank/scratch") {
+			t.Errorf("Snapshot created on disabled dataset %s", snapshot.FilesystemName)
+		}
+	}
+	if len(mock.createdSnapshots) != 5 {
+		t.Errorf("Created %d snapshot(s), want 5", len(mock.createdSnapshots))
+	}
+}
+
+// TestExplain tests that the effective settings are printed together with their source
+func TestExplain(t *testing.T) {
+	mock := &mockZFSManager{pools: scratchPools()}
+	op := newMockOperator(disabledScratchConfig(t), mock)
+
+	var buf bytes.Buffer
+	if err := op.Explain(&buf); err != nil {
+		t.Fatalf("Explain() error = %v", err)
+	}
+
+	// Normalize the tabwriter padding to single spaces
+	var lines []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	output := strings.Join(lines, "\n")
+
+	for _, want := range []string{
+		"tank/data daily 14 policy pools[tank]",
+		"tank/data hourly 24 global default",
+		"tank/scratch managed false policy datasets[tank/scratch]",
+		"tank/scratch/tmp managed false policy datasets[tank/scratch]",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Explain() output missing %q\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "tank/scratch daily") {
+		t.Errorf("Explain() printed retention for a disabled dataset\n%s", output)
+	}
+}
@@ -0,0 +1,147 @@
+package operator
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/zfs"
+	"k8s.io/klog/v2"
+)
+
+// Hold places the operator-owned hold name on a snapshot given as dataset@snapshot
+// The snapshot is pinned: retention and space-pressure pruning keep it until the hold is released
+func (o *Operator) Hold(snapshotName, name string) error {
+	snapshot, tag, err := o.holdTarget(snapshotName, name)
+	if err != nil {
+		return err
+	}
+
+	if o.config.DryRun {
+		klog.Infof("[DRY-RUN] Would place hold %s on %s", tag, snapshotName)
+		return nil
+	}
+	if err := zfs.HoldSnapshot(o.backend, snapshot, tag); err != nil {
+		return fmt.Errorf("failed to hold %s: %w", snapshotName, err)
+	}
+	klog.Infof("Placed hold %s on %s", tag, snapshotName)
+	return nil
+}
+
+// Release releases the operator-owned hold name from a snapshot given as dataset@snapshot
+func (o *Operator) Release(snapshotName, name string) error {
+	snapshot, tag, err := o.holdTarget(snapshotName, name)
+	if err != nil {
+		return err
+	}
+
+	if o.config.DryRun {
+		klog.Infof("[DRY-RUN] Would release hold %s from %s", tag, snapshotName)
+		return nil
+	}
+	if err := zfs.ReleaseSnapshot(o.backend, snapshot, tag); err != nil {
+		return fmt.Errorf("failed to release %s: %w", snapshotName, err)
+	}
+	klog.Infof("Released hold %s from %s", tag, snapshotName)
+	return nil
+}
+
+// holdTarget returns the snapshot and the operator-owned tag for a hold command
+func (o *Operator) holdTarget(snapshotName, name string) (*models.Snapshot, string, error) {
+	filesystemName, shortName, ok := strings.Cut(snapshotName, "@")
+	if !ok || filesystemName == "" || shortName == "" {
+		return nil, "", fmt.Errorf("invalid snapshot %q, expected dataset@snapshot", snapshotName)
+	}
+	tag, err := o.config.HoldTag(name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	poolName, _, _ := strings.Cut(filesystemName, "/")
+	snapshot := &models.Snapshot{PoolName: poolName, FilesystemName: filesystemName, SnapshotName: shortName}
+	return snapshot, tag, nil
+}
+
+// loadHolds lists the hold tags of all held snapshots of the inventory and releases operator-owned
+// holds older than HOLD_MAX_AGE, so a crashed replication job cannot pin its snapshots forever
+// Stale holds are only released on managed datasets of healthy pools, like space-pressure pruning
+// If the holds cannot be listed, held snapshots are still kept from deletion by their userrefs
+func (o *Operator) loadHolds(pools []*models.Pool, poolStatus map[string]*models.PoolStatus, now time.Time) {
+	o.holds = make(map[string][]*models.Hold)
+
+	held := make(map[string]*models.Snapshot)
+	var snapshots []*models.Snapshot
+	for _, snapshot := range o.inventory.All() {
+		if snapshot.UserRefs > 0 {
+			held[holdKey(snapshot.FilesystemName, snapshot.SnapshotName)] = snapshot
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	if len(snapshots) == 0 {
+		return
+	}
+
+	holds, err := zfs.GetHolds(o.backend, snapshots)
+	if err != nil {
+		klog.Warningf(" Failed to list the holds of %d snapshot(s): %v", len(snapshots), err)
+		return
+	}
+
+	managed := o.managedFilesystems(pools, o.healthyPools(poolStatus))
+	for _, hold := range holds {
+		key := holdKey(hold.FilesystemName, hold.SnapshotName)
+		if managed[hold.FilesystemName] && o.isStaleHold(hold, now) {
+			if o.config.DryRun {
+				klog.Infof("[DRY-RUN] Would release stale hold %s from %s, placed %s", hold.Tag, key, hold.Time.Format(time.RFC3339))
+			} else if err := zfs.ReleaseSnapshot(o.backend, &models.Snapshot{FilesystemName: hold.FilesystemName, SnapshotName: hold.SnapshotName}, hold.Tag); err != nil {
+				klog.Warningf(" Failed to release stale hold %s from %s: %v", hold.Tag, key, err)
+			} else {
+				klog.Infof("Released stale hold %s from %s, placed %s", hold.Tag, key, hold.Time.Format(time.RFC3339))
+				if snapshot := held[key]; snapshot != nil && snapshot.UserRefs > 0 {
+					snapshot.UserRefs--
+				}
+				continue
+			}
+		}
+		o.holds[key] = append(o.holds[key], hold)
+	}
+}
+
+// isStaleHold checks if an operator-owned hold is older than HOLD_MAX_AGE
+// Holds of other tools and administrators never expire
+func (o *Operator) isStaleHold(hold *models.Hold, now time.Time) bool {
+	if o.config.HoldMaxAge <= 0 || hold.Time.IsZero() || !o.config.IsOperatorHold(hold.Tag) {
+		return false
+	}
+	return now.Sub(hold.Time) > o.config.HoldMaxAge
+}
+
+// pinnedBy returns the operator-owned hold tag that pins a snapshot, or empty if it is not pinned
+func (o *Operator) pinnedBy(snapshot *models.Snapshot) string {
+	for _, hold := range o.holds[holdKey(snapshot.FilesystemName, snapshot.SnapshotName)] {
+		if o.config.IsOperatorHold(hold.Tag) {
+			return hold.Tag
+		}
+	}
+	return ""
+}
+
+// applyPins moves the pinned snapshots from the delete list to the keep list
+// Pinned snapshots are kept in addition to the snapshots retention keeps
+func (o *Operator) applyPins(keep, del []*models.Snapshot) ([]*models.Snapshot, []*models.Snapshot) {
+	remaining := del[:0:0]
+	for _, snapshot := range del {
+		if o.pinnedBy(snapshot) != "" {
+			keep = append(keep, snapshot)
+		} else {
+			remaining = append(remaining, snapshot)
+		}
+	}
+	return keep, remaining
+}
+
+// holdKey returns the key of the holds of a snapshot (e.g., "tank/data@autosnap_...")
+func holdKey(filesystemName, snapshotName string) string {
+	return filesystemName + "@" + snapshotName
+}
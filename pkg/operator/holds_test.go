@@ -0,0 +1,178 @@
+package operator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/config"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+)
+
+func TestPinnedSnapshots(t *testing.T) {
+	base := time.Date(2026, 1, 25, 10, 0, 0, 0, time.UTC)
+	now := base.Add(6 * time.Hour)
+
+	tests := []struct {
+		name         string
+		deferDestroy bool
+		maxAge       time.Duration
+		dryRun       bool
+		excluded     bool   // The dataset is blacklisted
+		poolState    string // State of the pool (default ONLINE)
+		wantDeleted  int
+		wantReleased int
+		wantRetained int
+		wantPinned   bool
+	}{
+		{name: "pinned snapshots are kept", wantDeleted: 1, wantRetained: 1, wantPinned: true},
+		{name: "deferred destroy skips pinned snapshots", deferDestroy: true, wantDeleted: 2, wantPinned: true},
+		{name: "fresh operator holds are kept", maxAge: 72 * time.Hour, wantDeleted: 1, wantRetained: 1, wantPinned: true},
+		{name: "stale operator holds expire", maxAge: 24 * time.Hour, wantDeleted: 2, wantReleased: 1, wantRetained: 1},
+		{name: "dry-run keeps stale holds", maxAge: 24 * time.Hour, dryRun: true, wantDeleted: 1, wantRetained: 1, wantPinned: true},
+		// Only managed datasets of healthy pools are touched
+		{name: "unmanaged dataset keeps stale holds", maxAge: 24 * time.Hour, excluded: true, wantDeleted: 1, wantRetained: 1, wantPinned: true},
+		{name: "degraded pool keeps stale holds", maxAge: 24 * time.Hour, poolState: "DEGRADED", wantDeleted: 1, wantRetained: 1, wantPinned: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewConfig("test")
+			cfg.MaxHourlySnapshots = 0
+			cfg.DeferDestroy = tt.deferDestroy
+			cfg.HoldMaxAge = tt.maxAge
+			cfg.DryRun = tt.dryRun
+			if tt.excluded {
+				cfg.FilesystemBlacklist = []string{"tank/data"}
+			}
+			poolState := tt.poolState
+			if poolState == "" {
+				poolState = "ONLINE"
+			}
+
+			pinned := hourlySnapshot("tank/data", base)
+			pinned.UserRefs = 1
+			foreign := hourlySnapshot("tank/data", base.Add(time.Hour))
+			foreign.UserRefs = 1
+			free := hourlySnapshot("tank/data", base.Add(2*time.Hour))
+			newest := hourlySnapshot("tank/data", base.Add(3*time.Hour))
+
+			mock := &mockZFSManager{
+				snapshots: []*models.Snapshot{pinned, foreign, free, newest},
+				holds: []*models.Hold{
+					{FilesystemName: "tank/data", SnapshotName: pinned.SnapshotName, Tag: "zfs-snapshot-operator:replication", Time: now.Add(-48 * time.Hour)},
+					// Holds of other tools never expire
+					{FilesystemName: "tank/data", SnapshotName: foreign.SnapshotName, Tag: "backup", Time: now.Add(-48 * time.Hour)},
+				},
+			}
+			op := newMockOperator(cfg, mock)
+			loadInventory(t, op)
+			pool := &models.Pool{PoolName: "tank", FilesystemName: "tank/data"}
+			poolStatus := map[string]*models.PoolStatus{"tank": {Name: "tank", State: poolState, ErrorCount: "0"}}
+			op.loadHolds([]*models.Pool{pool}, poolStatus, now)
+
+			if err := op.processFrequency(pool, "hourly", now); err != nil {
+				t.Fatalf("processFrequency() error = %v", err)
+			}
+
+			deleted := len(mock.deletedSnapshots)
+			if tt.dryRun {
+				deleted = op.deletionCount
+			}
+			if deleted != tt.wantDeleted {
+				t.Errorf("Deleted %d snapshot(s), want %d", deleted, tt.wantDeleted)
+			}
+			if len(mock.releasedHolds) != tt.wantReleased {
+				t.Errorf("Released %d hold(s), want %d", len(mock.releasedHolds), tt.wantReleased)
+			}
+			if op.retainedCount != tt.wantRetained {
+				t.Errorf("retainedCount = %d, want %d", op.retainedCount, tt.wantRetained)
+			}
+			if got := deletedNames(mock)[pinned.SnapshotName]; got == tt.wantPinned {
+				t.Errorf("pinned snapshot deleted = %v, want %v", got, !tt.wantPinned)
+			}
+			if got := op.pinnedBy(pinned) != ""; got != tt.wantPinned {
+				t.Errorf("pinnedBy() pinned = %v, want %v", got, tt.wantPinned)
+			}
+			if op.pinnedBy(foreign) != "" {
+				t.Errorf("Snapshot %s is pinned by a hold of another tool", foreign.SnapshotName)
+			}
+		})
+	}
+}
+
+// TestPinnedSnapshotsBeyondRetention tests that pinned snapshots do not take the slots of retention
+func TestPinnedSnapshotsBeyondRetention(t *testing.T) {
+	cfg := hourlyOnlyConfig()
+	cfg.MaxHourlySnapshots = 2
+	cfg.RetentionMode = config.RetentionModeCount
+
+	now := time.Date(2026, 1, 25, 12, 5, 0, 0, time.UTC)
+	var snapshots []*models.Snapshot
+	for i := 0; i < 5; i++ {
+		snapshots = append(snapshots, hourlySnapshot("tank/data", now.Add(-time.Duration(i)*time.Hour)))
+	}
+	oldest := snapshots[4]
+	oldest.UserRefs = 1
+	expired := []string{snapshots[2].SnapshotName, snapshots[3].SnapshotName}
+
+	mock := &mockZFSManager{
+		snapshots: snapshots,
+		holds:     []*models.Hold{{FilesystemName: "tank/data", SnapshotName: oldest.SnapshotName, Tag: "zfs-snapshot-operator:manual"}},
+	}
+	op := newMockOperator(cfg, mock)
+	loadInventory(t, op)
+	pool := &models.Pool{PoolName: "tank", FilesystemName: "tank/data"}
+	poolStatus := map[string]*models.PoolStatus{"tank": {Name: "tank", State: "ONLINE", ErrorCount: "0"}}
+	op.loadHolds([]*models.Pool{pool}, poolStatus, now)
+
+	if err := op.processFrequency(pool, "hourly", now); err != nil {
+		t.Fatalf("processFrequency() error = %v", err)
+	}
+
+	deleted := deletedNames(mock)
+	if len(deleted) != 2 || !deleted[expired[0]] || !deleted[expired[1]] {
+		t.Errorf("Deleted %v, want %v", deleted, expired)
+	}
+}
+
+func TestHoldAndRelease(t *testing.T) {
+	cfg := config.NewConfig("test")
+	mock := &mockZFSManager{}
+	op := newMockOperator(cfg, mock)
+
+	if err := op.Hold("tank/data@autosnap_2026-01-25_12:00:00_hourly", "replication"); err != nil {
+		t.Fatalf("Hold() error = %v", err)
+	}
+	if len(mock.holds) != 1 {
+		t.Fatalf("Placed %d hold(s), want 1", len(mock.holds))
+	}
+	hold := mock.holds[0]
+	if hold.FilesystemName != "tank/data" || hold.SnapshotName != "autosnap_2026-01-25_12:00:00_hourly" || hold.Tag != "zfs-snapshot-operator:replication" {
+		t.Errorf("Hold() placed %+v", hold)
+	}
+
+	if err := op.Release("tank/data@autosnap_2026-01-25_12:00:00_hourly", "replication"); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if len(mock.holds) != 0 {
+		t.Errorf("%d hold(s) left after Release(), want 0", len(mock.holds))
+	}
+
+	for _, args := range [][2]string{
+		{"tank/data", "replication"},
+		{"tank/data@", "replication"},
+		{"tank/data@snap", ""},
+	} {
+		if err := op.Hold(args[0], args[1]); err == nil {
+			t.Errorf("Hold(%q, %q) error = nil, want an error", args[0], args[1])
+		}
+	}
+
+	cfg.DryRun = true
+	if err := op.Hold("tank/data@snap", "replication"); err != nil {
+		t.Fatalf("Hold() error = %v", err)
+	}
+	if len(mock.holds) != 0 {
+		t.Errorf("Hold() placed a hold in dry-run mode")
+	}
+}
@@ -1,13 +1,16 @@
 package operator
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"sort"
 	"time"
 
 	"github.com/runningman84/zfs-snapshot-operator/pkg/config"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/metrics"
 	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/naming"
 	"github.com/runningman84/zfs-snapshot-operator/pkg/zfs"
 	"k8s.io/klog/v2"
 )
@@ -15,21 +18,93 @@ import (
 // Operator manages ZFS snapshot operations
 type Operator struct {
 	config        *config.Config
-	manager       *zfs.Manager
+	backend       zfs.Backend
+	metrics       *metrics.Collector
 	deletionCount int // Track number of deletions in current run
 	creationCount int // Track number of creations in current run
+
+	deletionFailures int // Track number of failed deletions in current run
+	creationFailures int // Track number of failed creations in current run
+	retainedCount    int // Track number of deletions skipped because of a hold or clone in current run
+
+	inventory        *zfs.Inventory            // Snapshots of the current run, loaded once by run
+	holds            map[string][]*models.Hold // Hold tags of the held snapshots of the current run, keyed by holdKey
+	atomicErrors     map[string]error          // Failed atomic snapshots of the current run, keyed by atomicKey
+	plannedDeletions []*models.Snapshot        // Snapshots a dry-run would delete, for the reclaim estimate
 }
 
-// NewOperator creates a new operator instance
+// NewOperator creates a new operator instance backed by the zfs/zpool command line tools
 func NewOperator(cfg *config.Config) *Operator {
+	return NewOperatorWithBackend(cfg, zfs.NewManager(cfg))
+}
+
+// NewOperatorWithBackend creates a new operator instance that uses the given backend
+// for all ZFS operations (e.g. an in-memory or remote implementation)
+func NewOperatorWithBackend(cfg *config.Config, backend zfs.Backend) *Operator {
 	return &Operator{
 		config:  cfg,
-		manager: zfs.NewManager(cfg),
+		backend: backend,
+		metrics: metrics.NewCollector(),
+	}
+}
+
+// Metrics returns the metrics collector of the operator
+func (o *Operator) Metrics() *metrics.Collector {
+	return o.metrics
+}
+
+// ExportMetrics writes the metrics of the last run to the configured textfile and/or Pushgateway
+// This is meant for CronJob mode, where there is no long-lived process to scrape
+func (o *Operator) ExportMetrics() error {
+	if o.config.MetricsTextfilePath != "" {
+		if err := o.metrics.WriteTextfile(o.config.MetricsTextfilePath); err != nil {
+			return fmt.Errorf("failed to write metrics textfile: %w", err)
+		}
+		klog.V(1).Infof("Wrote metrics to %s", o.config.MetricsTextfilePath)
 	}
+
+	if o.config.PushgatewayURL != "" {
+		if err := o.metrics.Push(o.config.PushgatewayURL, o.config.PushgatewayJob); err != nil {
+			return fmt.Errorf("failed to push metrics: %w", err)
+		}
+		klog.V(1).Infof("Pushed metrics to %s", o.config.PushgatewayURL)
+	}
+
+	return nil
 }
 
 // Run executes the snapshot management logic
 func (o *Operator) Run() error {
+	return o.RunAt(time.Now())
+}
+
+// RunAt executes the snapshot management logic as if the current time were now
+// This allows replaying runs against a virtual clock (e.g., in simulate mode)
+func (o *Operator) RunAt(now time.Time) error {
+	// Name and bucket snapshots in the configured time zone
+	now = now.In(o.config.Location())
+
+	// Reset counters
+	o.deletionCount = 0
+	o.creationCount = 0
+	o.deletionFailures = 0
+	o.creationFailures = 0
+	o.retainedCount = 0
+	o.plannedDeletions = nil
+	o.metrics.BeginRun()
+
+	err := o.run(now)
+	// A dry-run only counts planned actions, which must not show up as created or deleted snapshots
+	created, deleted := o.creationCount, o.deletionCount
+	if o.config.DryRun {
+		created, deleted = 0, 0
+	}
+	o.metrics.RecordRun(now, err == nil, created, deleted, o.creationFailures, o.deletionFailures)
+	return err
+}
+
+// run executes a single run of the snapshot management logic
+func (o *Operator) run(now time.Time) error {
 	// Acquire lock to prevent concurrent runs (if enabled)
 	if o.config.EnableLocking {
 		if err := o.acquireLock(); err != nil {
@@ -38,31 +113,45 @@ func (o *Operator) Run() error {
 		defer o.releaseLock()
 	}
 
-	// Reset counters
-	o.deletionCount = 0
-	o.creationCount = 0
-
-	now := time.Now()
-
 	o.logConfig(now)
 
 	// Get and log ZFS version information
-	userland, kernel, err := o.manager.GetVersion()
+	userland, kernel, err := o.backend.GetVersion()
 	if err != nil {
 		return fmt.Errorf("failed to get ZFS version: %w", err)
 	}
 	klog.Infof("ZFS Version - Userland: %s, Kernel: %s", userland, kernel)
 
-	// Get pool health status first
-	poolStatus, err := o.manager.GetPoolStatus()
+	pools, err := o.backend.GetPools()
+	if err != nil {
+		return fmt.Errorf("failed to get pools: %w", err)
+	}
+
+	// Get pool health status before processing anything
+	poolStatus, err := zfs.GetPoolStatus(o.backend, pools)
 	if err != nil {
 		return fmt.Errorf("failed to get pool status: %w", err)
 	}
 
-	pools, err := o.manager.GetPools()
+	// Record pool health metrics before processing, so unhealthy pools are reported too
+	for poolName, status := range poolStatus {
+		if o.config.IsPoolAllowed(poolName) {
+			o.metrics.SetPoolStatus(poolName, status)
+		}
+	}
+
+	// List all snapshots once; the inventory is updated in memory after every create and delete
+	o.inventory, err = zfs.LoadInventory(o.backend)
 	if err != nil {
-		return fmt.Errorf("failed to get pools: %w", err)
+		return err
 	}
+	klog.V(1).Infof("Loaded %d snapshot(s)", o.inventory.Len())
+	logTimeMismatches(o.inventory)
+	logToolSnapshots(o.inventory)
+	o.loadHolds(pools, poolStatus, now)
+
+	// Snapshot the atomic roots as a whole before processing the datasets one by one
+	o.createAtomicSnapshots(pools, poolStatus, now)
 
 	// Track errors during processing
 	var errors []error
@@ -73,11 +162,33 @@ func (o *Operator) Run() error {
 		}
 	}
 
+	// Prune beyond retention where pools or datasets are running out of space
+	o.pruneForSpace(pools, poolStatus)
+
+	if o.config.DryRun {
+		o.logReclaimEstimate()
+	}
+
+	// Verify that no tier has fallen behind (skipped in dry-run mode, where nothing is created)
+	if o.config.CheckFreshness {
+		if o.config.DryRun {
+			klog.Infof("[DRY-RUN] Skipping freshness check")
+		} else {
+			if report := o.checkFreshness(pools, o.inventory, now); !report.OK {
+				logFreshnessReport(report)
+				errors = append(errors, fmt.Errorf("freshness check found %d violation(s)", len(report.Violations)))
+			}
+		}
+	}
+
 	// Return error if any pools had issues
 	if len(errors) > 0 {
 		return fmt.Errorf("operator encountered %d error(s) during execution", len(errors))
 	}
 
+	if o.retainedCount > 0 {
+		klog.Infof("%d snapshot(s) selected for deletion are retained by a hold or clone", o.retainedCount)
+	}
 	klog.Infof("Run completed successfully - created %d snapshot(s), deleted %d snapshot(s)", o.creationCount, o.deletionCount)
 	return nil
 }
@@ -126,17 +237,53 @@ func (o *Operator) logConfig(now time.Time) {
 	klog.Infof("Max weekly snapshots: %d", o.config.MaxWeeklySnapshots)
 	klog.Infof("Max monthly snapshots: %d", o.config.MaxMonthlySnapshots)
 	klog.Infof("Max yearly snapshots: %d", o.config.MaxYearlySnapshots)
+	klog.Infof("Retention mode: %s", o.config.RetentionMode)
+	if len(o.config.SkipUnchanged) > 0 {
+		klog.Infof("Skip unchanged datasets: %v", o.config.SkipUnchanged)
+	}
+	if o.config.SpacePressureEnabled() {
+		klog.Infof("Space-pressure pruning: above %d%% usage, down to %d%%", o.config.SpacePressureThreshold, o.config.SpacePressureTarget)
+	}
+	for _, frequency := range o.config.TierNames() {
+		if mode := o.config.GetRetentionMode(frequency); mode != o.config.RetentionMode {
+			klog.Infof("Retention mode of %s: %s", frequency, mode)
+		}
+	}
+	for _, tier := range o.config.CustomTiers {
+		klog.Infof("Custom tier %s: every %d %s(s), max %d snapshots", tier.Name, tier.Every, tier.Unit, tier.Retention)
+	}
 	if len(o.config.PoolWhitelist) > 0 {
 		klog.Infof("Pool whitelist: %v", o.config.PoolWhitelist)
 	} else {
 		klog.Infof("Pool whitelist: all pools")
 	}
+	if len(o.config.PoolBlacklist) > 0 {
+		klog.Infof("Pool blacklist: %v", o.config.PoolBlacklist)
+	}
 	if len(o.config.FilesystemWhitelist) > 0 {
 		klog.Infof("Filesystem whitelist: %v", o.config.FilesystemWhitelist)
 	} else {
 		klog.Infof("Filesystem whitelist: all filesystems")
 	}
+	if len(o.config.FilesystemBlacklist) > 0 {
+		klog.Infof("Filesystem blacklist: %v", o.config.FilesystemBlacklist)
+	}
 	klog.Infof("Snapshot prefix: %s", o.config.SnapshotPrefix)
+	klog.Infof("Snapshot name template: %s", o.config.SnapshotNameTemplate)
+	if len(o.config.AtomicSnapshotRoots) > 0 {
+		klog.Infof("Atomic snapshot roots: %v", o.config.AtomicSnapshotRoots)
+	}
+	if o.config.DeferDestroy {
+		klog.Infof("Deferred destroy of held or cloned snapshots: enabled")
+	}
+	if o.config.HoldMaxAge > 0 {
+		klog.Infof("Operator holds (%s:*) expire after %s", o.config.HoldTagPrefix, o.config.HoldMaxAge)
+	}
+	klog.Infof("Honor user properties: %t", o.config.HonorUserProperties)
+	if o.config.PolicyFilePath != "" {
+		klog.Infof("Policy file: %s (%d pool, %d dataset, %d selector policies)", o.config.PolicyFilePath,
+			len(o.config.PoolPolicies), len(o.config.DatasetPolicies), len(o.config.SelectorPolicies))
+	}
 	klog.Infof("Max hourly snapshot age: %s", o.config.GetMaxSnapshotDate("hourly", now).Format("2006-01-02 15:04:05"))
 	klog.Infof("Max daily snapshot age: %s", o.config.GetMaxSnapshotDate("daily", now).Format("2006-01-02 15:04:05"))
 	klog.Infof("Max weekly snapshot age: %s", o.config.GetMaxSnapshotDate("weekly", now).Format("2006-01-02 15:04:05"))
@@ -150,14 +297,14 @@ func (o *Operator) logConfig(now time.Time) {
 }
 
 func (o *Operator) processPool(pool *models.Pool, now time.Time, poolStatus map[string]*models.PoolStatus) error {
-	// Check if pool is in whitelist
-	if !o.config.IsPoolAllowed(pool.PoolName) {
-		klog.Infof("Skipping pool %s (not in whitelist)", pool.PoolName)
+	// Check if pool passes the whitelist and blacklist
+	if allowed, reason := o.config.FilterPool(pool.PoolName); !allowed {
+		klog.Infof("Skipping pool %s (%s)", pool.PoolName, reason)
 		return nil
 	}
 
 	// Check pool health before any operations (only log once per unique pool)
-	if !o.manager.IsPoolHealthy(pool.PoolName, poolStatus) {
+	if !zfs.IsPoolHealthy(pool.PoolName, poolStatus) {
 		klog.Infof("Skipping pool %s due to health issues", pool.PoolName)
 		return fmt.Errorf("pool %s is not healthy", pool.PoolName)
 	}
@@ -176,9 +323,19 @@ func (o *Operator) processPool(pool *models.Pool, now time.Time, poolStatus map[
 		return nil
 	}
 
-	// Check if filesystem is in whitelist
-	if !o.config.IsFilesystemAllowed(pool.FilesystemName) {
-		klog.Infof("Skipping filesystem %s (not in whitelist)", pool.FilesystemName)
+	// Check if filesystem passes the whitelist and blacklist
+	if allowed, reason := o.config.FilterFilesystem(pool.FilesystemName); !allowed {
+		klog.Infof("Skipping filesystem %s (%s)", pool.FilesystemName, reason)
+		return nil
+	}
+
+	for _, err := range o.config.ValidateUserProperties(pool) {
+		klog.Warningf(" Ignoring user property of %s: %v", pool.FilesystemName, err)
+	}
+
+	// Check if filesystem is disabled by the policy file or com.sun:auto-snapshot (inherited from parent datasets)
+	if enabled, source := o.config.ResolvePoolEnabled(pool); !enabled {
+		klog.Infof("Skipping filesystem %s (disabled by %s)", pool.FilesystemName, source)
 		return nil
 	}
 
@@ -187,14 +344,14 @@ func (o *Operator) processPool(pool *models.Pool, now time.Time, poolStatus map[
 	// Log filesystem usage
 	o.logFilesystemUsage(pool)
 
-	for _, frequency := range config.Frequencies() {
+	for _, frequency := range o.config.TierNames() {
 		if err := o.processFrequency(pool, frequency, now); err != nil {
 			klog.Infof("Error processing frequency %s: %v", frequency, err)
 		}
 	}
 
 	// Log snapshot summary for this filesystem
-	o.logSnapshotSummary(pool)
+	o.logSnapshotSummary(pool, now)
 
 	klog.Infof("Finished filesystem %s", pool.FilesystemName)
 
@@ -283,41 +440,37 @@ func (o *Operator) processFrequency(pool *models.Pool, frequency string, now tim
 	klog.Infof("Processing frequency %s", frequency)
 
 	// Get retention configuration for this frequency
-	maxCount := o.config.GetMaxSnapshotsForFrequency(frequency, pool.FilesystemName)
+	maxCount := o.config.GetPoolMaxSnapshots(frequency, pool)
 
 	// If maxCount is 0, skip this frequency entirely (no snapshots created or kept)
 	if maxCount == 0 {
 		klog.V(1).Infof("Skipping frequency %s (max count is 0)", frequency)
 
-		// Still delete any existing snapshots for this frequency to clean up
-		snapshots, err := o.manager.GetSnapshots(pool.PoolName, pool.FilesystemName, frequency)
-		if err != nil {
-			return fmt.Errorf("failed to get snapshots: %w", err)
+		// Still delete any existing snapshots for this frequency to clean up, down to the min_keep floor
+		snapshots := o.inventory.Get(pool.FilesystemName, frequency)
+		sort.Slice(snapshots, func(i, j int) bool {
+			return snapshots[i].DateTime.After(snapshots[j].DateTime)
+		})
+		snapshotsToKeep, snapshotsToDelete := applyMinKeep(nil, snapshots, o.config.GetMinKeep(pool.FilesystemName))
+		for _, snapshot := range snapshotsToKeep {
+			klog.Infof("Keeping snapshot %s (min_keep)", snapshot.SnapshotName)
 		}
-
-		for _, snapshot := range snapshots {
-			if o.config.DryRun {
-				klog.Infof("[DRY-RUN] Would delete snapshot %s (frequency disabled)", snapshot.SnapshotName)
-			} else {
-				if err := o.manager.DeleteSnapshot(snapshot); err != nil {
-					klog.Infof("Failed to delete snapshot %s: %v", snapshot.SnapshotName, err)
-				} else {
-					o.deletionCount++
-				}
-			}
+		pinned, snapshotsToDelete := o.applyPins(nil, snapshotsToDelete)
+		for _, snapshot := range pinned {
+			klog.Infof("Keeping snapshot %s (pinned by hold %s)", snapshot.SnapshotName, o.pinnedBy(snapshot))
 		}
+
+		o.deleteSnapshots(snapshotsToDelete, "frequency disabled")
 		return nil
 	}
 
-	snapshots, err := o.manager.GetSnapshots(pool.PoolName, pool.FilesystemName, frequency)
-	if err != nil {
-		return fmt.Errorf("failed to get snapshots: %w", err)
-	}
+	snapshots := o.inventory.Get(pool.FilesystemName, frequency)
 
-	retentionCutoff := o.config.GetMaxSnapshotDate(frequency, now, pool.FilesystemName)
+	retentionCutoff := o.config.RetentionCutoff(frequency, now, maxCount)
+	retentionMode := o.config.GetRetentionMode(frequency)
 
-	klog.V(1).Infof(" Found %d %s snapshot(s), retention window: %d periods, cutoff: %s",
-		len(snapshots), frequency, maxCount, retentionCutoff.Format("2006-01-02 15:04:05"))
+	klog.V(1).Infof(" Found %d %s snapshot(s), retention mode: %s, retention window: %d periods, cutoff: %s",
+		len(snapshots), frequency, retentionMode, maxCount, retentionCutoff.Format("2006-01-02 15:04:05"))
 
 	// Sort snapshots by date (newest first)
 	sort.Slice(snapshots, func(i, j int) bool {
@@ -325,21 +478,41 @@ func (o *Operator) processFrequency(pool *models.Pool, frequency string, now tim
 	})
 
 	// Group snapshots by time period and keep only the newest in each period
+	tier, _ := o.config.Tier(frequency)
 	periodMap := make(map[string]*models.Snapshot)
 	for _, snapshot := range snapshots {
-		periodKey := zfs.GetTimePeriodKey(snapshot.DateTime, frequency)
+		periodKey := tier.PeriodKey(snapshot.DateTime.In(now.Location()))
 		// Keep the newest snapshot in each period (since we're iterating newest-first)
 		if _, exists := periodMap[periodKey]; !exists {
 			periodMap[periodKey] = snapshot
 		}
 	}
 
+	// Check if we need to create a new snapshot - do this BEFORE deleting anything
+	// This ensures we never reduce protection before increasing it
+	snapshotRecent := findRecentSnapshot(snapshots, tier, now)
+
+	// If nothing was written since the newest snapshot of the tier, it covers the current period as well
+	var snapshotCovering *models.Snapshot
+	if snapshotRecent == nil && len(snapshots) > 0 && o.config.IsSkipUnchanged(frequency) &&
+		isUnchangedSince(pool, o.inventory, snapshots[0]) {
+		snapshotCovering = snapshots[0]
+		snapshotRecent = snapshotCovering
+	}
+
+	// In count mode the snapshot created in this run takes one of the N slots
+	countLimit := maxCount
+	if snapshotRecent == nil {
+		countLimit--
+	}
+
 	// Determine which snapshots to keep and which to delete
 	var snapshotsToDelete []*models.Snapshot
 	var snapshotsToKeep []*models.Snapshot
 
+	keepers := 0
 	for _, snapshot := range snapshots {
-		periodKey := zfs.GetTimePeriodKey(snapshot.DateTime, frequency)
+		periodKey := tier.PeriodKey(snapshot.DateTime.In(now.Location()))
 
 		// Check if this snapshot is the keeper for its period
 		isKeeperForPeriod := periodMap[periodKey] == snapshot
@@ -347,51 +520,64 @@ func (o *Operator) processFrequency(pool *models.Pool, frequency string, now tim
 		// Check if snapshot is within retention window
 		isWithinRetention := snapshot.DateTime.After(retentionCutoff) || snapshot.DateTime.Equal(retentionCutoff)
 
-		if isKeeperForPeriod && isWithinRetention {
+		// Check if snapshot is one of the newest period keepers (snapshots are sorted newest first)
+		isWithinCount := isKeeperForPeriod && keepers < countLimit
+		if isKeeperForPeriod {
+			keepers++
+		}
+
+		// The covering snapshot stands in for the current period and is kept however old it is
+		if snapshot == snapshotCovering || (isKeeperForPeriod && isRetained(retentionMode, isWithinRetention, isWithinCount)) {
 			snapshotsToKeep = append(snapshotsToKeep, snapshot)
 		} else {
 			snapshotsToDelete = append(snapshotsToDelete, snapshot)
 		}
 	}
 
-	// Check if we need to create a new snapshot - do this BEFORE deleting anything
-	// This ensures we never reduce protection before increasing it
-	var snapshotRecent *models.Snapshot
-	for _, snapshot := range snapshots {
-		if o.manager.IsSnapshotRecent(snapshot, frequency, now) {
-			if snapshotRecent == nil || snapshotRecent.DateTime.Before(snapshot.DateTime) {
-				snapshotRecent = snapshot
-			}
-		}
+	// Snapshots pinned by an operator-owned hold are kept whatever retention decided
+	snapshotsToKeep, snapshotsToDelete = o.applyPins(snapshotsToKeep, snapshotsToDelete)
+
+	// Never go below the min_keep floor; a snapshot created in this run counts towards it
+	minKeep := o.config.GetMinKeep(pool.FilesystemName)
+	if snapshotRecent == nil {
+		minKeep--
 	}
+	snapshotsToKeep, snapshotsToDelete = applyMinKeep(snapshotsToKeep, snapshotsToDelete, minKeep)
 
 	// Create new snapshot first if needed (before any deletions)
 	// This is safer: if snapshot creation fails due to disk issues, we still have old snapshots
-	if snapshotRecent != nil {
+	if snapshotCovering != nil {
+		klog.Infof("Skipping %s snapshot, nothing was written since %s", frequency, snapshotCovering.SnapshotName)
+	} else if snapshotRecent != nil {
 		klog.Infof("Found recent snapshot %s", snapshotRecent.SnapshotName)
+	} else if root, ok := o.config.AtomicRoot(pool.FilesystemName); ok {
+		// The snapshot is created together with the rest of the tree in createAtomicSnapshots
+		if err := o.atomicErrors[atomicKey(root, frequency)]; err != nil {
+			return fmt.Errorf("failed to create atomic snapshot of %s: %w", root, err)
+		}
+		if !o.config.DryRun {
+			return fmt.Errorf("no atomic snapshot of %s was created", root)
+		}
 	} else {
 		klog.Infof("Did not find any recent snapshot for frequency %s", frequency)
 
-		formattedTime := now.Format("2006-01-02_15:04:05")
-		snapshotName := fmt.Sprintf("%s_%s_%s", o.config.SnapshotPrefix, formattedTime, frequency)
-
-		newSnapshot := &models.Snapshot{
-			PoolName:       pool.PoolName,
-			FilesystemName: pool.FilesystemName,
-			SnapshotName:   snapshotName,
-			DateTime:       now,
-			Frequency:      frequency,
+		newSnapshot, err := o.newSnapshot(pool, frequency, now)
+		if err != nil {
+			return err
 		}
+		snapshotName := newSnapshot.SnapshotName
 
 		if o.config.DryRun {
 			klog.Infof("[DRY-RUN] Would create snapshot %s", snapshotName)
 			o.creationCount++
 		} else {
-			if err := o.manager.CreateSnapshot(newSnapshot); err != nil {
+			if err := o.backend.CreateSnapshot(newSnapshot); err != nil {
 				// If snapshot creation fails, don't delete anything - keep old snapshots for safety
+				o.creationFailures++
 				return fmt.Errorf("failed to create snapshot: %w", err)
 			} else {
 				o.creationCount++
+				o.inventory.Add(newSnapshot)
 				klog.Infof("Successfully created snapshot %s", snapshotName)
 			}
 		}
@@ -399,50 +585,213 @@ func (o *Operator) processFrequency(pool *models.Pool, frequency string, now tim
 
 	// Log kept snapshots
 	for _, snapshot := range snapshotsToKeep {
-		klog.Infof("Keeping snapshot %s", snapshot.SnapshotName)
+		if tag := o.pinnedBy(snapshot); tag != "" {
+			klog.Infof("Keeping snapshot %s (pinned by hold %s)", snapshot.SnapshotName, tag)
+		} else {
+			klog.Infof("Keeping snapshot %s", snapshot.SnapshotName)
+		}
 	}
 
 	// Now that we've successfully created a new snapshot (if needed), process deletions
-	for _, snapshot := range snapshotsToDelete {
+	o.deleteSnapshots(snapshotsToDelete, "retention")
+
+	return nil
+}
+
+// applyMinKeep moves the newest snapshots from the delete list to the keep list until at least
+// minKeep snapshots are kept; both lists are expected newest first
+func applyMinKeep(keep, del []*models.Snapshot, minKeep int) ([]*models.Snapshot, []*models.Snapshot) {
+	missing := minKeep - len(keep)
+	if missing <= 0 {
+		return keep, del
+	}
+	if missing > len(del) {
+		missing = len(del)
+	}
+	keep = append(keep, del[:missing]...)
+	return keep, del[missing:]
+}
+
+// deleteSnapshots destroys snapshots selected by retention, honoring the deletion limit and dry-run mode
+// and returns the number of snapshots deleted (or that would be deleted in dry-run mode)
+// This is the only place the operator deletes snapshots. The newest managed snapshot of a dataset is
+// never deleted, whatever retention decided, so a clock jump or misconfiguration cannot wipe a dataset
+func (o *Operator) deleteSnapshots(snapshots []*models.Snapshot, reason string) int {
+	deleted := 0
+	for _, snapshot := range snapshots {
+		if newest := o.inventory.Newest(snapshot.FilesystemName); newest != nil && newest.SnapshotName == snapshot.SnapshotName {
+			klog.Warningf(" Not deleting snapshot %s, it is the newest snapshot of %s", snapshot.SnapshotName, snapshot.FilesystemName)
+			continue
+		}
+
+		// Pinned snapshots are not even marked for deferred destruction
+		if tag := o.pinnedBy(snapshot); tag != "" {
+			klog.Infof("Not deleting snapshot %s (%s), pinned by hold %s", snapshot.SnapshotName, reason, tag)
+			continue
+		}
+
+		// Snapshots that are already marked are destroyed by ZFS once released
+		if snapshot.DeferDestroy {
+			klog.V(1).Infof("Not deleting snapshot %s, it is already marked for deferred destruction", snapshot.SnapshotName)
+			continue
+		}
+		retainer := retainedBy(snapshot)
+		if retainer != "" && !o.config.DeferDestroy {
+			klog.Infof("Not deleting snapshot %s (%s), retained by %s", snapshot.SnapshotName, reason, retainer)
+			o.retainedCount++
+			continue
+		}
+
 		// Check deletion limit
 		if o.deletionCount >= o.config.MaxDeletionsPerRun {
 			klog.Warningf(" Reached deletion limit of %d snapshots - skipping remaining deletions", o.config.MaxDeletionsPerRun)
-			break
+			return deleted
 		}
 
 		if o.config.DryRun {
-			klog.Infof("[DRY-RUN] Would delete snapshot %s", snapshot.SnapshotName)
+			klog.Infof("[DRY-RUN] Would delete snapshot %s (%s)", snapshot.SnapshotName, reason)
 			o.deletionCount++
+			o.plannedDeletions = append(o.plannedDeletions, snapshot)
+			deleted++
 		} else {
-			if err := o.manager.DeleteSnapshot(snapshot); err != nil {
-				klog.Infof("Failed to delete snapshot: %v", err)
+			if err := o.backend.DeleteSnapshot(snapshot); errors.Is(err, zfs.ErrSnapshotRetained) {
+				klog.Infof("Not deleting snapshot %s (%s), retained by hold/clone: %v", snapshot.SnapshotName, reason, err)
+				o.retainedCount++
+			} else if err != nil {
+				klog.Infof("Failed to delete snapshot %s: %v", snapshot.SnapshotName, err)
+				o.deletionFailures++
 			} else {
+				if retainer != "" {
+					klog.Infof("Marked snapshot %s for deferred destruction, retained by %s", snapshot.SnapshotName, retainer)
+				}
 				o.deletionCount++
+				deleted++
+				o.inventory.Remove(snapshot)
 			}
 		}
 	}
+	return deleted
+}
 
-	return nil
+// retainedBy describes what keeps ZFS from destroying a snapshot: "hold", "clone", "hold/clone" or empty
+func retainedBy(snapshot *models.Snapshot) string {
+	switch {
+	case snapshot.UserRefs > 0 && len(snapshot.Clones) > 0:
+		return "hold/clone"
+	case snapshot.UserRefs > 0:
+		return "hold"
+	case len(snapshot.Clones) > 0:
+		return "clone"
+	}
+	return ""
 }
 
-func (o *Operator) logSnapshotSummary(pool *models.Pool) {
-	klog.Infof("Snapshot summary for %s:", pool.FilesystemName)
+// isRetained decides with the retention mode of a tier whether a period keeper is kept
+func isRetained(mode string, withinWindow, withinCount bool) bool {
+	switch mode {
+	case config.RetentionModeCount:
+		return withinCount
+	case config.RetentionModeBoth:
+		return withinWindow || withinCount
+	default:
+		return withinWindow
+	}
+}
 
-	for _, frequency := range config.Frequencies() {
-		snapshots, err := o.manager.GetSnapshots(pool.PoolName, pool.FilesystemName, frequency)
-		if err != nil {
-			klog.Infof("  Error getting %s snapshots: %v", frequency, err)
+// findRecentSnapshot returns the newest snapshot from the current period of the tier, or nil
+func findRecentSnapshot(snapshots []*models.Snapshot, tier config.Tier, now time.Time) *models.Snapshot {
+	var snapshotRecent *models.Snapshot
+	for _, snapshot := range snapshots {
+		if zfs.IsSnapshotInPeriod(snapshot, tier, now) {
+			if snapshotRecent == nil || snapshotRecent.DateTime.Before(snapshot.DateTime) {
+				snapshotRecent = snapshot
+			}
+		}
+	}
+	return snapshotRecent
+}
+
+// newSnapshot returns the snapshot to create for a dataset and frequency at the given time
+// Its written property is what the dataset reports as written since its newest snapshot, so
+// a snapshot of an idle dataset is not taken for a change by a higher tier later in the run
+func (o *Operator) newSnapshot(pool *models.Pool, frequency string, now time.Time) (*models.Snapshot, error) {
+	template, err := o.config.NameTemplate()
+	if err != nil {
+		return nil, err
+	}
+
+	now = now.In(o.config.Location())
+	return &models.Snapshot{
+		PoolName:       pool.PoolName,
+		FilesystemName: pool.FilesystemName,
+		SnapshotName:   template.Format(frequency, now),
+		DateTime:       now,
+		Frequency:      frequency,
+		NameTime:       now.Truncate(time.Second),
+		CreationTime:   now,
+		Written:        0,
+		WrittenKnown:   isZeroSize(pool.Written),
+	}, nil
+}
+
+// logToolSnapshots reports the snapshots of third-party tools, so migrations do not leave them behind unnoticed
+func logToolSnapshots(inventory *zfs.Inventory) {
+	adopted := make(map[string]int)
+	ignored := make(map[string]int)
+	for _, snapshot := range inventory.All() {
+		if snapshot.Tool == "" {
 			continue
 		}
+		if snapshot.Frequency != "" {
+			adopted[snapshot.Tool]++
+		} else {
+			ignored[snapshot.Tool]++
+		}
+	}
+
+	for _, tool := range naming.ToolNames() {
+		if adopted[tool] > 0 {
+			klog.Infof("Managing %d snapshot(s) adopted from %s", adopted[tool], tool)
+		}
+		if ignored[tool] > 0 {
+			klog.Infof("Ignoring %d snapshot(s) created by %s (set ADOPT_SNAPSHOTS=%s to prune them with the retention policy)",
+				ignored[tool], tool, tool)
+		}
+	}
+}
 
+// logTimeMismatches reports snapshots whose name timestamp does not match their creation property
+func logTimeMismatches(inventory *zfs.Inventory) {
+	mismatches := zfs.TimeMismatches(inventory.All())
+	if len(mismatches) == 0 {
+		return
+	}
+	klog.Warningf(" %d snapshot(s) have a name timestamp that differs from their creation time by more than %s",
+		len(mismatches), zfs.TimeMismatchTolerance)
+	for _, snapshot := range mismatches {
+		klog.V(1).Infof("  %s@%s: name %s, creation %s", snapshot.FilesystemName, snapshot.SnapshotName,
+			snapshot.NameTime.Format(time.RFC3339), snapshot.CreationTime.Format(time.RFC3339))
+	}
+}
+
+func (o *Operator) logSnapshotSummary(pool *models.Pool, now time.Time) {
+	klog.Infof("Snapshot summary for %s:", pool.FilesystemName)
+
+	for _, frequency := range o.config.TierNames() {
+		snapshots := o.inventory.Get(pool.FilesystemName, frequency)
 		if len(snapshots) == 0 {
 			klog.Infof("  %s: %d snapshot(s)", frequency, len(snapshots))
+			o.metrics.SetSnapshotStats(pool.FilesystemName, frequency, 0, time.Time{})
 			continue
 		}
 
 		// Find oldest and newest snapshots
 		var oldest, newest *models.Snapshot
+		pinned := 0
 		for _, snapshot := range snapshots {
+			if o.pinnedBy(snapshot) != "" {
+				pinned++
+			}
 			if oldest == nil || snapshot.DateTime.Before(oldest.DateTime) {
 				oldest = snapshot
 			}
@@ -451,10 +800,16 @@ func (o *Operator) logSnapshotSummary(pool *models.Pool) {
 			}
 		}
 
-		klog.Infof("  %s: %d snapshot(s) [oldest: %s, newest: %s]",
+		o.metrics.SetSnapshotStats(pool.FilesystemName, frequency, len(snapshots), newest.DateTime)
+
+		pinnedInfo := ""
+		if pinned > 0 {
+			pinnedInfo = fmt.Sprintf(", %d pinned by hold", pinned)
+		}
+		klog.Infof("  %s: %d snapshot(s) [oldest: %s, newest: %s]%s",
 			frequency, len(snapshots),
 			oldest.DateTime.Format("2006-01-02 15:04:05"),
-			newest.DateTime.Format("2006-01-02 15:04:05"))
+			newest.DateTime.Format("2006-01-02 15:04:05"), pinnedInfo)
 	}
 }
 
@@ -1,14 +1,18 @@
 package operator
 
 import (
+	"bytes"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/runningman84/zfs-snapshot-operator/pkg/config"
 	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/zfs"
 )
 
-// mockZFSManager is a mock implementation of zfs.Manager for testing
+// mockZFSManager is a mock implementation of zfs.Backend for testing
 type mockZFSManager struct {
 	snapshots         []*models.Snapshot
 	pools             []*models.Pool
@@ -18,9 +22,17 @@ type mockZFSManager struct {
 	getSnapshotsError error
 	getPoolsError     error
 	createdSnapshots  []*models.Snapshot
+	createdBatches    [][]*models.Snapshot
+	getSnapshotsCalls int
 	deletedSnapshots  []*models.Snapshot
+	holds             []*models.Hold
+	releasedHolds     []*models.Hold
+	reclaim           func([]*models.Snapshot) uint64 // Estimates the space of destroying snapshots, the default sums their used
 }
 
+// Ensure mockZFSManager satisfies the Backend interface
+var _ zfs.Backend = (*mockZFSManager)(nil)
+
 func (m *mockZFSManager) GetVersion() (string, string, error) {
 	return "zfs-2.3.3-1", "zfs-kmod-2.3.3-1", nil
 }
@@ -33,15 +45,23 @@ func (m *mockZFSManager) GetPools() ([]*models.Pool, error) {
 }
 
 func (m *mockZFSManager) GetSnapshots(poolName, filesystemName, frequency string) ([]*models.Snapshot, error) {
+	m.getSnapshotsCalls++
 	if m.getSnapshotsError != nil {
 		return nil, m.getSnapshotsError
 	}
 
 	var result []*models.Snapshot
 	for _, snap := range m.snapshots {
-		if snap.PoolName == poolName && snap.FilesystemName == filesystemName && snap.Frequency == frequency {
-			result = append(result, snap)
+		if poolName != "" && snap.PoolName != poolName {
+			continue
+		}
+		if filesystemName != "" && snap.FilesystemName != filesystemName {
+			continue
 		}
+		if frequency != "" && snap.Frequency != frequency {
+			continue
+		}
+		result = append(result, snap)
 	}
 	return result, nil
 }
@@ -55,61 +75,103 @@ func (m *mockZFSManager) CreateSnapshot(snapshot *models.Snapshot) error {
 	return nil
 }
 
+func (m *mockZFSManager) CreateSnapshots(snapshots []*models.Snapshot) error {
+	if m.createError != nil {
+		return m.createError
+	}
+	m.createdBatches = append(m.createdBatches, snapshots)
+	m.createdSnapshots = append(m.createdSnapshots, snapshots...)
+	m.snapshots = append(m.snapshots, snapshots...)
+	return nil
+}
+
 func (m *mockZFSManager) DeleteSnapshot(snapshot *models.Snapshot) error {
 	if m.deleteError != nil {
 		return m.deleteError
 	}
 	m.deletedSnapshots = append(m.deletedSnapshots, snapshot)
+	for i, snap := range m.snapshots {
+		if snap == snapshot {
+			m.snapshots = append(m.snapshots[:i], m.snapshots[i+1:]...)
+			break
+		}
+	}
 	return nil
 }
 
-func (m *mockZFSManager) IsSnapshotRecent(snapshot *models.Snapshot, frequency string, now time.Time) bool {
-	if snapshot.Frequency != frequency {
-		return false
+func (m *mockZFSManager) EstimateReclaim(snapshots []*models.Snapshot) (uint64, error) {
+	if m.reclaim != nil {
+		return m.reclaim(snapshots), nil
 	}
-
-	minDate := now.Add(-1 * time.Hour)
-	switch frequency {
-	case "daily":
-		minDate = now.Add(-24 * time.Hour)
-	case "weekly":
-		minDate = now.Add(-7 * 24 * time.Hour)
-	case "monthly":
-		minDate = now.Add(-30 * 24 * time.Hour)
-	case "yearly":
-		minDate = now.Add(-365 * 24 * time.Hour)
+	var reclaim uint64
+	for _, snapshot := range snapshots {
+		reclaim += snapshot.Used
 	}
+	return reclaim, nil
+}
+
+func (m *mockZFSManager) HoldSnapshot(snapshot *models.Snapshot, tag string) error {
+	m.holds = append(m.holds, &models.Hold{
+		FilesystemName: snapshot.FilesystemName,
+		SnapshotName:   snapshot.SnapshotName,
+		Tag:            tag,
+	})
+	snapshot.UserRefs++
+	return nil
+}
 
-	return snapshot.DateTime.After(minDate) || snapshot.DateTime.Equal(minDate)
+func (m *mockZFSManager) ReleaseSnapshot(snapshot *models.Snapshot, tag string) error {
+	for i, hold := range m.holds {
+		if hold.FilesystemName == snapshot.FilesystemName && hold.SnapshotName == snapshot.SnapshotName && hold.Tag == tag {
+			m.releasedHolds = append(m.releasedHolds, hold)
+			m.holds = append(m.holds[:i], m.holds[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("no hold %s on %s@%s", tag, snapshot.FilesystemName, snapshot.SnapshotName)
 }
 
-func (m *mockZFSManager) CanSnapshotBeDeleted(snapshot *models.Snapshot, frequency string, now time.Time) bool {
-	return !m.IsSnapshotRecent(snapshot, frequency, now)
+func (m *mockZFSManager) GetHolds(snapshots []*models.Snapshot) ([]*models.Hold, error) {
+	var holds []*models.Hold
+	for _, snapshot := range snapshots {
+		for _, hold := range m.holds {
+			if hold.FilesystemName == snapshot.FilesystemName && hold.SnapshotName == snapshot.SnapshotName {
+				holds = append(holds, hold)
+			}
+		}
+	}
+	return holds, nil
 }
 
-func (m *mockZFSManager) GetPoolStatus(poolName string) (map[string]*models.PoolStatus, error) {
+func (m *mockZFSManager) GetPoolStatus() (map[string]*models.PoolStatus, error) {
 	return m.poolStatus, nil
 }
 
-func (m *mockZFSManager) IsPoolHealthy(poolName string, poolStatus map[string]*models.PoolStatus) bool {
-	status, exists := poolStatus[poolName]
-	if !exists {
-		return true
-	}
-	if status.State != "ONLINE" {
-		return false
-	}
-	// Check error counts
-	if status.ReadErrors != "" && status.ReadErrors != "0" {
-		return false
-	}
-	if status.WriteErrors != "" && status.WriteErrors != "0" {
-		return false
+// newMockOperator creates an operator wired to the given mock backend
+func newMockOperator(cfg *config.Config, mock *mockZFSManager) *Operator {
+	cfg.EnableLocking = false
+	return NewOperatorWithBackend(cfg, mock)
+}
+
+// loadInventory loads the snapshot inventory for tests that call processFrequency directly
+func loadInventory(t *testing.T, op *Operator) {
+	t.Helper()
+	inventory, err := zfs.LoadInventory(op.backend)
+	if err != nil {
+		t.Fatalf("LoadInventory() error = %v", err)
 	}
-	if status.ChecksumErrors != "" && status.ChecksumErrors != "0" {
-		return false
+	op.inventory = inventory
+}
+
+func yearlySnapshot(year int) *models.Snapshot {
+	dateTime := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	return &models.Snapshot{
+		PoolName:       "tank",
+		FilesystemName: "tank/data",
+		SnapshotName:   "autosnap_" + dateTime.Format("2006-01-02_15:04:05") + "_yearly",
+		DateTime:       dateTime,
+		Frequency:      "yearly",
 	}
-	return true
 }
 
 // TestProcessFrequencyCreateFirst tests that snapshots are created before deletions
@@ -117,10 +179,27 @@ func TestProcessFrequencyCreateFirst(t *testing.T) {
 	cfg := config.NewConfig("test")
 	cfg.MaxYearlySnapshots = 2
 
-	// This test documents the expected create-first behavior
-	t.Log("processFrequency should create new snapshot before deleting old ones")
-	t.Log("If creation fails, no deletions should occur")
-	t.Log("This requires dependency injection to test with mock manager")
+	mock := &mockZFSManager{
+		snapshots: []*models.Snapshot{yearlySnapshot(2020), yearlySnapshot(2021)},
+	}
+	op := newMockOperator(cfg, mock)
+	loadInventory(t, op)
+	pool := &models.Pool{PoolName: "tank", FilesystemName: "tank/data"}
+	now := time.Date(2026, 1, 25, 12, 0, 0, 0, time.UTC)
+
+	if err := op.processFrequency(pool, "yearly", now); err != nil {
+		t.Fatalf("processFrequency() error = %v", err)
+	}
+
+	if len(mock.createdSnapshots) != 1 {
+		t.Fatalf("Created %d snapshot(s), want 1", len(mock.createdSnapshots))
+	}
+	if len(mock.deletedSnapshots) != 2 {
+		t.Errorf("Deleted %d snapshot(s), want 2", len(mock.deletedSnapshots))
+	}
+	if op.creationCount != 1 || op.deletionCount != 2 {
+		t.Errorf("Counters = created %d, deleted %d, want 1 and 2", op.creationCount, op.deletionCount)
+	}
 }
 
 // TestProcessFrequencyWithCreateError tests that deletions are skipped when creation fails
@@ -128,22 +207,118 @@ func TestProcessFrequencyWithCreateError(t *testing.T) {
 	cfg := config.NewConfig("test")
 	cfg.MaxYearlySnapshots = 2
 
-	// This test documents that when CreateSnapshot fails, no deletions should happen
-	// The create-first logic ensures backup protection is never reduced
-	t.Log("When CreateSnapshot returns error, processFrequency should:")
-	t.Log("1. Not delete any existing snapshots")
-	t.Log("2. Return the error immediately")
-	t.Log("3. Preserve all old snapshots as safety measure")
+	mock := &mockZFSManager{
+		snapshots:   []*models.Snapshot{yearlySnapshot(2020), yearlySnapshot(2021)},
+		createError: fmt.Errorf("out of space"),
+	}
+	op := newMockOperator(cfg, mock)
+	loadInventory(t, op)
+	pool := &models.Pool{PoolName: "tank", FilesystemName: "tank/data"}
+	now := time.Date(2026, 1, 25, 12, 0, 0, 0, time.UTC)
+
+	if err := op.processFrequency(pool, "yearly", now); err == nil {
+		t.Fatal("processFrequency() should return error when snapshot creation fails")
+	}
+
+	if len(mock.deletedSnapshots) != 0 {
+		t.Errorf("Deleted %d snapshot(s) after failed creation, want 0", len(mock.deletedSnapshots))
+	}
+	if len(mock.snapshots) != 2 {
+		t.Errorf("Remaining snapshots = %d, want 2", len(mock.snapshots))
+	}
 }
 
 // TestProcessFrequencyDeduplication tests that only newest snapshot per period is kept
 func TestProcessFrequencyDeduplication(t *testing.T) {
-	// Setup: Multiple yearly snapshots in 2024
-	// Expected: Only the newest 2024 snapshot should be kept
-	t.Log("When multiple snapshots exist in same period (e.g., multiple 2024 snapshots):")
-	t.Log("1. Group snapshots by getTimePeriodKey")
-	t.Log("2. Keep only the newest snapshot per period")
-	t.Log("3. Delete all other snapshots in that period")
+	cfg := config.NewConfig("test")
+	cfg.MaxYearlySnapshots = 3
+
+	older := yearlySnapshot(2025)
+	newer := yearlySnapshot(2025)
+	newer.DateTime = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	newer.SnapshotName = "autosnap_2025-06-01_00:00:00_yearly"
+	current := yearlySnapshot(2026)
+
+	mock := &mockZFSManager{
+		snapshots: []*models.Snapshot{older, newer, current},
+	}
+	op := newMockOperator(cfg, mock)
+	loadInventory(t, op)
+	pool := &models.Pool{PoolName: "tank", FilesystemName: "tank/data"}
+	now := time.Date(2026, 1, 25, 12, 0, 0, 0, time.UTC)
+
+	if err := op.processFrequency(pool, "yearly", now); err != nil {
+		t.Fatalf("processFrequency() error = %v", err)
+	}
+
+	if len(mock.createdSnapshots) != 0 {
+		t.Errorf("Created %d snapshot(s), want 0 (current period already covered)", len(mock.createdSnapshots))
+	}
+	if len(mock.deletedSnapshots) != 1 || mock.deletedSnapshots[0] != older {
+		t.Errorf("Deleted %v, want only the older 2025 snapshot", mock.deletedSnapshots)
+	}
+}
+
+// TestRunWithBackend tests a full run against an injected backend
+func TestRunWithBackend(t *testing.T) {
+	cfg := config.NewConfig("test")
+	mock := &mockZFSManager{
+		pools: []*models.Pool{
+			{PoolName: "tank"},
+			{PoolName: "tank", FilesystemName: "tank/data"},
+		},
+		poolStatus: map[string]*models.PoolStatus{
+			"tank": {Name: "tank", State: "ONLINE", ErrorCount: "0"},
+		},
+	}
+	op := newMockOperator(cfg, mock)
+
+	if err := op.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// Default config enables hourly, daily, weekly, monthly and yearly
+	if len(mock.createdSnapshots) != 5 {
+		t.Errorf("Created %d snapshot(s), want 5", len(mock.createdSnapshots))
+	}
+	for _, snapshot := range mock.createdSnapshots {
+		if snapshot.FilesystemName != "tank/data" {
+			t.Errorf("Snapshot created on %s, want tank/data", snapshot.FilesystemName)
+		}
+	}
+}
+
+// TestRunHonorsUserProperties tests that com.sun:auto-snapshot opts datasets and frequencies out
+func TestRunHonorsUserProperties(t *testing.T) {
+	cfg := config.NewConfig("test")
+	mock := &mockZFSManager{
+		pools: []*models.Pool{
+			{PoolName: "tank", FilesystemName: "tank/data", Properties: map[string]models.Property{
+				"com.sun:auto-snapshot:hourly": {Value: "false", Source: "tank/data"},
+			}},
+			{PoolName: "tank", FilesystemName: "tank/scratch", Properties: map[string]models.Property{
+				"com.sun:auto-snapshot": {Value: "false", Source: "tank"},
+			}},
+		},
+		poolStatus: map[string]*models.PoolStatus{
+			"tank": {Name: "tank", State: "ONLINE", ErrorCount: "0"},
+		},
+	}
+	op := newMockOperator(cfg, mock)
+
+	if err := op.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// daily, weekly, monthly and yearly on tank/data only
+	if len(mock.createdSnapshots) != 4 {
+		t.Errorf("Created %d snapshot(s), want 4", len(mock.createdSnapshots))
+	}
+	for _, snapshot := range mock.createdSnapshots {
+		if snapshot.FilesystemName != "tank/data" || snapshot.Frequency == "hourly" {
+			t.Errorf("Unexpected snapshot %s@%s", snapshot.FilesystemName, snapshot.SnapshotName)
+		}
+	}
 }
 
 // TestParseSize tests the parseSize helper function
@@ -447,11 +622,53 @@ func TestDeletionCounterAccuracy(t *testing.T) {
 
 // TestGetSnapshotsError tests error handling when GetSnapshots fails
 func TestGetSnapshotsError(t *testing.T) {
-	t.Log("When GetSnapshots returns error:")
-	t.Log("1. processFrequency should return error immediately")
-	t.Log("2. No snapshots should be created")
-	t.Log("3. No snapshots should be deleted")
-	t.Log("4. Error should propagate to caller")
+	cfg := config.NewConfig("test")
+	mock := &mockZFSManager{
+		pools: []*models.Pool{{PoolName: "tank", FilesystemName: "tank/data"}},
+		poolStatus: map[string]*models.PoolStatus{
+			"tank": {Name: "tank", State: "ONLINE", ErrorCount: "0"},
+		},
+		getSnapshotsError: fmt.Errorf("zfs list failed"),
+	}
+	op := newMockOperator(cfg, mock)
+
+	if err := op.Run(); err == nil {
+		t.Fatal("Run() should return an error when snapshots cannot be listed")
+	}
+	if len(mock.createdSnapshots) != 0 || len(mock.deletedSnapshots) != 0 {
+		t.Errorf("Created %d and deleted %d snapshot(s), want none", len(mock.createdSnapshots), len(mock.deletedSnapshots))
+	}
+}
+
+// TestRunListsSnapshotsOnce tests that a run lists the snapshots once and keeps the inventory up to date
+func TestRunListsSnapshotsOnce(t *testing.T) {
+	cfg := config.NewConfig("test")
+	cfg.CheckFreshness = true
+	mock := &mockZFSManager{
+		pools: []*models.Pool{
+			{PoolName: "tank", FilesystemName: "tank/data"},
+			{PoolName: "tank", FilesystemName: "tank/media"},
+		},
+		poolStatus: map[string]*models.PoolStatus{
+			"tank": {Name: "tank", State: "ONLINE", ErrorCount: "0"},
+		},
+		snapshots: []*models.Snapshot{yearlySnapshot(2000)},
+	}
+	op := newMockOperator(cfg, mock)
+
+	// The freshness phase only passes if the inventory saw the snapshots created during the run
+	if err := op.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if mock.getSnapshotsCalls != 1 {
+		t.Errorf("GetSnapshots() called %d times, want 1", mock.getSnapshotsCalls)
+	}
+	if len(mock.deletedSnapshots) != 1 {
+		t.Fatalf("Deleted %d snapshot(s), want 1", len(mock.deletedSnapshots))
+	}
+	if got := op.inventory.Get("tank/data", "yearly"); len(got) != 1 || got[0].DateTime.Year() == 2000 {
+		t.Errorf("Inventory has %v, want only the new yearly snapshot", got)
+	}
 }
 
 // TestRetentionWindowCalculation tests that retention cutoff dates are correct
@@ -469,25 +686,26 @@ func TestRetentionWindowCalculation(t *testing.T) {
 		frequency      string
 		expectedCutoff time.Time
 	}{
+		// The window starts at the oldest of the last N calendar periods, including the current one
 		{
 			frequency:      "hourly",
-			expectedCutoff: now.Add(-24 * time.Hour),
+			expectedCutoff: time.Date(2026, 1, 24, 13, 0, 0, 0, time.UTC),
 		},
 		{
 			frequency:      "daily",
-			expectedCutoff: now.Add(-7 * 24 * time.Hour),
+			expectedCutoff: time.Date(2026, 1, 19, 0, 0, 0, 0, time.UTC),
 		},
 		{
 			frequency:      "weekly",
-			expectedCutoff: now.Add(-4 * 7 * 24 * time.Hour),
+			expectedCutoff: time.Date(2025, 12, 29, 0, 0, 0, 0, time.UTC), // Monday of ISO week 2026-W01
 		},
 		{
 			frequency:      "monthly",
-			expectedCutoff: now.Add(-12 * 4 * 7 * 24 * time.Hour),
+			expectedCutoff: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
 		},
 		{
 			frequency:      "yearly",
-			expectedCutoff: now.Add(-3 * 52 * 7 * 24 * time.Hour),
+			expectedCutoff: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
 		},
 	}
 
@@ -513,3 +731,73 @@ func TestDryRunModeNoModifications(t *testing.T) {
 	t.Log("4. Counters should still increment")
 	t.Log("5. All logic should execute normally except actual ZFS commands")
 }
+
+// TestRunRecordsMetrics tests that a run populates the metrics collector
+func TestRunRecordsMetrics(t *testing.T) {
+	cfg := config.NewConfig("test")
+	cfg.PoolWhitelist = []string{"tank"}
+	mock := &mockZFSManager{
+		pools: []*models.Pool{
+			{PoolName: "tank", FilesystemName: "tank/data"},
+		},
+		poolStatus: map[string]*models.PoolStatus{
+			"tank":  {Name: "tank", State: "ONLINE", ErrorCount: "0"},
+			"other": {Name: "other", State: "ONLINE", ErrorCount: "0"},
+		},
+	}
+	op := newMockOperator(cfg, mock)
+
+	if err := op.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	op.Metrics().WriteTo(&buf)
+	output := buf.String()
+
+	expected := []string{
+		`zfs_snapshot_operator_snapshots{dataset="tank/data",frequency="hourly"} 1`,
+		`zfs_snapshot_operator_snapshots_created_total 5`,
+		`zfs_snapshot_operator_last_run_success 1`,
+		`zfs_snapshot_operator_pool_state{pool="tank",state="ONLINE"} 1`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(output, line) {
+			t.Errorf("Metrics missing %q\n%s", line, output)
+		}
+	}
+	if strings.Contains(output, `pool="other"`) {
+		t.Error("Metrics should not include pools outside the whitelist")
+	}
+}
+
+// TestDryRunMetrics tests that planned actions of a dry-run are not counted as created or deleted snapshots
+func TestDryRunMetrics(t *testing.T) {
+	cfg := config.NewConfig("test")
+	cfg.DryRun = true
+	mock := &mockZFSManager{
+		pools:      []*models.Pool{{PoolName: "tank", FilesystemName: "tank/data"}},
+		poolStatus: map[string]*models.PoolStatus{"tank": {Name: "tank", State: "ONLINE", ErrorCount: "0"}},
+	}
+	op := newMockOperator(cfg, mock)
+
+	if err := op.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if op.creationCount == 0 {
+		t.Fatal("The dry-run planned no snapshots")
+	}
+
+	var buf bytes.Buffer
+	op.Metrics().WriteTo(&buf)
+	output := buf.String()
+	for _, line := range []string{
+		"zfs_snapshot_operator_snapshots_created_total 0\n",
+		"zfs_snapshot_operator_snapshots_deleted_total 0\n",
+		"zfs_snapshot_operator_last_run_success 1\n",
+	} {
+		if !strings.Contains(output, line) {
+			t.Errorf("Metrics missing %q\n%s", line, output)
+		}
+	}
+}
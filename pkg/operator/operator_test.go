@@ -19,8 +19,8 @@ func TestNewOperator(t *testing.T) {
 		t.Error("Operator config not properly set")
 	}
 
-	if op.manager == nil {
-		t.Error("Operator manager not properly initialized")
+	if op.backend == nil {
+		t.Error("Operator backend not properly initialized")
 	}
 }
 
@@ -102,8 +102,8 @@ func TestLogSnapshotSummary(t *testing.T) {
 	if op.config == nil {
 		t.Error("Operator config should not be nil")
 	}
-	if op.manager == nil {
-		t.Error("Operator manager should not be nil")
+	if op.backend == nil {
+		t.Error("Operator backend should not be nil")
 	}
 }
 
@@ -295,8 +295,8 @@ func TestDryRunIntegration(t *testing.T) {
 		t.Fatal("Operator should not be nil")
 	}
 
-	if op.manager == nil {
-		t.Fatal("Manager should not be nil")
+	if op.backend == nil {
+		t.Fatal("Backend should not be nil")
 	}
 
 	// The key behavior to test: In the processFrequency method,
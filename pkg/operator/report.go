@@ -0,0 +1,166 @@
+package operator
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/zfs"
+	"k8s.io/klog/v2"
+)
+
+// otherTier is the report label of snapshots that belong to no tier (manual or ignored tool snapshots)
+const otherTier = "other"
+
+// spaceTotals sums the space properties of a group of snapshots
+type spaceTotals struct {
+	count             int
+	used              uint64
+	referenced        uint64
+	logicalReferenced uint64
+	reclaim           uint64
+	reclaimUnknown    bool // The reclaimable space of at least one group could not be estimated
+}
+
+func (t *spaceTotals) add(snapshot *models.Snapshot) {
+	t.count++
+	t.used += snapshot.Used
+	t.referenced += snapshot.Referenced
+	t.logicalReferenced += snapshot.LogicalReferenced
+}
+
+// SpaceReport writes the space used by the snapshots of every dataset, per tier and in total
+// USED only counts blocks unique to a single snapshot; RECLAIMABLE is estimated with zfs destroy -nvp
+// for all snapshots of a row together, so it includes blocks shared among them as well
+func (o *Operator) SpaceReport(w io.Writer) error {
+	inventory, err := zfs.LoadInventory(o.backend)
+	if err != nil {
+		return err
+	}
+
+	// Group the snapshots by dataset and tier
+	byDataset := make(map[string]map[string][]*models.Snapshot)
+	for _, snapshot := range inventory.All() {
+		if !o.config.IsPoolAllowed(snapshot.PoolName) || !o.config.IsFilesystemAllowed(snapshot.FilesystemName) {
+			continue
+		}
+		tier := snapshot.Frequency
+		if tier == "" {
+			tier = otherTier
+		}
+		if byDataset[snapshot.FilesystemName] == nil {
+			byDataset[snapshot.FilesystemName] = make(map[string][]*models.Snapshot)
+		}
+		byDataset[snapshot.FilesystemName][tier] = append(byDataset[snapshot.FilesystemName][tier], snapshot)
+	}
+
+	datasets := make([]string, 0, len(byDataset))
+	for name := range byDataset {
+		datasets = append(datasets, name)
+	}
+	sort.Strings(datasets)
+	tiers := append(o.config.TierNames(), otherTier)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATASET\tTIER\tSNAPSHOTS\tUSED\tREFERENCED\tLOGICALREFERENCED\tRECLAIMABLE")
+
+	tierTotals := make(map[string]*spaceTotals)
+	var grandTotal spaceTotals
+	for _, dataset := range datasets {
+		var datasetTotal spaceTotals
+		var all []*models.Snapshot
+		for _, tier := range tiers {
+			snapshots := byDataset[dataset][tier]
+			if len(snapshots) == 0 {
+				continue
+			}
+
+			var totals spaceTotals
+			for _, snapshot := range snapshots {
+				totals.add(snapshot)
+				datasetTotal.add(snapshot)
+				grandTotal.add(snapshot)
+			}
+			o.estimateReclaim(&totals, snapshots)
+			writeSpaceRow(tw, dataset, tier, &totals)
+
+			if tierTotals[tier] == nil {
+				tierTotals[tier] = &spaceTotals{}
+			}
+			tierTotals[tier].count += totals.count
+			tierTotals[tier].used += totals.used
+			tierTotals[tier].referenced += totals.referenced
+			tierTotals[tier].logicalReferenced += totals.logicalReferenced
+			tierTotals[tier].reclaim += totals.reclaim
+			tierTotals[tier].reclaimUnknown = tierTotals[tier].reclaimUnknown || totals.reclaimUnknown
+			all = append(all, snapshots...)
+		}
+
+		// Destroying all snapshots of a dataset also frees the blocks shared across tiers
+		o.estimateReclaim(&datasetTotal, all)
+		writeSpaceRow(tw, dataset, "total", &datasetTotal)
+		grandTotal.reclaim += datasetTotal.reclaim
+		grandTotal.reclaimUnknown = grandTotal.reclaimUnknown || datasetTotal.reclaimUnknown
+	}
+
+	for _, tier := range tiers {
+		if totals := tierTotals[tier]; totals != nil {
+			writeSpaceRow(tw, "TOTAL", tier, totals)
+		}
+	}
+	writeSpaceRow(tw, "TOTAL", "total", &grandTotal)
+
+	return tw.Flush()
+}
+
+// estimateReclaim asks the backend how much space destroying the snapshots of one dataset would free
+func (o *Operator) estimateReclaim(totals *spaceTotals, snapshots []*models.Snapshot) {
+	reclaim, err := zfs.EstimateReclaim(o.backend, snapshots)
+	if err != nil {
+		klog.Warningf(" Failed to estimate the reclaimable space of %d snapshot(s) of %s: %v",
+			len(snapshots), snapshots[0].FilesystemName, err)
+		totals.reclaimUnknown = true
+		return
+	}
+	totals.reclaim = reclaim
+}
+
+func writeSpaceRow(w io.Writer, dataset, tier string, totals *spaceTotals) {
+	reclaim := formatSize(totals.reclaim)
+	if totals.reclaimUnknown {
+		reclaim = "-"
+	}
+	fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n", dataset, tier, totals.count, formatSize(totals.used),
+		formatSize(totals.referenced), formatSize(totals.logicalReferenced), reclaim)
+}
+
+// logReclaimEstimate logs how much space the deletions planned by a dry-run would reclaim
+func (o *Operator) logReclaimEstimate() {
+	if len(o.plannedDeletions) == 0 {
+		return
+	}
+
+	byDataset := make(map[string][]*models.Snapshot)
+	for _, snapshot := range o.plannedDeletions {
+		byDataset[snapshot.FilesystemName] = append(byDataset[snapshot.FilesystemName], snapshot)
+	}
+	datasets := make([]string, 0, len(byDataset))
+	for name := range byDataset {
+		datasets = append(datasets, name)
+	}
+	sort.Strings(datasets)
+
+	var total uint64
+	for _, dataset := range datasets {
+		reclaim, err := zfs.EstimateReclaim(o.backend, byDataset[dataset])
+		if err != nil {
+			klog.Warningf(" [DRY-RUN] Failed to estimate the reclaimable space of %s: %v", dataset, err)
+			continue
+		}
+		total += reclaim
+		klog.Infof("[DRY-RUN] Deleting %d snapshot(s) of %s would reclaim %s", len(byDataset[dataset]), dataset, formatSize(reclaim))
+	}
+	klog.Infof("[DRY-RUN] Planned deletions would reclaim %s in total", formatSize(total))
+}
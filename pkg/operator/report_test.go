@@ -0,0 +1,85 @@
+package operator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/config"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+)
+
+func TestSpaceReport(t *testing.T) {
+	snapshots := spaceSnapshots()
+	snapshots = append(snapshots,
+		&models.Snapshot{PoolName: "tank", FilesystemName: "tank/data", SnapshotName: "before-upgrade", Used: gib, Referenced: 50 * gib},
+		&models.Snapshot{PoolName: "backup", FilesystemName: "backup/data", SnapshotName: "autosnap_2026-01-25_10:00:00_hourly",
+			Frequency: "hourly", Used: 2 * gib})
+	mock := &mockZFSManager{snapshots: snapshots}
+
+	cfg := config.NewConfig("test")
+	cfg.PoolBlacklist = []string{"backup"}
+	op := newMockOperator(cfg, mock)
+
+	var buf bytes.Buffer
+	if err := op.SpaceReport(&buf); err != nil {
+		t.Fatalf("SpaceReport() error = %v", err)
+	}
+
+	// Normalize the tabwriter padding to single spaces
+	var lines []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	output := strings.Join(lines, "\n")
+
+	for _, want := range []string{
+		"tank/data hourly 5 20.00G 0B 0B 20.00G",
+		"tank/data daily 2 20.00G 0B 0B 20.00G",
+		"tank/data other 1 1.00G 50.00G 0B 1.00G",
+		"tank/data total 8 41.00G 50.00G 0B 41.00G",
+		"TOTAL hourly 5 20.00G 0B 0B 20.00G",
+		"TOTAL total 8 41.00G 50.00G 0B 41.00G",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("SpaceReport() output missing %q\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "backup/data") {
+		t.Errorf("SpaceReport() reported a blacklisted pool\n%s", output)
+	}
+}
+
+// TestDryRunPlannedDeletions tests that a dry-run remembers the deletions for the reclaim estimate
+func TestDryRunPlannedDeletions(t *testing.T) {
+	cfg := hourlyOnlyConfig()
+	cfg.DryRun = true
+	cfg.MaxHourlySnapshots = 2
+
+	base := time.Date(2026, 1, 25, 8, 0, 0, 0, time.UTC)
+	var snapshots []*models.Snapshot
+	for i := 0; i < 4; i++ {
+		snapshot := hourlySnapshot("tank/data", base.Add(time.Duration(i)*time.Hour))
+		snapshot.Used = gib
+		snapshots = append(snapshots, snapshot)
+	}
+	mock := &mockZFSManager{
+		snapshots:  snapshots,
+		pools:      []*models.Pool{{PoolName: "tank", FilesystemName: "tank/data"}},
+		poolStatus: map[string]*models.PoolStatus{"tank": {Name: "tank", State: "ONLINE", ErrorCount: "0"}},
+	}
+	op := newMockOperator(cfg, mock)
+
+	if err := op.RunAt(base.Add(4 * time.Hour)); err != nil {
+		t.Fatalf("RunAt() error = %v", err)
+	}
+
+	if len(mock.deletedSnapshots) != 0 {
+		t.Errorf("Dry-run deleted %d snapshot(s)", len(mock.deletedSnapshots))
+	}
+	// The new snapshot takes one of the two hourly periods, so three snapshots would be deleted
+	if len(op.plannedDeletions) != 3 {
+		t.Errorf("Planned %d deletion(s), want 3", len(op.plannedDeletions))
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/runningman84/zfs-snapshot-operator/pkg/config"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
 	"github.com/runningman84/zfs-snapshot-operator/pkg/zfs"
 )
 
@@ -20,7 +22,8 @@ import (
 // - Delete all yearly snapshots older than 3 years
 //
 // This ensures temporal coverage (one snapshot per period) rather than
-// just keeping the N most recent snapshots.
+// just keeping the N most recent snapshots. The count retention mode keeps the
+// N newest period keepers instead, regardless of their age.
 
 func TestGetTimePeriodKey(t *testing.T) {
 
@@ -457,3 +460,68 @@ func TestSafetyCheckPreventsZeroSnapshots(t *testing.T) {
 	t.Log("The safety check ensures we never have a period with zero snapshots")
 	t.Log("Even during transitions or if snapshot creation fails, at least one snapshot is retained")
 }
+
+// TestRetentionModes tests window, count and both retention after an outage
+func TestRetentionModes(t *testing.T) {
+	// Daily snapshots of a box that was offline for three weeks, plus one from the week before that
+	dailySnapshot := func(day int) *models.Snapshot {
+		dateTime := time.Date(2026, 1, day, 0, 5, 0, 0, time.UTC)
+		return &models.Snapshot{
+			PoolName:       "tank",
+			FilesystemName: "tank/data",
+			SnapshotName:   "autosnap_" + dateTime.Format("2006-01-02_15:04:05") + "_daily",
+			DateTime:       dateTime,
+			Frequency:      "daily",
+		}
+	}
+	now := time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		mode        string
+		maxDaily    int
+		wantDeleted []int
+	}{
+		{"window expires snapshots by age", config.RetentionModeWindow, 3, []int{1, 2, 3, 8}},
+		{"count keeps the newest regardless of age", config.RetentionModeCount, 4, []int{1}},
+		{"count includes the new snapshot", config.RetentionModeCount, 3, []int{1, 2}},
+		{"both keeps the union", config.RetentionModeBoth, 3, []int{1, 2}},
+		{"both keeps the larger window", config.RetentionModeBoth, 30, []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewConfig("test")
+			cfg.MaxDailySnapshots = tt.maxDaily
+			cfg.RetentionModes = map[string]string{"daily": tt.mode}
+
+			mock := &mockZFSManager{
+				snapshots: []*models.Snapshot{dailySnapshot(1), dailySnapshot(2), dailySnapshot(3), dailySnapshot(8)},
+			}
+			op := newMockOperator(cfg, mock)
+			loadInventory(t, op)
+
+			pool := &models.Pool{PoolName: "tank", FilesystemName: "tank/data"}
+			if err := op.processFrequency(pool, "daily", now); err != nil {
+				t.Fatalf("processFrequency() error = %v", err)
+			}
+
+			if len(mock.createdSnapshots) != 1 {
+				t.Errorf("Created %d snapshot(s), want 1", len(mock.createdSnapshots))
+			}
+
+			deleted := make(map[string]bool)
+			for _, snapshot := range mock.deletedSnapshots {
+				deleted[snapshot.SnapshotName] = true
+			}
+			if len(deleted) != len(tt.wantDeleted) {
+				t.Errorf("Deleted %d snapshot(s), want %d", len(deleted), len(tt.wantDeleted))
+			}
+			for _, day := range tt.wantDeleted {
+				if name := dailySnapshot(day).SnapshotName; !deleted[name] {
+					t.Errorf("Expected %s to be deleted", name)
+				}
+			}
+		})
+	}
+}
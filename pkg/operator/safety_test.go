@@ -0,0 +1,264 @@
+package operator
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/config"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/zfs"
+)
+
+func deletedNames(mock *mockZFSManager) map[string]bool {
+	deleted := make(map[string]bool)
+	for _, snapshot := range mock.deletedSnapshots {
+		deleted[snapshot.SnapshotName] = true
+	}
+	return deleted
+}
+
+// TestNeverDeleteNewestSnapshot tests that the newest snapshot of a dataset survives a disabled tier
+func TestNeverDeleteNewestSnapshot(t *testing.T) {
+	cfg := config.NewConfig("test")
+	cfg.MaxHourlySnapshots = 0
+
+	base := time.Date(2026, 1, 25, 10, 0, 0, 0, time.UTC)
+	snapshots := []*models.Snapshot{
+		hourlySnapshot("tank/data", base),
+		hourlySnapshot("tank/data", base.Add(time.Hour)),
+		hourlySnapshot("tank/data", base.Add(2*time.Hour)),
+	}
+	mock := &mockZFSManager{snapshots: snapshots}
+	op := newMockOperator(cfg, mock)
+	loadInventory(t, op)
+
+	pool := &models.Pool{PoolName: "tank", FilesystemName: "tank/data"}
+	if err := op.processFrequency(pool, "hourly", base.Add(3*time.Hour)); err != nil {
+		t.Fatalf("processFrequency() error = %v", err)
+	}
+
+	deleted := deletedNames(mock)
+	if len(deleted) != 2 {
+		t.Errorf("Deleted %d snapshot(s), want 2", len(deleted))
+	}
+	if deleted[snapshots[2].SnapshotName] {
+		t.Errorf("The newest snapshot %s of the dataset must never be deleted", snapshots[2].SnapshotName)
+	}
+}
+
+// TestNeverDeleteNewestSnapshotOtherTier tests that the guarantee covers the newest managed snapshot across tiers
+func TestNeverDeleteNewestSnapshotOtherTier(t *testing.T) {
+	base := time.Date(2026, 1, 25, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		frequency   string // Frequency of the newer snapshot, empty for an unmanaged snapshot
+		wantDeleted bool
+	}{
+		{name: "newer snapshot of another tier", frequency: "daily", wantDeleted: true},
+		// A manual snapshot does not protect the managed ones, it is never deleted by the operator anyway
+		{name: "newer unmanaged snapshot", frequency: "", wantDeleted: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewConfig("test")
+			cfg.MaxHourlySnapshots = 0
+
+			hourly := hourlySnapshot("tank/data", base)
+			newer := &models.Snapshot{
+				PoolName:       "tank",
+				FilesystemName: "tank/data",
+				SnapshotName:   "autosnap_2026-01-25_11:00:00_daily",
+				DateTime:       base.Add(time.Hour),
+				Frequency:      tt.frequency,
+			}
+			if tt.frequency == "" {
+				newer.SnapshotName = "before-upgrade"
+			}
+			mock := &mockZFSManager{snapshots: []*models.Snapshot{hourly, newer}}
+			op := newMockOperator(cfg, mock)
+			loadInventory(t, op)
+
+			pool := &models.Pool{PoolName: "tank", FilesystemName: "tank/data"}
+			if err := op.processFrequency(pool, "hourly", base.Add(3*time.Hour)); err != nil {
+				t.Fatalf("processFrequency() error = %v", err)
+			}
+
+			if got := deletedNames(mock)[hourly.SnapshotName]; got != tt.wantDeleted {
+				t.Errorf("%s deleted = %v, want %v", hourly.SnapshotName, got, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+// TestMinKeepAfterClockJump tests that a clock jump far into the future cannot delete below min_keep
+func TestMinKeepAfterClockJump(t *testing.T) {
+	base := time.Date(2026, 1, 25, 10, 0, 0, 0, time.UTC)
+	jump := base.AddDate(5, 0, 0)
+
+	tests := []struct {
+		name        string
+		minKeep     int
+		maxHourly   int
+		wantDeleted int
+	}{
+		// The snapshot created in the run is the newest of the dataset, the old ones may all go
+		{"no floor", 0, 24, 5},
+		// The snapshot created in the run counts towards the floor
+		{"floor of three", 3, 24, 3},
+		{"floor larger than the snapshot count", 10, 24, 0},
+		{"floor on a disabled tier", 2, 0, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewConfig("test")
+			cfg.MinKeepSnapshots = tt.minKeep
+			cfg.MaxHourlySnapshots = tt.maxHourly
+
+			var snapshots []*models.Snapshot
+			for i := 0; i < 5; i++ {
+				snapshots = append(snapshots, hourlySnapshot("tank/data", base.Add(time.Duration(i)*time.Hour)))
+			}
+			mock := &mockZFSManager{snapshots: snapshots}
+			op := newMockOperator(cfg, mock)
+			loadInventory(t, op)
+
+			pool := &models.Pool{PoolName: "tank", FilesystemName: "tank/data"}
+			if err := op.processFrequency(pool, "hourly", jump); err != nil {
+				t.Fatalf("processFrequency() error = %v", err)
+			}
+
+			deleted := deletedNames(mock)
+			if len(deleted) != tt.wantDeleted {
+				t.Errorf("Deleted %d snapshot(s), want %d", len(deleted), tt.wantDeleted)
+			}
+			// Whatever is deleted, it is always the oldest snapshots
+			for i := tt.wantDeleted; i < len(snapshots); i++ {
+				if deleted[snapshots[i].SnapshotName] {
+					t.Errorf("%s should be kept, a newer snapshot was deleted first", snapshots[i].SnapshotName)
+				}
+			}
+		})
+	}
+}
+
+// TestMinKeepPerDataset tests that the min_keep floor of the policy file applies per dataset
+func TestMinKeepPerDataset(t *testing.T) {
+	minKeep := 2
+	cfg := config.NewConfig("test")
+	cfg.MaxHourlySnapshots = 0
+	cfg.DatasetPolicies = map[string]config.Policy{"tank/important": {MinKeep: &minKeep}}
+
+	base := time.Date(2026, 1, 25, 10, 0, 0, 0, time.UTC)
+	var snapshots []*models.Snapshot
+	for _, filesystemName := range []string{"tank/important", "tank/scratch"} {
+		for i := 0; i < 3; i++ {
+			snapshots = append(snapshots, hourlySnapshot(filesystemName, base.Add(time.Duration(i)*time.Hour)))
+		}
+	}
+	mock := &mockZFSManager{snapshots: snapshots}
+	op := newMockOperator(cfg, mock)
+	loadInventory(t, op)
+
+	for _, filesystemName := range []string{"tank/important", "tank/scratch"} {
+		pool := &models.Pool{PoolName: "tank", FilesystemName: filesystemName}
+		if err := op.processFrequency(pool, "hourly", base.Add(5*time.Hour)); err != nil {
+			t.Fatalf("processFrequency() error = %v", err)
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, snapshot := range mock.deletedSnapshots {
+		counts[snapshot.FilesystemName]++
+	}
+	if counts["tank/important"] != 1 {
+		t.Errorf("Deleted %d snapshot(s) of tank/important, want 1", counts["tank/important"])
+	}
+	if counts["tank/scratch"] != 2 {
+		t.Errorf("Deleted %d snapshot(s) of tank/scratch, want 2", counts["tank/scratch"])
+	}
+}
+
+// TestRetainedSnapshots tests that held and cloned snapshots are skipped instead of failing on every run
+func TestRetainedSnapshots(t *testing.T) {
+	base := time.Date(2026, 1, 25, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		deferDestroy bool
+		deleteError  error
+		wantDeleted  int
+		wantRetained int
+		wantFailures int
+	}{
+		{name: "held and cloned snapshots are retained", wantDeleted: 1, wantRetained: 3},
+		{name: "deferred destroy marks them", deferDestroy: true, wantDeleted: 4, wantRetained: 0},
+		{name: "hold placed after listing", deleteError: fmt.Errorf("%w: dataset is busy", zfs.ErrSnapshotRetained), wantRetained: 4},
+		{name: "other errors are failures", deleteError: fmt.Errorf("command failed"), wantRetained: 3, wantFailures: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewConfig("test")
+			cfg.MaxHourlySnapshots = 0
+			cfg.DeferDestroy = tt.deferDestroy
+
+			held := hourlySnapshot("tank/data", base)
+			held.UserRefs = 1
+			cloned := hourlySnapshot("tank/data", base.Add(time.Hour))
+			cloned.Clones = []string{"tank/clone"}
+			both := hourlySnapshot("tank/data", base.Add(2*time.Hour))
+			both.UserRefs = 1
+			both.Clones = []string{"tank/clone2"}
+			marked := hourlySnapshot("tank/data", base.Add(3*time.Hour))
+			marked.UserRefs = 1
+			marked.DeferDestroy = true
+			free := hourlySnapshot("tank/data", base.Add(4*time.Hour))
+			newest := hourlySnapshot("tank/data", base.Add(5*time.Hour))
+
+			mock := &mockZFSManager{snapshots: []*models.Snapshot{held, cloned, both, marked, free, newest}, deleteError: tt.deleteError}
+			op := newMockOperator(cfg, mock)
+			loadInventory(t, op)
+
+			pool := &models.Pool{PoolName: "tank", FilesystemName: "tank/data"}
+			if err := op.processFrequency(pool, "hourly", base.Add(6*time.Hour)); err != nil {
+				t.Fatalf("processFrequency() error = %v", err)
+			}
+
+			if len(mock.deletedSnapshots) != tt.wantDeleted {
+				t.Errorf("Deleted %d snapshot(s), want %d", len(mock.deletedSnapshots), tt.wantDeleted)
+			}
+			if deletedNames(mock)[marked.SnapshotName] {
+				t.Errorf("Snapshot %s is already marked for deferred destruction", marked.SnapshotName)
+			}
+			if op.retainedCount != tt.wantRetained {
+				t.Errorf("retainedCount = %d, want %d", op.retainedCount, tt.wantRetained)
+			}
+			if op.deletionFailures != tt.wantFailures {
+				t.Errorf("deletionFailures = %d, want %d", op.deletionFailures, tt.wantFailures)
+			}
+		})
+	}
+}
+
+func TestRetainedBy(t *testing.T) {
+	tests := []struct {
+		userRefs uint64
+		clones   []string
+		want     string
+	}{
+		{0, nil, ""},
+		{1, nil, "hold"},
+		{0, []string{"tank/clone"}, "clone"},
+		{2, []string{"tank/clone"}, "hold/clone"},
+	}
+	for _, tt := range tests {
+		snapshot := &models.Snapshot{UserRefs: tt.userRefs, Clones: tt.clones}
+		if got := retainedBy(snapshot); got != tt.want {
+			t.Errorf("retainedBy(userrefs %d, clones %v) = %q, want %q", tt.userRefs, tt.clones, got, tt.want)
+		}
+	}
+}
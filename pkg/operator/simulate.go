@@ -0,0 +1,72 @@
+package operator
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/config"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/zfs"
+)
+
+// Simulate replays the given number of runs against a simulated backend using a virtual clock
+// The clock starts at start and advances by interval after every run. The snapshot set of
+// the simulated backend is written to w after each run so a retention policy can be reviewed
+// before it is rolled out
+func Simulate(cfg *config.Config, backend *zfs.SimulatedBackend, start time.Time, interval time.Duration, runs int, w io.Writer) error {
+	if runs < 1 {
+		return fmt.Errorf("number of runs must be at least 1, got %d", runs)
+	}
+	if interval <= 0 {
+		return fmt.Errorf("interval must be positive, got %s", interval)
+	}
+
+	op := NewOperatorWithBackend(cfg, backend)
+
+	failedRuns := 0
+	now := start
+	for run := 1; run <= runs; run++ {
+		backend.SetTime(now)
+		err := op.RunAt(now)
+
+		fmt.Fprintf(w, "=== Run %d/%d at %s: created %d, deleted %d ===\n",
+			run, runs, now.Format("2006-01-02 15:04:05"), op.creationCount, op.deletionCount)
+		if err != nil {
+			failedRuns++
+			fmt.Fprintf(w, "Run failed: %v\n", err)
+		}
+
+		if err := writeSnapshotSet(backend, w); err != nil {
+			return err
+		}
+
+		now = now.Add(interval)
+	}
+
+	if failedRuns > 0 {
+		return fmt.Errorf("%d of %d simulated run(s) failed", failedRuns, runs)
+	}
+	return nil
+}
+
+// writeSnapshotSet writes all snapshots of the simulated backend grouped by filesystem
+func writeSnapshotSet(backend *zfs.SimulatedBackend, w io.Writer) error {
+	snapshots, err := backend.GetSnapshots("", "", "")
+	if err != nil {
+		return fmt.Errorf("failed to get snapshots: %w", err)
+	}
+
+	currentFilesystem := ""
+	for _, snapshot := range snapshots {
+		if snapshot.FilesystemName != currentFilesystem {
+			currentFilesystem = snapshot.FilesystemName
+			fmt.Fprintf(w, "%s:\n", currentFilesystem)
+		}
+		fmt.Fprintf(w, "  %s\n", snapshot.SnapshotName)
+	}
+	if len(snapshots) == 0 {
+		fmt.Fprintln(w, "(no snapshots)")
+	}
+
+	return nil
+}
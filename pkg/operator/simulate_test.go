@@ -0,0 +1,69 @@
+package operator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/config"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/zfs"
+)
+
+func newSimulatedTank() *zfs.SimulatedBackend {
+	return zfs.NewSimulatedBackend(
+		[]*models.Pool{
+			{PoolName: "tank"},
+			{PoolName: "tank", FilesystemName: "tank/data"},
+		},
+		map[string]*models.PoolStatus{
+			"tank": {Name: "tank", State: "ONLINE", ErrorCount: "0"},
+		},
+		nil,
+	)
+}
+
+func TestSimulateHourlyRetention(t *testing.T) {
+	cfg := config.NewConfig("simulate")
+	cfg.EnableLocking = false
+	cfg.MaxHourlySnapshots = 3
+	cfg.MaxDailySnapshots = 0
+	cfg.MaxWeeklySnapshots = 0
+	cfg.MaxMonthlySnapshots = 0
+	cfg.MaxYearlySnapshots = 0
+
+	backend := newSimulatedTank()
+	start := time.Date(2026, 1, 25, 0, 0, 0, 0, time.UTC)
+
+	var out bytes.Buffer
+	if err := Simulate(cfg, backend, start, time.Hour, 10, &out); err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+
+	snapshots, _ := backend.GetSnapshots("tank", "tank/data", "hourly")
+	// Retention window covers the last 3 hours including the current one
+	if len(snapshots) != 3 {
+		t.Errorf("Hourly snapshots after simulation = %d, want 3", len(snapshots))
+	}
+
+	if strings.Count(out.String(), "=== Run ") != 10 {
+		t.Errorf("Simulation output should contain 10 runs, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "autosnap_2026-01-25_09:00:00_hourly") {
+		t.Errorf("Simulation output should contain the last hourly snapshot, got:\n%s", out.String())
+	}
+}
+
+func TestSimulateInvalidArguments(t *testing.T) {
+	cfg := config.NewConfig("simulate")
+	backend := newSimulatedTank()
+	now := time.Date(2026, 1, 25, 0, 0, 0, 0, time.UTC)
+
+	if err := Simulate(cfg, backend, now, time.Hour, 0, &bytes.Buffer{}); err == nil {
+		t.Error("Simulate() should reject zero runs")
+	}
+	if err := Simulate(cfg, backend, now, 0, 1, &bytes.Buffer{}); err == nil {
+		t.Error("Simulate() should reject a zero interval")
+	}
+}
@@ -0,0 +1,209 @@
+package operator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/zfs"
+	"k8s.io/klog/v2"
+)
+
+// spacePruner tracks the state of one space-pressure pruning phase
+type spacePruner struct {
+	op        *Operator
+	managed   map[string]bool               // Filesystems whose snapshots may be pruned
+	tierIndex map[string]int                // Position of each tier, lowest (shortest period) first
+	remaining map[string]int                // Snapshots left per filesystem and tier, for the min_keep floors
+	freed     map[string]uint64             // Estimated bytes freed per filesystem
+	pending   map[string][]*models.Snapshot // Pruned snapshots per filesystem that still exist (dry-run mode)
+}
+
+// pruneForSpace deletes the oldest snapshots of the lowest tiers of every pool or dataset above
+// SPACE_PRESSURE_THRESHOLD, until its usage is estimated to be back at SPACE_PRESSURE_TARGET
+// Datasets are checked before their pools, so space freed for a dataset also counts for its pool
+func (o *Operator) pruneForSpace(pools []*models.Pool, poolStatus map[string]*models.PoolStatus) {
+	if !o.config.SpacePressureEnabled() {
+		return
+	}
+
+	healthy := o.healthyPools(poolStatus)
+	p := &spacePruner{
+		op:        o,
+		managed:   o.managedFilesystems(pools, healthy),
+		tierIndex: make(map[string]int),
+		remaining: make(map[string]int),
+		freed:     make(map[string]uint64),
+		pending:   make(map[string][]*models.Snapshot),
+	}
+	for i, name := range o.config.TierNames() {
+		p.tierIndex[name] = i
+	}
+
+	for _, pool := range pools {
+		if !p.managed[pool.FilesystemName] {
+			continue
+		}
+		used := parseSize(pool.Used)
+		avail := parseSize(pool.Avail)
+		p.relieve("dataset "+pool.FilesystemName, pool.FilesystemName, used, used+avail)
+	}
+
+	poolNames := make([]string, 0, len(poolStatus))
+	for name := range poolStatus {
+		poolNames = append(poolNames, name)
+	}
+	sort.Strings(poolNames)
+	for _, name := range poolNames {
+		if !healthy[name] {
+			continue
+		}
+		status := poolStatus[name]
+		p.relieve("pool "+name, name, parseSize(status.AllocSpace), parseSize(status.TotalSpace))
+	}
+}
+
+// relieve prunes the snapshots of root and its descendants if used is above the threshold of total
+func (p *spacePruner) relieve(label, root string, used, total int64) {
+	cfg := p.op.config
+	if used <= 0 || total <= 0 {
+		return
+	}
+
+	// Space freed earlier in this phase, e.g. for a child dataset, is already gone
+	used -= int64(p.freedBelow(root))
+	percent := float64(used) / float64(total) * 100
+	if percent < float64(cfg.SpacePressureThreshold) {
+		klog.V(1).Infof("Space pressure: %s at %.1f%%, below the threshold of %d%%", label, percent, cfg.SpacePressureThreshold)
+		return
+	}
+
+	needed := uint64(used - total*int64(cfg.SpacePressureTarget)/100)
+	klog.Warningf(" Space pressure: %s at %.1f%% (threshold %d%%) - pruning %s to get back to %d%%",
+		label, percent, cfg.SpacePressureThreshold, formatSize(needed), cfg.SpacePressureTarget)
+
+	var freed uint64
+	for _, snapshot := range p.candidates(root) {
+		if freed >= needed {
+			break
+		}
+		if p.op.deletionCount >= cfg.MaxDeletionsPerRun {
+			klog.Warningf(" Space pressure: reached deletion limit of %d snapshots", cfg.MaxDeletionsPerRun)
+			break
+		}
+
+		name := snapshot.FilesystemName + "@" + snapshot.SnapshotName
+		// Deferred destruction only frees the space once the hold or clone is gone
+		if retainer := retainedBy(snapshot); retainer != "" {
+			klog.Infof("Space pressure: keeping %s, retained by %s", name, retainer)
+			continue
+		}
+		key := snapshot.FilesystemName + "@" + snapshot.Frequency
+		if _, ok := p.remaining[key]; !ok {
+			p.remaining[key] = len(p.op.inventory.Get(snapshot.FilesystemName, snapshot.Frequency))
+		}
+		if minKeep := cfg.GetMinKeep(snapshot.FilesystemName); p.remaining[key] <= minKeep {
+			klog.Infof("Space pressure: keeping %s, %s is at its min_keep of %d", name, snapshot.Frequency, minKeep)
+			continue
+		}
+
+		reclaim := p.reclaim(snapshot)
+		if p.op.deleteSnapshots([]*models.Snapshot{snapshot}, "space pressure") == 0 {
+			continue
+		}
+		klog.Infof("Space pressure: pruned %s (%s tier, %s)", name, snapshot.Frequency, formatSize(reclaim))
+		p.remaining[key]--
+		if p.op.config.DryRun {
+			p.pending[snapshot.FilesystemName] = append(p.pending[snapshot.FilesystemName], snapshot)
+		}
+		p.freed[snapshot.FilesystemName] += reclaim
+		freed += reclaim
+	}
+
+	if freed >= needed {
+		klog.Infof("Space pressure: freed about %s on %s", formatSize(freed), label)
+	} else {
+		klog.Warningf(" Space pressure: freed only about %s of %s on %s, no more snapshots may be pruned",
+			formatSize(freed), formatSize(needed), label)
+	}
+}
+
+// reclaim estimates the bytes deleting a snapshot frees after the snapshots pruned before it
+// The used property only counts blocks unique to one snapshot, so blocks shared by consecutive snapshots
+// are freed by deleting the last of them: the estimate covers the pruned snapshots that still exist
+// (all of them in dry-run mode, ZFS has updated the used property of the rest after real deletions)
+func (p *spacePruner) reclaim(snapshot *models.Snapshot) uint64 {
+	pending := p.pending[snapshot.FilesystemName]
+	before, err := zfs.EstimateReclaim(p.op.backend, pending)
+	var after uint64
+	if err == nil {
+		after, err = zfs.EstimateReclaim(p.op.backend, append(pending[:len(pending):len(pending)], snapshot))
+	}
+	if err != nil {
+		klog.Warningf(" Space pressure: failed to estimate the space of %s@%s, using its used property: %v",
+			snapshot.FilesystemName, snapshot.SnapshotName, err)
+		return snapshot.Used
+	}
+	if after < before {
+		return 0
+	}
+	return after - before
+}
+
+// candidates returns the managed snapshots of root and its descendants in pruning order:
+// lowest tier first and, within a tier, oldest first
+func (p *spacePruner) candidates(root string) []*models.Snapshot {
+	var candidates []*models.Snapshot
+	for _, snapshot := range p.op.inventory.All() {
+		if _, ok := p.tierIndex[snapshot.Frequency]; !ok || !p.managed[snapshot.FilesystemName] {
+			continue
+		}
+		if isDescendant(snapshot.FilesystemName, root) {
+			candidates = append(candidates, snapshot)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if p.tierIndex[a.Frequency] != p.tierIndex[b.Frequency] {
+			return p.tierIndex[a.Frequency] < p.tierIndex[b.Frequency]
+		}
+		if !a.DateTime.Equal(b.DateTime) {
+			return a.DateTime.Before(b.DateTime)
+		}
+		return a.CreateTxg < b.CreateTxg
+	})
+	return candidates
+}
+
+// freedBelow returns the bytes freed so far on root and its descendants
+func (p *spacePruner) freedBelow(root string) uint64 {
+	var freed uint64
+	for filesystem, bytes := range p.freed {
+		if isDescendant(filesystem, root) {
+			freed += bytes
+		}
+	}
+	return freed
+}
+
+// isDescendant checks if a filesystem is root itself or lies below it
+func isDescendant(filesystemName, root string) bool {
+	return filesystemName == root || strings.HasPrefix(filesystemName, root+"/")
+}
+
+// formatSize converts bytes to a size string like "9.07T", the reverse of parseSize
+func formatSize(bytes uint64) string {
+	const units = "KMGTP"
+	if bytes < 1024 {
+		return fmt.Sprintf("%dB", bytes)
+	}
+	value := float64(bytes)
+	unit := -1
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.2f%c", value, units[unit])
+}
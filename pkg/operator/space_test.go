@@ -0,0 +1,198 @@
+package operator
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/config"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+)
+
+const gib = 1024 * 1024 * 1024
+
+// spaceSnapshots returns five hourly snapshots of 4G and two daily snapshots of 10G of tank/data, oldest first
+func spaceSnapshots() []*models.Snapshot {
+	base := time.Date(2026, 1, 25, 10, 0, 0, 0, time.UTC)
+	var snapshots []*models.Snapshot
+	for i := 0; i < 2; i++ {
+		daily := hourlySnapshot("tank/data", base.AddDate(0, 0, i-2))
+		daily.SnapshotName = "autosnap_" + daily.DateTime.Format("2006-01-02_15:04:05") + "_daily"
+		daily.Frequency = "daily"
+		daily.Used = 10 * gib
+		snapshots = append(snapshots, daily)
+	}
+	for i := 0; i < 5; i++ {
+		hourly := hourlySnapshot("tank/data", base.Add(time.Duration(i)*time.Hour))
+		hourly.Used = 4 * gib
+		snapshots = append(snapshots, hourly)
+	}
+	return snapshots
+}
+
+func TestPruneForSpace(t *testing.T) {
+	tests := []struct {
+		name        string
+		threshold   int
+		minKeep     int
+		alloc       string
+		datasetUsed string
+		datasetFree string
+		wantDeleted []string
+	}{
+		{
+			name:        "disabled",
+			threshold:   0,
+			alloc:       "99G",
+			datasetUsed: "50G",
+			datasetFree: "50G",
+		},
+		{
+			name:        "pool below threshold",
+			threshold:   90,
+			alloc:       "85G",
+			datasetUsed: "50G",
+			datasetFree: "50G",
+		},
+		{
+			// 15G have to go: the four oldest hourly snapshots free 16G
+			name:        "pool above threshold",
+			threshold:   90,
+			alloc:       "95G",
+			datasetUsed: "50G",
+			datasetFree: "50G",
+			wantDeleted: []string{
+				"autosnap_2026-01-25_10:00:00_hourly",
+				"autosnap_2026-01-25_11:00:00_hourly",
+				"autosnap_2026-01-25_12:00:00_hourly",
+				"autosnap_2026-01-25_13:00:00_hourly",
+			},
+		},
+		{
+			// The next tier is only pruned once the lowest tier is at its floor
+			name:        "min_keep floors",
+			threshold:   90,
+			minKeep:     1,
+			alloc:       "99G",
+			datasetUsed: "50G",
+			datasetFree: "50G",
+			wantDeleted: []string{
+				"autosnap_2026-01-25_10:00:00_hourly",
+				"autosnap_2026-01-25_11:00:00_hourly",
+				"autosnap_2026-01-25_12:00:00_hourly",
+				"autosnap_2026-01-25_13:00:00_hourly",
+				"autosnap_2026-01-23_10:00:00_daily",
+			},
+		},
+		{
+			// A dataset limited by a quota can run out of space in a pool that still has plenty;
+			// 3G of the 20G quota have to go
+			name:        "dataset above threshold",
+			threshold:   90,
+			alloc:       "50G",
+			datasetUsed: "19G",
+			datasetFree: "1G",
+			wantDeleted: []string{
+				"autosnap_2026-01-25_10:00:00_hourly",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewConfig("test")
+			cfg.SpacePressureThreshold = tt.threshold
+			cfg.SpacePressureTarget = 80
+			cfg.MinKeepSnapshots = tt.minKeep
+
+			pools := []*models.Pool{{PoolName: "tank", FilesystemName: "tank/data", Used: tt.datasetUsed, Avail: tt.datasetFree}}
+			poolStatus := map[string]*models.PoolStatus{
+				"tank": {Name: "tank", State: "ONLINE", ErrorCount: "0", AllocSpace: tt.alloc, TotalSpace: "100G"},
+			}
+			mock := &mockZFSManager{snapshots: spaceSnapshots(), pools: pools, poolStatus: poolStatus}
+			op := newMockOperator(cfg, mock)
+			loadInventory(t, op)
+
+			op.pruneForSpace(pools, poolStatus)
+
+			deleted := deletedNames(mock)
+			if len(deleted) != len(tt.wantDeleted) {
+				t.Errorf("Deleted %d snapshot(s) %v, want %v", len(deleted), deleted, tt.wantDeleted)
+			}
+			for _, name := range tt.wantDeleted {
+				if !deleted[name] {
+					t.Errorf("Snapshot %s was not deleted", name)
+				}
+			}
+		})
+	}
+}
+
+// TestPruneForSpaceSharedBlocks tests that snapshots with a used of zero are pruned when they share blocks
+// with the next snapshot, and that the space of the shared blocks counts once both are deleted
+func TestPruneForSpaceSharedBlocks(t *testing.T) {
+	for _, dryRun := range []bool{false, true} {
+		t.Run(fmt.Sprintf("dry-run %v", dryRun), func(t *testing.T) {
+			cfg := config.NewConfig("test")
+			cfg.SpacePressureThreshold = 90
+			cfg.SpacePressureTarget = 80
+			cfg.DryRun = dryRun
+
+			snapshots := spaceSnapshots()
+			// The two oldest hourly snapshots share 16G that neither of them uses alone
+			first, second := snapshots[2], snapshots[3]
+			first.Used, second.Used = 0, 0
+			pools := []*models.Pool{{PoolName: "tank", FilesystemName: "tank/data", Used: "50G", Avail: "50G"}}
+			poolStatus := map[string]*models.PoolStatus{
+				"tank": {Name: "tank", State: "ONLINE", ErrorCount: "0", AllocSpace: "95G", TotalSpace: "100G"},
+			}
+			mock := &mockZFSManager{snapshots: snapshots, pools: pools, poolStatus: poolStatus}
+			mock.reclaim = func(snapshots []*models.Snapshot) uint64 {
+				gone := deletedNames(mock)
+				var reclaim uint64
+				for _, snapshot := range snapshots {
+					reclaim += snapshot.Used
+					gone[snapshot.SnapshotName] = true
+				}
+				if gone[first.SnapshotName] && gone[second.SnapshotName] {
+					reclaim += 16 * gib
+				}
+				return reclaim
+			}
+			op := newMockOperator(cfg, mock)
+			loadInventory(t, op)
+
+			op.pruneForSpace(pools, poolStatus)
+
+			var deleted []string
+			for _, snapshot := range mock.deletedSnapshots {
+				deleted = append(deleted, snapshot.SnapshotName)
+			}
+			for _, snapshot := range op.plannedDeletions {
+				deleted = append(deleted, snapshot.SnapshotName)
+			}
+			want := []string{first.SnapshotName, second.SnapshotName}
+			if strings.Join(deleted, ",") != strings.Join(want, ",") {
+				t.Errorf("Deleted %v, want %v", deleted, want)
+			}
+		})
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		bytes uint64
+		want  string
+	}{
+		{512, "512B"},
+		{1536, "1.50K"},
+		{4 * gib, "4.00G"},
+		{uint64(parseSize("9.07T")), "9.07T"},
+	}
+	for _, tt := range tests {
+		if got := formatSize(tt.bytes); got != tt.want {
+			t.Errorf("formatSize(%d) = %s, want %s", tt.bytes, got, tt.want)
+		}
+	}
+}
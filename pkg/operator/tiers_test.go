@@ -0,0 +1,72 @@
+package operator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/config"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+)
+
+// TestProcessCustomTier tests that a custom tier creates one snapshot per period and prunes by its own periods
+func TestProcessCustomTier(t *testing.T) {
+	cfg := config.NewConfig("test")
+	cfg.CustomTiers = []config.Tier{{Name: "every-5m", Unit: config.UnitMinute, Every: 5, Retention: 3}}
+
+	pool := &models.Pool{PoolName: "tank", FilesystemName: "tank/data"}
+	snapshotAt := func(minute int) *models.Snapshot {
+		dateTime := time.Date(2026, 1, 25, 14, minute, 0, 0, time.UTC)
+		return &models.Snapshot{
+			PoolName:       "tank",
+			FilesystemName: "tank/data",
+			SnapshotName:   "autosnap_" + dateTime.Format("2006-01-02_15:04:05") + "_every-5m",
+			DateTime:       dateTime,
+			Frequency:      "every-5m",
+		}
+	}
+	// Two snapshots in the 14:30 period, one in 14:25 and one outside of the retention window
+	mock := &mockZFSManager{
+		snapshots: []*models.Snapshot{snapshotAt(30), snapshotAt(31), snapshotAt(25), snapshotAt(10)},
+	}
+	op := newMockOperator(cfg, mock)
+	loadInventory(t, op)
+
+	if err := op.processFrequency(pool, "every-5m", time.Date(2026, 1, 25, 14, 37, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("processFrequency() error = %v", err)
+	}
+
+	if len(mock.createdSnapshots) != 1 {
+		t.Fatalf("Created %d snapshot(s), want 1", len(mock.createdSnapshots))
+	}
+	if got := mock.createdSnapshots[0].SnapshotName; got != "autosnap_2026-01-25_14:37:00_every-5m" {
+		t.Errorf("Created %s, want autosnap_2026-01-25_14:37:00_every-5m", got)
+	}
+
+	deleted := make(map[string]bool)
+	for _, snapshot := range mock.deletedSnapshots {
+		deleted[snapshot.SnapshotName] = true
+	}
+	want := []string{"autosnap_2026-01-25_14:30:00_every-5m", "autosnap_2026-01-25_14:10:00_every-5m"}
+	if len(deleted) != len(want) {
+		t.Errorf("Deleted %v, want %v", mock.deletedSnapshots, want)
+	}
+	for _, name := range want {
+		if !deleted[name] {
+			t.Errorf("Expected %s to be deleted", name)
+		}
+	}
+}
+
+func TestDefaultDaemonIntervalCustomTier(t *testing.T) {
+	cfg := config.NewConfig("test")
+	cfg.CustomTiers = []config.Tier{{Name: "every-5m", Unit: config.UnitMinute, Every: 5, Retention: 12}}
+	op := newMockOperator(cfg, &mockZFSManager{pools: []*models.Pool{{PoolName: "tank", FilesystemName: "tank/data"}}})
+	if got, _ := op.DefaultDaemonInterval(); got != "every-5m" {
+		t.Errorf("DefaultDaemonInterval() = %s, want every-5m", got)
+	}
+
+	cfg.CustomTiers[0].Retention = 0
+	if got, _ := op.DefaultDaemonInterval(); got != "frequently" && got != "hourly" {
+		t.Errorf("DefaultDaemonInterval() = %s, a disabled custom tier should not be scheduled", got)
+	}
+}
@@ -0,0 +1,92 @@
+package operator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/config"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+)
+
+func berlinConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg := config.NewConfig("test")
+	cfg.EnableLocking = false
+	if err := cfg.SetTimezone("Europe/Berlin"); err != nil {
+		t.Fatalf("SetTimezone() error = %v", err)
+	}
+	return cfg
+}
+
+// TestNewSnapshotTimezone tests that snapshot names use the configured time zone and optional offset
+func TestNewSnapshotTimezone(t *testing.T) {
+	pool := &models.Pool{PoolName: "tank", FilesystemName: "tank/data"}
+	// 01:30 UTC is 03:30 CEST, right after the spring DST transition
+	now := time.Date(2026, 3, 29, 1, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		offset bool
+		want   string
+	}{
+		{"without offset", false, "autosnap_2026-03-29_03:30:00_hourly"},
+		{"with offset", true, "autosnap_2026-03-29_03:30:00+0200_hourly"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := berlinConfig(t)
+			cfg.SnapshotNameOffset = tt.offset
+			op := newMockOperator(cfg, &mockZFSManager{})
+
+			snapshot, err := op.newSnapshot(pool, "hourly", now)
+			if err != nil {
+				t.Fatalf("newSnapshot() error = %v", err)
+			}
+			if snapshot.SnapshotName != tt.want {
+				t.Errorf("SnapshotName = %s, want %s", snapshot.SnapshotName, tt.want)
+			}
+			if !snapshot.DateTime.Equal(now) {
+				t.Errorf("DateTime = %v, want %v", snapshot.DateTime, now)
+			}
+		})
+	}
+}
+
+// TestRunBucketsInTimezone tests that daily periods follow the configured time zone, not UTC
+func TestRunBucketsInTimezone(t *testing.T) {
+	cfg := berlinConfig(t)
+	cfg.MaxHourlySnapshots = 0
+	cfg.MaxWeeklySnapshots = 0
+	cfg.MaxMonthlySnapshots = 0
+	cfg.MaxYearlySnapshots = 0
+
+	berlin := cfg.Location()
+	daily := &models.Snapshot{
+		PoolName:       "tank",
+		FilesystemName: "tank/data",
+		SnapshotName:   "autosnap_2026-01-25_08:00:00_daily",
+		DateTime:       time.Date(2026, 1, 25, 8, 0, 0, 0, berlin),
+		Frequency:      "daily",
+	}
+	mock := &mockZFSManager{
+		pools: []*models.Pool{{PoolName: "tank", FilesystemName: "tank/data"}},
+		poolStatus: map[string]*models.PoolStatus{
+			"tank": {Name: "tank", State: "ONLINE", ErrorCount: "0"},
+		},
+		snapshots: []*models.Snapshot{daily},
+	}
+	op := newMockOperator(cfg, mock)
+
+	// 23:30 UTC on January 25th is already January 26th in Berlin
+	if err := op.RunAt(time.Date(2026, 1, 25, 23, 30, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("RunAt() error = %v", err)
+	}
+
+	if len(mock.createdSnapshots) != 1 {
+		t.Fatalf("Created %d snapshot(s), want 1", len(mock.createdSnapshots))
+	}
+	if got := mock.createdSnapshots[0].SnapshotName; got != "autosnap_2026-01-26_00:30:00_daily" {
+		t.Errorf("Created %s, want autosnap_2026-01-26_00:30:00_daily", got)
+	}
+}
@@ -0,0 +1,44 @@
+package operator
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/zfs"
+)
+
+// isUnchangedSince checks if nothing was written to a dataset since the given snapshot
+// The written property of the dataset covers the time since its newest snapshot, and the written
+// property of every snapshot the time since the snapshot before it, so the dataset is unchanged
+// if both are zero for the dataset and every snapshot newer than since
+// An unknown written property counts as a change
+func isUnchangedSince(pool *models.Pool, inventory *zfs.Inventory, since *models.Snapshot) bool {
+	if !isZeroSize(pool.Written) {
+		return false
+	}
+
+	for _, snapshot := range inventory.All() {
+		if snapshot.FilesystemName != pool.FilesystemName || snapshot.SnapshotName == since.SnapshotName {
+			continue
+		}
+		if isNewerSnapshot(snapshot, since) && (!snapshot.WrittenKnown || snapshot.Written != 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// isNewerSnapshot checks if a was taken after b, by transaction group if both are known
+func isNewerSnapshot(a, b *models.Snapshot) bool {
+	if a.CreateTxg != 0 && b.CreateTxg != 0 {
+		return a.CreateTxg > b.CreateTxg
+	}
+	return a.DateTime.After(b.DateTime)
+}
+
+// isZeroSize checks if a size reported by zfs list (e.g. "0", "0B") is exactly zero
+func isZeroSize(size string) bool {
+	value, err := strconv.ParseFloat(strings.TrimSuffix(size, "B"), 64)
+	return err == nil && value == 0
+}
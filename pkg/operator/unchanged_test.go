@@ -0,0 +1,193 @@
+package operator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+)
+
+func TestSkipUnchanged(t *testing.T) {
+	now := time.Date(2026, 1, 25, 12, 5, 0, 0, time.UTC)
+	// The newest hourly snapshot is older than the retention window of two hours
+	old := hourlySnapshot("tank/data", time.Date(2026, 1, 25, 8, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name          string
+		skipUnchanged []string
+		written       string
+		newer         *models.Snapshot
+		wantCreated   bool
+	}{
+		{
+			name:          "unchanged dataset",
+			skipUnchanged: []string{"hourly"},
+			written:       "0",
+		},
+		{
+			name:          "unchanged since a newer manual snapshot",
+			skipUnchanged: []string{"hourly"},
+			written:       "0B",
+			newer:         &models.Snapshot{PoolName: "tank", FilesystemName: "tank/data", SnapshotName: "manual", DateTime: now.Add(-time.Hour), WrittenKnown: true},
+		},
+		{
+			name:          "written property of a newer snapshot unknown",
+			skipUnchanged: []string{"hourly"},
+			written:       "0",
+			newer:         &models.Snapshot{PoolName: "tank", FilesystemName: "tank/data", SnapshotName: "manual", DateTime: now.Add(-time.Hour)},
+			wantCreated:   true,
+		},
+		{
+			name:          "data written since the newest snapshot",
+			skipUnchanged: []string{"hourly"},
+			written:       "12K",
+			wantCreated:   true,
+		},
+		{
+			name:          "data written before a newer manual snapshot",
+			skipUnchanged: []string{"hourly"},
+			written:       "0",
+			newer:         &models.Snapshot{PoolName: "tank", FilesystemName: "tank/data", SnapshotName: "manual", DateTime: now.Add(-time.Hour), Written: 4096, WrittenKnown: true},
+			wantCreated:   true,
+		},
+		{
+			name:          "written property unknown",
+			skipUnchanged: []string{"hourly"},
+			wantCreated:   true,
+		},
+		{
+			name:          "tier does not skip unchanged datasets",
+			skipUnchanged: []string{"daily"},
+			written:       "0",
+			wantCreated:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := hourlyOnlyConfig()
+			cfg.MaxHourlySnapshots = 2
+			cfg.SkipUnchanged = tt.skipUnchanged
+
+			snapshots := []*models.Snapshot{old}
+			if tt.newer != nil {
+				snapshots = append(snapshots, tt.newer)
+			}
+			pool := &models.Pool{PoolName: "tank", FilesystemName: "tank/data", Written: tt.written}
+			mock := &mockZFSManager{snapshots: snapshots, pools: []*models.Pool{pool}}
+			op := newMockOperator(cfg, mock)
+			loadInventory(t, op)
+
+			// A period skipped for an unchanged dataset does not count as falling behind
+			report := op.checkFreshness([]*models.Pool{pool}, op.inventory, now)
+			if report.OK != !tt.wantCreated {
+				t.Errorf("Freshness report OK = %v, want %v (violations: %+v)", report.OK, !tt.wantCreated, report.Violations)
+			}
+
+			if err := op.processFrequency(pool, "hourly", now); err != nil {
+				t.Fatalf("processFrequency() error = %v", err)
+			}
+
+			if created := len(mock.createdSnapshots) > 0; created != tt.wantCreated {
+				t.Errorf("Created a snapshot = %v, want %v", created, tt.wantCreated)
+			}
+			if !tt.wantCreated && deletedNames(mock)[old.SnapshotName] {
+				t.Errorf("The snapshot %s covering the unchanged dataset must be kept", old.SnapshotName)
+			}
+		})
+	}
+}
+
+// TestSkipUnchangedAfterLowerTierDeletion tests that data written before a snapshot deleted by a lower tier
+// in the same run still counts as a change for a higher tier
+func TestSkipUnchangedAfterLowerTierDeletion(t *testing.T) {
+	cfg := hourlyOnlyConfig()
+	cfg.MaxHourlySnapshots = 2
+	cfg.MaxDailySnapshots = 7
+	cfg.SkipUnchanged = []string{"hourly", "daily"}
+
+	now := time.Date(2026, 1, 25, 12, 5, 0, 0, time.UTC)
+	daily := &models.Snapshot{
+		PoolName:       "tank",
+		FilesystemName: "tank/data",
+		SnapshotName:   "autosnap_2026-01-24_00:00:00_daily",
+		DateTime:       time.Date(2026, 1, 24, 0, 0, 0, 0, time.UTC),
+		Frequency:      "daily",
+		WrittenKnown:   true,
+	}
+	changed := hourlySnapshot("tank/data", time.Date(2026, 1, 24, 0, 30, 0, 0, time.UTC))
+	changed.Written, changed.WrittenKnown = 1<<30, true
+	newest := hourlySnapshot("tank/data", time.Date(2026, 1, 25, 10, 0, 0, 0, time.UTC))
+	newest.WrittenKnown = true
+
+	pool := &models.Pool{PoolName: "tank", FilesystemName: "tank/data", Written: "0"}
+	mock := &mockZFSManager{snapshots: []*models.Snapshot{daily, changed, newest}, pools: []*models.Pool{pool}}
+	op := newMockOperator(cfg, mock)
+	loadInventory(t, op)
+
+	if err := op.processFrequency(pool, "hourly", now); err != nil {
+		t.Fatalf("processFrequency(hourly) error = %v", err)
+	}
+	if !deletedNames(mock)[changed.SnapshotName] {
+		t.Fatalf("Hourly retention did not delete %s", changed.SnapshotName)
+	}
+	if len(mock.createdSnapshots) != 0 {
+		t.Fatalf("Created an hourly snapshot of an unchanged dataset")
+	}
+
+	if err := op.processFrequency(pool, "daily", now); err != nil {
+		t.Fatalf("processFrequency(daily) error = %v", err)
+	}
+	if len(mock.createdSnapshots) != 1 || mock.createdSnapshots[0].Frequency != "daily" {
+		t.Errorf("Created %v, want a daily snapshot for the data written after %s", mock.createdSnapshots, daily.SnapshotName)
+	}
+}
+
+// TestSkipUnchangedAfterLowerTierCreation tests that the snapshot a lower tier creates of an idle dataset
+// in the same run does not count as a change for a higher tier
+func TestSkipUnchangedAfterLowerTierCreation(t *testing.T) {
+	cfg := hourlyOnlyConfig()
+	cfg.MaxDailySnapshots = 7
+	cfg.SkipUnchanged = []string{"daily"}
+
+	daily := &models.Snapshot{
+		PoolName:       "tank",
+		FilesystemName: "tank/data",
+		SnapshotName:   "autosnap_2026-01-24_00:00:00_daily",
+		DateTime:       time.Date(2026, 1, 24, 0, 0, 0, 0, time.UTC),
+		Frequency:      "daily",
+		WrittenKnown:   true,
+	}
+	mock := &mockZFSManager{
+		snapshots: []*models.Snapshot{daily},
+		pools:     []*models.Pool{{PoolName: "tank", FilesystemName: "tank/data", Written: "0"}},
+		poolStatus: map[string]*models.PoolStatus{
+			"tank": {Name: "tank", State: "ONLINE", ErrorCount: "0"},
+		},
+	}
+	op := newMockOperator(cfg, mock)
+
+	if err := op.RunAt(time.Date(2026, 1, 25, 12, 5, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("RunAt() error = %v", err)
+	}
+
+	if len(mock.createdSnapshots) != 1 || mock.createdSnapshots[0].Frequency != "hourly" {
+		t.Errorf("Created %v, want only an hourly snapshot of the idle dataset", mock.createdSnapshots)
+	}
+}
+
+func TestIsZeroSize(t *testing.T) {
+	tests := map[string]bool{
+		"0":    true,
+		"0B":   true,
+		"":     false,
+		"-":    false,
+		"512":  false,
+		"1.5K": false,
+	}
+	for size, want := range tests {
+		if got := isZeroSize(size); got != want {
+			t.Errorf("isZeroSize(%q) = %v, want %v", size, got, want)
+		}
+	}
+}
@@ -3,11 +3,12 @@ package parser
 import (
 	"encoding/json"
 	"fmt"
-	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/naming"
 )
 
 // ZFSSnapshotJSON represents a ZFS snapshot in JSON format
@@ -17,12 +18,17 @@ type ZFSSnapshotJSON struct {
 	Pool         string                 `json:"pool"`
 	Dataset      string                 `json:"dataset"`
 	SnapshotName string                 `json:"snapshot_name"`
+	CreateTxg    string                 `json:"createtxg"`
 	Properties   map[string]ZFSProperty `json:"properties,omitempty"`
 }
 
 // ZFSProperty represents a ZFS property value
 type ZFSProperty struct {
-	Value string `json:"value"`
+	Value  string `json:"value"`
+	Source struct {
+		Type string `json:"type"` // NONE, DEFAULT, LOCAL, INHERITED, RECEIVED, ...
+		Data string `json:"data"` // Parent dataset for inherited values
+	} `json:"source"`
 }
 
 // ZFSDatasetResponse represents the root response from zfs list -j
@@ -36,7 +42,9 @@ type ZFSDatasetResponse struct {
 }
 
 // ParseSnapshotsJSON parses zfs list snapshots JSON output
-func ParseSnapshotsJSON(data []byte, snapshotPrefix string) ([]*models.Snapshot, error) {
+// Frequency and time are read from the snapshot name with the name template;
+// timestamps in names without a UTC offset are interpreted in loc
+func ParseSnapshotsJSON(data []byte, template *naming.Template, loc *time.Location) ([]*models.Snapshot, error) {
 	var response ZFSDatasetResponse
 
 	if err := json.Unmarshal(data, &response); err != nil {
@@ -48,39 +56,146 @@ func ParseSnapshotsJSON(data []byte, snapshotPrefix string) ([]*models.Snapshot,
 		if dataset.Type != "SNAPSHOT" {
 			continue
 		}
+		fillNameFields(&dataset)
 
-		// Extract frequency from snapshot name
-		pattern := regexp.MustCompile(`.*_(yearly|monthly|weekly|daily|hourly|frequently)$`)
-		matches := pattern.FindStringSubmatch(dataset.SnapshotName)
+		// Extract frequency and datetime from snapshot name (e.g. autosnap_2024-01-15_10:00:00_hourly)
+		// Names that do not match the template get an empty frequency and are never touched
+		frequency, nameTime := template.Parse(dataset.SnapshotName, loc)
 
-		frequency := ""
-		if len(matches) > 1 {
-			frequency = matches[1]
+		creationTime := time.Time{}
+		if creationProp, ok := dataset.Properties["creation"]; ok {
+			creationTime = parseCreation(creationProp.Value)
 		}
 
-		// Extract datetime from snapshot name (format: autosnap_2024-01-15_10:00:00_frequency)
-		dateTime := time.Time{}
-		datePattern := regexp.MustCompile(`(\d{4}-\d{2}-\d{2}_\d{2}:\d{2}:\d{2})`)
-		dateMatches := datePattern.FindStringSubmatch(dataset.SnapshotName)
-		if len(dateMatches) > 1 {
-			parsedTime, err := time.Parse("2006-01-02_15:04:05", dateMatches[1])
-			if err == nil {
-				dateTime = parsedTime
-			}
+		createTxg, _ := strconv.ParseUint(dataset.CreateTxg, 10, 64)
+
+		// Snapshots are listed with -p, so the space properties are exact numbers of bytes
+		used := parseBytes(dataset.Properties["used"])
+		referenced := parseBytes(dataset.Properties["referenced"])
+		logicalReferenced := parseBytes(dataset.Properties["logicalreferenced"])
+		writtenProp, writtenKnown := dataset.Properties["written"]
+		written, err := strconv.ParseUint(writtenProp.Value, 10, 64)
+		writtenKnown = writtenKnown && err == nil
+		userRefs, _ := strconv.ParseUint(dataset.Properties["userrefs"].Value, 10, 64)
+
+		// The name time is used by default, the creation time covers renamed snapshots
+		dateTime := nameTime
+		if dateTime.IsZero() {
+			dateTime = creationTime
 		}
 
 		snapshots = append(snapshots, &models.Snapshot{
-			PoolName:       dataset.Pool,
-			FilesystemName: dataset.Dataset,
-			SnapshotName:   dataset.SnapshotName,
-			Frequency:      frequency,
-			DateTime:       dateTime,
+			PoolName:          dataset.Pool,
+			FilesystemName:    dataset.Dataset,
+			SnapshotName:      dataset.SnapshotName,
+			Frequency:         frequency,
+			DateTime:          dateTime,
+			NameTime:          nameTime,
+			CreationTime:      creationTime,
+			CreateTxg:         createTxg,
+			Used:              used,
+			Referenced:        referenced,
+			LogicalReferenced: logicalReferenced,
+			Written:           written,
+			WrittenKnown:      writtenKnown,
+			UserRefs:          userRefs,
+			Clones:            parseClones(dataset.Properties["clones"].Value),
+			DeferDestroy:      dataset.Properties["defer_destroy"].Value == "on",
 		})
 	}
 
 	return snapshots, nil
 }
 
+// parseBytes parses a space property listed with zfs list -p, 0 if it is missing or not a number
+func parseBytes(property ZFSProperty) uint64 {
+	value, _ := strconv.ParseUint(property.Value, 10, 64)
+	return value
+}
+
+// parseClones splits the comma-separated clones property, which is empty or "-" without clones
+func parseClones(value string) []string {
+	var clones []string
+	for _, clone := range strings.Split(value, ",") {
+		if clone != "" && clone != "-" {
+			clones = append(clones, clone)
+		}
+	}
+	return clones
+}
+
+// ParseDestroyDryRun returns the space a zfs destroy -nvp would reclaim from its parsable output
+// (a "destroy<TAB>name" line per snapshot and a final "reclaim<TAB>bytes" line)
+func ParseDestroyDryRun(data []byte) (uint64, error) {
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "reclaim" {
+			reclaim, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid reclaim value %q: %w", fields[1], err)
+			}
+			return reclaim, nil
+		}
+	}
+	return 0, fmt.Errorf("no reclaim line in zfs destroy output")
+}
+
+// ParseHolds parses the output of zfs holds -H -p (a "name<TAB>tag<TAB>timestamp" line per hold)
+func ParseHolds(data []byte) ([]*models.Hold, error) {
+	var holds []*models.Hold
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid hold line %q", line)
+		}
+		filesystemName, snapshotName, ok := strings.Cut(fields[0], "@")
+		if !ok {
+			return nil, fmt.Errorf("invalid snapshot name %q in hold line", fields[0])
+		}
+		seconds, err := strconv.ParseInt(strings.TrimSpace(fields[2]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hold timestamp %q: %w", fields[2], err)
+		}
+		holds = append(holds, &models.Hold{
+			FilesystemName: filesystemName,
+			SnapshotName:   snapshotName,
+			Tag:            fields[1],
+			Time:           time.Unix(seconds, 0),
+		})
+	}
+	return holds, nil
+}
+
+// parseCreation parses the creation property, either as Unix seconds (zfs list -p)
+// or in the human readable format of zfs list (e.g. "Sun Jan 25 12:00 2026", local time)
+func parseCreation(value string) time.Time {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0)
+	}
+	if t, err := time.ParseInLocation("Mon Jan _2 15:04 2006", value, time.Local); err == nil {
+		return t
+	}
+	return time.Time{}
+}
+
+// fillNameFields derives the pool, dataset and snapshot name from the full name if zfs list did not
+// report them, so the parser does not depend on the property set that was requested with -o
+func fillNameFields(dataset *ZFSSnapshotJSON) {
+	datasetName, snapshotName, _ := strings.Cut(dataset.Name, "@")
+	if dataset.Dataset == "" {
+		dataset.Dataset = datasetName
+	}
+	if dataset.SnapshotName == "" {
+		dataset.SnapshotName = snapshotName
+	}
+	if dataset.Pool == "" {
+		dataset.Pool, _, _ = strings.Cut(datasetName, "/")
+	}
+}
+
 // ParsePoolsJSON parses zfs list filesystems JSON output
 func ParsePoolsJSON(data []byte) ([]*models.Pool, error) {
 	var response ZFSDatasetResponse
@@ -94,6 +209,7 @@ func ParsePoolsJSON(data []byte) ([]*models.Pool, error) {
 		if dataset.Type != "FILESYSTEM" {
 			continue
 		}
+		fillNameFields(&dataset)
 
 		// Split the name to get pool and filesystem parts
 		poolName := dataset.Pool
@@ -108,6 +224,7 @@ func ParsePoolsJSON(data []byte) ([]*models.Pool, error) {
 		used := ""
 		avail := ""
 		mountpoint := ""
+		written := ""
 		if dataset.Properties != nil {
 			if usedProp, ok := dataset.Properties["used"]; ok {
 				used = usedProp.Value
@@ -118,6 +235,9 @@ func ParsePoolsJSON(data []byte) ([]*models.Pool, error) {
 			if mountpointProp, ok := dataset.Properties["mountpoint"]; ok {
 				mountpoint = mountpointProp.Value
 			}
+			if writtenProp, ok := dataset.Properties["written"]; ok {
+				written = writtenProp.Value
+			}
 		}
 
 		pools = append(pools, &models.Pool{
@@ -125,13 +245,43 @@ func ParsePoolsJSON(data []byte) ([]*models.Pool, error) {
 			FilesystemName: filesystemName,
 			Used:           used,
 			Avail:          avail,
+			Written:        written,
 			Mountpoint:     mountpoint,
+			Properties:     parseUserProperties(dataset.Name, dataset.Properties),
 		})
 	}
 
 	return pools, nil
 }
 
+// parseUserProperties extracts the user properties (names containing a colon) that have a value
+// Unset user properties are reported by zfs as "-" with source NONE and are skipped
+func parseUserProperties(datasetName string, properties map[string]ZFSProperty) map[string]models.Property {
+	var result map[string]models.Property
+	for name, property := range properties {
+		if !strings.Contains(name, ":") {
+			continue
+		}
+
+		source := ""
+		switch property.Source.Type {
+		case "LOCAL", "RECEIVED":
+			source = datasetName
+		case "INHERITED":
+			source = property.Source.Data
+		}
+		if source == "" || property.Value == "-" {
+			continue
+		}
+
+		if result == nil {
+			result = make(map[string]models.Property)
+		}
+		result[name] = models.Property{Value: property.Value, Source: source}
+	}
+	return result
+}
+
 // ZPoolStatusVdevJSON represents a vdev in the pool
 type ZPoolStatusVdevJSON struct {
 	Name           string                         `json:"name"`
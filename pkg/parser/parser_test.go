@@ -1,9 +1,17 @@
 package parser
 
 import (
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/naming"
 )
 
+var testTemplate = naming.MustCompile(naming.DefaultTemplate, "autosnap",
+	[]string{"frequently", "hourly", "daily", "weekly", "monthly", "yearly"}, false)
+
 func TestParseSnapshotsJSON(t *testing.T) {
 	jsonData := `{
   "output_version": {
@@ -36,7 +44,7 @@ func TestParseSnapshotsJSON(t *testing.T) {
   }
 }`
 
-	snapshots, err := ParseSnapshotsJSON([]byte(jsonData), "autosnap")
+	snapshots, err := ParseSnapshotsJSON([]byte(jsonData), testTemplate, time.UTC)
 	if err != nil {
 		t.Fatalf("ParseSnapshotsJSON() error = %v", err)
 	}
@@ -99,7 +107,7 @@ func TestParseSnapshotsJSON(t *testing.T) {
 func TestParseSnapshotsJSON_InvalidJSON(t *testing.T) {
 	jsonData := `invalid json`
 
-	_, err := ParseSnapshotsJSON([]byte(jsonData), "autosnap")
+	_, err := ParseSnapshotsJSON([]byte(jsonData), testTemplate, time.UTC)
 	if err == nil {
 		t.Error("ParseSnapshotsJSON() expected error for invalid JSON, got nil")
 	}
@@ -153,7 +161,7 @@ func TestParseSnapshotsJSON_InvalidSnapshotFormat(t *testing.T) {
   }
 }`
 
-	snapshots, err := ParseSnapshotsJSON([]byte(jsonData), "autosnap")
+	snapshots, err := ParseSnapshotsJSON([]byte(jsonData), testTemplate, time.UTC)
 	if err != nil {
 		t.Fatalf("ParseSnapshotsJSON() error = %v", err)
 	}
@@ -257,6 +265,168 @@ func TestParsePoolsJSON(t *testing.T) {
 	}
 }
 
+func TestParsePoolsJSON_UserProperties(t *testing.T) {
+	jsonData := `{
+  "output_version": {"command": "zfs list", "vers_major": 0, "vers_minor": 1},
+  "datasets": {
+    "tank/data": {
+      "name": "tank/data",
+      "type": "FILESYSTEM",
+      "pool": "tank",
+      "properties": {
+        "used": {"value": "1G", "source": {"type": "NONE", "data": "-"}},
+        "written": {"value": "0B", "source": {"type": "NONE", "data": "-"}},
+        "com.sun:auto-snapshot": {"value": "false", "source": {"type": "INHERITED", "data": "tank"}},
+        "com.sun:auto-snapshot:hourly": {"value": "true", "source": {"type": "LOCAL", "data": "-"}},
+        "zfs-snapshot-operator:daily": {"value": "-", "source": {"type": "NONE", "data": "-"}}
+      }
+    }
+  }
+}`
+
+	pools, err := ParsePoolsJSON([]byte(jsonData))
+	if err != nil {
+		t.Fatalf("ParsePoolsJSON() error = %v", err)
+	}
+	if len(pools) != 1 {
+		t.Fatalf("ParsePoolsJSON() returned %d pools, want 1", len(pools))
+	}
+
+	want := map[string]models.Property{
+		"com.sun:auto-snapshot":        {Value: "false", Source: "tank"},
+		"com.sun:auto-snapshot:hourly": {Value: "true", Source: "tank/data"},
+	}
+	if !reflect.DeepEqual(pools[0].Properties, want) {
+		t.Errorf("Properties = %v, want %v", pools[0].Properties, want)
+	}
+	if pools[0].Written != "0B" {
+		t.Errorf("Written = %q, want 0B", pools[0].Written)
+	}
+}
+
+func TestParseSnapshotsJSON_NameOnly(t *testing.T) {
+	// zfs list -o name,creation does not need to report the pool, dataset or snapshot_name fields
+	jsonData := `{
+  "output_version": {"command": "zfs list", "vers_major": 0, "vers_minor": 1},
+  "datasets": {
+    "tank/data@autosnap_2026-01-25_12:00:00_hourly": {
+      "name": "tank/data@autosnap_2026-01-25_12:00:00_hourly",
+      "type": "SNAPSHOT",
+      "properties": {
+        "creation": {"value": "1769342400", "source": {"type": "NONE", "data": "-"}}
+      }
+    }
+  }
+}`
+
+	snapshots, err := ParseSnapshotsJSON([]byte(jsonData), testTemplate, time.UTC)
+	if err != nil {
+		t.Fatalf("ParseSnapshotsJSON() error = %v", err)
+	}
+	if len(snapshots) != 1 {
+		t.Fatalf("ParseSnapshotsJSON() returned %d snapshots, want 1", len(snapshots))
+	}
+
+	snapshot := snapshots[0]
+	if snapshot.PoolName != "tank" || snapshot.FilesystemName != "tank/data" ||
+		snapshot.SnapshotName != "autosnap_2026-01-25_12:00:00_hourly" || snapshot.Frequency != "hourly" {
+		t.Errorf("ParseSnapshotsJSON() = %+v", snapshot)
+	}
+}
+
+func TestParseSnapshotsJSON_CreationTime(t *testing.T) {
+	jsonData := `{
+  "output_version": {"command": "zfs list", "vers_major": 0, "vers_minor": 1},
+  "datasets": {
+    "tank/data@autosnap_2026-01-25_12:00:00_hourly": {
+      "name": "tank/data@autosnap_2026-01-25_12:00:00_hourly",
+      "type": "SNAPSHOT",
+      "createtxg": "4711",
+      "properties": {
+        "creation": {"value": "1769342400", "source": {"type": "NONE", "data": "-"}},
+        "used": {"value": "1048576", "source": {"type": "NONE", "data": "-"}},
+        "referenced": {"value": "8388608", "source": {"type": "NONE", "data": "-"}},
+        "logicalreferenced": {"value": "16777216", "source": {"type": "NONE", "data": "-"}},
+        "written": {"value": "4096", "source": {"type": "NONE", "data": "-"}}
+      }
+    },
+    "tank/data@renamed_hourly": {
+      "name": "tank/data@renamed_hourly",
+      "type": "SNAPSHOT",
+      "properties": {
+        "creation": {"value": "1769346000", "source": {"type": "NONE", "data": "-"}}
+      }
+    }
+  }
+}`
+
+	snapshots, err := ParseSnapshotsJSON([]byte(jsonData), testTemplate, time.UTC)
+	if err != nil {
+		t.Fatalf("ParseSnapshotsJSON() error = %v", err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("ParseSnapshotsJSON() returned %d snapshots, want 2", len(snapshots))
+	}
+	byName := make(map[string]*models.Snapshot)
+	for _, s := range snapshots {
+		byName[s.SnapshotName] = s
+	}
+
+	snapshot := byName["autosnap_2026-01-25_12:00:00_hourly"]
+	if !snapshot.CreationTime.Equal(time.Unix(1769342400, 0)) {
+		t.Errorf("CreationTime = %v, want %v", snapshot.CreationTime, time.Unix(1769342400, 0))
+	}
+	if want := time.Date(2026, 1, 25, 12, 0, 0, 0, time.UTC); !snapshot.NameTime.Equal(want) {
+		t.Errorf("NameTime = %v, want %v", snapshot.NameTime, want)
+	}
+	if !snapshot.DateTime.Equal(snapshot.NameTime) {
+		t.Errorf("DateTime = %v, want the name time %v", snapshot.DateTime, snapshot.NameTime)
+	}
+	if snapshot.CreateTxg != 4711 {
+		t.Errorf("CreateTxg = %d, want 4711", snapshot.CreateTxg)
+	}
+	if snapshot.Used != 1048576 || snapshot.Written != 4096 {
+		t.Errorf("Used = %d, Written = %d, want 1048576 and 4096", snapshot.Used, snapshot.Written)
+	}
+	if !snapshot.WrittenKnown {
+		t.Error("WrittenKnown = false, want true")
+	}
+	if snapshot.Referenced != 8388608 || snapshot.LogicalReferenced != 16777216 {
+		t.Errorf("Referenced = %d, LogicalReferenced = %d, want 8388608 and 16777216", snapshot.Referenced, snapshot.LogicalReferenced)
+	}
+
+	// A renamed snapshot has no timestamp in its name and falls back to the creation time
+	renamed := byName["renamed_hourly"]
+	if renamed.WrittenKnown {
+		t.Error("renamed snapshot WrittenKnown = true, want false without a written property")
+	}
+	if !renamed.NameTime.IsZero() || !renamed.DateTime.Equal(time.Unix(1769346000, 0)) {
+		t.Errorf("renamed snapshot NameTime = %v, DateTime = %v, want zero and %v",
+			renamed.NameTime, renamed.DateTime, time.Unix(1769346000, 0))
+	}
+}
+
+func TestParseCreation(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"1769342400", time.Unix(1769342400, 0)},
+		{"Sun Jan 25 12:00 2026", time.Date(2026, 1, 25, 12, 0, 0, 0, time.Local)},
+		{"Mon Feb  2 09:30 2026", time.Date(2026, 2, 2, 9, 30, 0, 0, time.Local)},
+		{"-", time.Time{}},
+		{"", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := parseCreation(tt.value); !got.Equal(tt.want) {
+				t.Errorf("parseCreation(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestParsePoolsJSON_InvalidJSON(t *testing.T) {
 	jsonData := `invalid json`
 
@@ -529,3 +699,142 @@ func TestParsePoolStatusJSON_WithScrub(t *testing.T) {
 		t.Error("inprogress pool not found in status map")
 	}
 }
+
+func TestParseDestroyDryRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    uint64
+		wantErr bool
+	}{
+		{
+			name:   "several snapshots",
+			output: "destroy\ttank/data@a\ndestroy\ttank/data@b\nreclaim\t1073741824\n",
+			want:   1073741824,
+		},
+		{
+			name:   "nothing to reclaim",
+			output: "destroy\ttank/data@a\nreclaim\t0\n",
+			want:   0,
+		},
+		{
+			name:    "human readable output",
+			output:  "would destroy tank/data@a\nwould reclaim 1.00G\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid value",
+			output:  "reclaim\t1G\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDestroyDryRun([]byte(tt.output))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDestroyDryRun() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDestroyDryRun() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSnapshotsJSON_HoldsAndClones(t *testing.T) {
+	jsonData := `{
+  "output_version": {"command": "zfs list", "vers_major": 0, "vers_minor": 1},
+  "datasets": {
+    "tank/data@autosnap_2026-01-25_12:00:00_hourly": {
+      "name": "tank/data@autosnap_2026-01-25_12:00:00_hourly",
+      "type": "SNAPSHOT",
+      "properties": {
+        "userrefs": {"value": "2", "source": {"type": "NONE", "data": "-"}},
+        "clones": {"value": "tank/clone1,tank/clone2", "source": {"type": "NONE", "data": "-"}},
+        "defer_destroy": {"value": "on", "source": {"type": "NONE", "data": "-"}}
+      }
+    },
+    "tank/data@autosnap_2026-01-25_13:00:00_hourly": {
+      "name": "tank/data@autosnap_2026-01-25_13:00:00_hourly",
+      "type": "SNAPSHOT",
+      "properties": {
+        "userrefs": {"value": "0", "source": {"type": "NONE", "data": "-"}},
+        "clones": {"value": "", "source": {"type": "NONE", "data": "-"}},
+        "defer_destroy": {"value": "off", "source": {"type": "NONE", "data": "-"}}
+      }
+    }
+  }
+}`
+
+	snapshots, err := ParseSnapshotsJSON([]byte(jsonData), testTemplate, time.UTC)
+	if err != nil {
+		t.Fatalf("ParseSnapshotsJSON() error = %v", err)
+	}
+	byName := make(map[string]*models.Snapshot)
+	for _, s := range snapshots {
+		byName[s.SnapshotName] = s
+	}
+
+	held := byName["autosnap_2026-01-25_12:00:00_hourly"]
+	if held.UserRefs != 2 || !reflect.DeepEqual(held.Clones, []string{"tank/clone1", "tank/clone2"}) || !held.DeferDestroy {
+		t.Errorf("Held snapshot = UserRefs %d, Clones %v, DeferDestroy %t", held.UserRefs, held.Clones, held.DeferDestroy)
+	}
+	free := byName["autosnap_2026-01-25_13:00:00_hourly"]
+	if free.UserRefs != 0 || len(free.Clones) != 0 || free.DeferDestroy {
+		t.Errorf("Free snapshot = UserRefs %d, Clones %v, DeferDestroy %t", free.UserRefs, free.Clones, free.DeferDestroy)
+	}
+}
+
+func TestParseHolds(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    []models.Hold
+		wantErr bool
+	}{
+		{
+			name: "several holds",
+			output: "tank/data@autosnap_2026-01-25_12:00:00_hourly\tzfs-snapshot-operator:replication\t1769342400\n" +
+				"tank/data@autosnap_2026-01-25_12:00:00_hourly\tbackup tool\t1769346000\n",
+			want: []models.Hold{
+				{FilesystemName: "tank/data", SnapshotName: "autosnap_2026-01-25_12:00:00_hourly", Tag: "zfs-snapshot-operator:replication", Time: time.Unix(1769342400, 0)},
+				{FilesystemName: "tank/data", SnapshotName: "autosnap_2026-01-25_12:00:00_hourly", Tag: "backup tool", Time: time.Unix(1769346000, 0)},
+			},
+		},
+		{
+			name:   "no holds",
+			output: "",
+		},
+		{
+			name:    "human readable timestamp",
+			output:  "tank/data@a\tkeep\tSun Jan 25 12:00 2026\n",
+			wantErr: true,
+		},
+		{
+			name:    "missing tag",
+			output:  "tank/data@a\t1769342400\n",
+			wantErr: true,
+		},
+		{
+			name:    "not a snapshot",
+			output:  "tank/data\tkeep\t1769342400\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHolds([]byte(tt.output))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHolds() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseHolds() returned %d hold(s), want %d", len(got), len(tt.want))
+			}
+			for i, hold := range got {
+				if *hold != tt.want[i] {
+					t.Errorf("hold %d = %+v, want %+v", i, *hold, tt.want[i])
+				}
+			}
+		})
+	}
+}
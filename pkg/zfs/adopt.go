@@ -0,0 +1,35 @@
+package zfs
+
+import (
+	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/naming"
+)
+
+// recognizeToolSnapshots marks snapshots that do not match the name template but were created
+// by a known third-party tool
+// Snapshots of adopted tools get the frequency and time of their name and are managed like own
+// snapshots; the others keep an empty frequency, so retention never touches them
+func recognizeToolSnapshots(snapshots []*models.Snapshot, adopted func(tool string) bool, loc *time.Location) {
+	tools := naming.Tools()
+	for _, snapshot := range snapshots {
+		if snapshot.Frequency != "" {
+			continue
+		}
+
+		for _, tool := range tools {
+			frequency, nameTime := tool.Parse(snapshot.SnapshotName, loc)
+			if frequency == "" {
+				continue
+			}
+
+			snapshot.Tool = tool.Name
+			if adopted(tool.Name) {
+				snapshot.Frequency = frequency
+				snapshot.NameTime = nameTime
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package zfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/config"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+)
+
+func TestRecognizeToolSnapshots(t *testing.T) {
+	own := &models.Snapshot{SnapshotName: "autosnap_2026-01-25_14:00:00_hourly", Frequency: "hourly"}
+	sanoid := &models.Snapshot{SnapshotName: "autosnap_2026-01-25_14:00:00_hourly"}
+	zfsAutoSnap := &models.Snapshot{SnapshotName: "zfs-auto-snap_frequent-2026-01-25-1415"}
+	manual := &models.Snapshot{SnapshotName: "before-upgrade"}
+
+	adopted := func(tool string) bool { return tool == "zfs-auto-snapshot" }
+	recognizeToolSnapshots([]*models.Snapshot{own, sanoid, zfsAutoSnap, manual}, adopted, time.UTC)
+
+	if own.Tool != "" {
+		t.Errorf("own snapshot Tool = %q, want empty", own.Tool)
+	}
+	if sanoid.Tool != "sanoid" || sanoid.Frequency != "" {
+		t.Errorf("sanoid snapshot Tool = %q, Frequency = %q, want sanoid and empty (not adopted)", sanoid.Tool, sanoid.Frequency)
+	}
+	wantTime := time.Date(2026, 1, 25, 14, 15, 0, 0, time.UTC)
+	if zfsAutoSnap.Tool != "zfs-auto-snapshot" || zfsAutoSnap.Frequency != "frequently" || !zfsAutoSnap.NameTime.Equal(wantTime) {
+		t.Errorf("zfs-auto-snapshot snapshot = %+v, want adopted as frequently at %v", zfsAutoSnap, wantTime)
+	}
+	if manual.Tool != "" || manual.Frequency != "" {
+		t.Errorf("manual snapshot Tool = %q, Frequency = %q, want both empty", manual.Tool, manual.Frequency)
+	}
+}
+
+func TestGetSnapshotsAdopted(t *testing.T) {
+	listing := `{
+  "output_version": {"command": "zfs list", "vers_major": 0, "vers_minor": 1},
+  "datasets": {
+    "tank/data@zfs-auto-snap_daily-2025-06-01-0000": {
+      "name": "tank/data@zfs-auto-snap_daily-2025-06-01-0000",
+      "type": "SNAPSHOT"
+    },
+    "tank/data@autosnap_2026-01-25_00:00:00_daily": {
+      "name": "tank/data@autosnap_2026-01-25_00:00:00_daily",
+      "type": "SNAPSHOT"
+    }
+  }
+}`
+	path := filepath.Join(t.TempDir(), "snapshots.json")
+	if err := os.WriteFile(path, []byte(listing), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		adopt     []string
+		wantDaily int
+	}{
+		{"not adopted", nil, 1},
+		{"adopted", []string{"zfs-auto-snapshot"}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewConfig("test")
+			cfg.ZFSListSnapshotsCmd = []string{"cat", path}
+			cfg.AdoptSnapshots = tt.adopt
+
+			snapshots, err := NewManager(cfg).GetSnapshots("", "tank/data", "daily")
+			if err != nil {
+				t.Fatalf("GetSnapshots() error = %v", err)
+			}
+			if len(snapshots) != tt.wantDaily {
+				t.Errorf("GetSnapshots() returned %d daily snapshot(s), want %d", len(snapshots), tt.wantDaily)
+			}
+		})
+	}
+}
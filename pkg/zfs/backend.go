@@ -0,0 +1,111 @@
+package zfs
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+)
+
+// Backend is the set of ZFS operations the operator depends on.
+// Manager implements it by shelling out to the zfs and zpool binaries;
+// alternative implementations can be injected via operator.NewOperatorWithBackend.
+// Further operations are optional interfaces (BatchCreator, ReclaimEstimator, HoldManager,
+// PoolStatusReader), which the operator detects with a type assertion and falls back on if missing.
+type Backend interface {
+	GetVersion() (string, string, error)
+	GetPools() ([]*models.Pool, error)
+	GetSnapshots(poolName, filesystemName, frequency string) ([]*models.Snapshot, error)
+	CreateSnapshot(snapshot *models.Snapshot) error
+	DeleteSnapshot(snapshot *models.Snapshot) error
+}
+
+// BatchCreator is implemented by backends that create several snapshots atomically
+type BatchCreator interface {
+	CreateSnapshots(snapshots []*models.Snapshot) error
+}
+
+// ReclaimEstimator is implemented by backends that estimate the space destroying snapshots frees
+type ReclaimEstimator interface {
+	EstimateReclaim(snapshots []*models.Snapshot) (uint64, error)
+}
+
+// HoldManager is implemented by backends that support user holds on snapshots
+type HoldManager interface {
+	HoldSnapshot(snapshot *models.Snapshot, tag string) error
+	ReleaseSnapshot(snapshot *models.Snapshot, tag string) error
+	GetHolds(snapshots []*models.Snapshot) ([]*models.Hold, error)
+}
+
+// PoolStatusReader is implemented by backends that report the health of their pools
+type PoolStatusReader interface {
+	GetPoolStatus() (map[string]*models.PoolStatus, error)
+}
+
+// ErrHoldsUnsupported is returned for hold operations on a backend that is not a HoldManager
+var ErrHoldsUnsupported = errors.New("backend does not support holds")
+
+// CreateSnapshots creates several snapshots, atomically if the backend is a BatchCreator
+// Otherwise they are created one by one, stopping at the first failure
+func CreateSnapshots(backend Backend, snapshots []*models.Snapshot) error {
+	if creator, ok := backend.(BatchCreator); ok {
+		return creator.CreateSnapshots(snapshots)
+	}
+	for _, snapshot := range snapshots {
+		if err := backend.CreateSnapshot(snapshot); err != nil {
+			return fmt.Errorf("failed to create snapshot %s: %w", snapshotPath(snapshot), err)
+		}
+	}
+	return nil
+}
+
+// EstimateReclaim estimates the space destroying snapshots together frees
+// Without a ReclaimEstimator it sums the used property of the snapshots, which leaves out
+// the space that is shared by several of them
+func EstimateReclaim(backend Backend, snapshots []*models.Snapshot) (uint64, error) {
+	if estimator, ok := backend.(ReclaimEstimator); ok {
+		return estimator.EstimateReclaim(snapshots)
+	}
+	var reclaim uint64
+	for _, snapshot := range snapshots {
+		reclaim += snapshot.Used
+	}
+	return reclaim, nil
+}
+
+// HoldSnapshot places a user hold on a snapshot
+func HoldSnapshot(backend Backend, snapshot *models.Snapshot, tag string) error {
+	if manager, ok := backend.(HoldManager); ok {
+		return manager.HoldSnapshot(snapshot, tag)
+	}
+	return ErrHoldsUnsupported
+}
+
+// ReleaseSnapshot releases a user hold from a snapshot
+func ReleaseSnapshot(backend Backend, snapshot *models.Snapshot, tag string) error {
+	if manager, ok := backend.(HoldManager); ok {
+		return manager.ReleaseSnapshot(snapshot, tag)
+	}
+	return ErrHoldsUnsupported
+}
+
+// GetHolds lists the user holds of snapshots; a backend without holds has none
+func GetHolds(backend Backend, snapshots []*models.Snapshot) ([]*models.Hold, error) {
+	if manager, ok := backend.(HoldManager); ok {
+		return manager.GetHolds(snapshots)
+	}
+	return nil, nil
+}
+
+// GetPoolStatus returns the health of the pools
+// A backend that is not a PoolStatusReader has every one of the given pools taken as healthy
+func GetPoolStatus(backend Backend, pools []*models.Pool) (map[string]*models.PoolStatus, error) {
+	if reader, ok := backend.(PoolStatusReader); ok {
+		return reader.GetPoolStatus()
+	}
+	poolStatus := make(map[string]*models.PoolStatus)
+	for _, pool := range pools {
+		poolStatus[pool.PoolName] = &models.PoolStatus{Name: pool.PoolName, State: "ONLINE", ErrorCount: "0"}
+	}
+	return poolStatus, nil
+}
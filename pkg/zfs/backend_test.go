@@ -0,0 +1,60 @@
+package zfs
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+)
+
+// coreBackend hides the optional interfaces of a backend
+type coreBackend struct {
+	Backend
+}
+
+func TestOptionalInterfaceFallbacks(t *testing.T) {
+	simulated := NewSimulatedBackend(
+		[]*models.Pool{
+			{PoolName: "tank", FilesystemName: "tank/db"},
+			{PoolName: "tank", FilesystemName: "tank/db/wal"},
+		},
+		nil,
+		nil,
+	)
+	backend := coreBackend{simulated}
+
+	snapshots := []*models.Snapshot{
+		{PoolName: "tank", FilesystemName: "tank/db", SnapshotName: "autosnap_2026-01-25_12:00:00_hourly", DateTime: time.Date(2026, 1, 25, 12, 0, 0, 0, time.UTC), Used: 1024},
+		{PoolName: "tank", FilesystemName: "tank/db/wal", SnapshotName: "autosnap_2026-01-25_12:00:00_hourly", DateTime: time.Date(2026, 1, 25, 12, 0, 0, 0, time.UTC), Used: 2048},
+	}
+	if err := CreateSnapshots(backend, snapshots); err != nil {
+		t.Fatalf("CreateSnapshots() error = %v", err)
+	}
+	if all, _ := simulated.GetSnapshots("", "", ""); len(all) != 2 {
+		t.Errorf("CreateSnapshots() created %d snapshot(s), want 2", len(all))
+	}
+
+	if reclaim, err := EstimateReclaim(backend, snapshots); err != nil || reclaim != 3072 {
+		t.Errorf("EstimateReclaim() = %d, %v, want the summed used 3072", reclaim, err)
+	}
+
+	if holds, err := GetHolds(backend, snapshots); err != nil || len(holds) != 0 {
+		t.Errorf("GetHolds() = %v, %v, want no holds", holds, err)
+	}
+	if err := HoldSnapshot(backend, snapshots[0], "zfs-snapshot-operator:test"); !errors.Is(err, ErrHoldsUnsupported) {
+		t.Errorf("HoldSnapshot() error = %v, want %v", err, ErrHoldsUnsupported)
+	}
+	if err := ReleaseSnapshot(backend, snapshots[0], "zfs-snapshot-operator:test"); !errors.Is(err, ErrHoldsUnsupported) {
+		t.Errorf("ReleaseSnapshot() error = %v, want %v", err, ErrHoldsUnsupported)
+	}
+
+	pools, _ := backend.GetPools()
+	poolStatus, err := GetPoolStatus(backend, pools)
+	if err != nil {
+		t.Fatalf("GetPoolStatus() error = %v", err)
+	}
+	if !IsPoolHealthy("tank", poolStatus) {
+		t.Errorf("GetPoolStatus() = %+v, want tank taken as healthy", poolStatus["tank"])
+	}
+}
@@ -0,0 +1,129 @@
+package zfs
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/config"
+	"k8s.io/klog/v2"
+)
+
+// poolListProperties are the properties GetPools requests from zfs list
+var poolListProperties = []string{"name", "used", "avail", "refer", "mountpoint", "written"}
+
+// snapshotListProperties are the properties GetSnapshots requests from zfs list
+var snapshotListProperties = []string{"name", "used", "referenced", "logicalreferenced", "creation", "createtxg", "userrefs", "clones", "defer_destroy", "written"}
+
+// listPoolsArgs returns the arguments appended to ZFSListPoolsCmd
+// Only the parsed properties are requested, and with an exact pool whitelist only those pools are listed
+func (m *Manager) listPoolsArgs() []string {
+	columns := append([]string{}, poolListProperties...)
+	if m.config.HonorUserProperties {
+		columns = append(columns, m.config.UserProperties()...)
+	}
+
+	args := []string{"-t", "filesystem", "-o", strings.Join(columns, ",")}
+	if roots, _, ok := listRoots(m.config.PoolWhitelist); ok {
+		args = append(append(args, "-r"), roots...)
+	}
+	return args
+}
+
+// listSnapshotsArgs returns the arguments appended to ZFSListSnapshotsCmd
+// If the filesystem whitelist only names datasets (or dataset trees), only their snapshots are listed
+// with -d 1 (or -r); otherwise an exact pool whitelist limits the listing to those pools
+func (m *Manager) listSnapshotsArgs() []string {
+	// -p reports the creation time as Unix seconds
+	args := []string{"-p", "-o", strings.Join(snapshotListProperties, ",")}
+
+	if roots, recursive, ok := listRoots(m.config.FilesystemWhitelist); ok {
+		if recursive {
+			args = append(args, "-r")
+		} else {
+			args = append(args, "-d", "1")
+		}
+		return append(args, roots...)
+	}
+
+	if roots, _, ok := listRoots(m.config.PoolWhitelist); ok {
+		args = append(append(args, "-r"), roots...)
+	}
+	return args
+}
+
+// listRoots converts a whitelist into zfs list arguments
+// ok is false if the whitelist is empty or contains globs or regexes, which zfs list cannot evaluate
+func listRoots(whitelist []string) (roots []string, recursive bool, ok bool) {
+	if len(whitelist) == 0 {
+		return nil, false, false
+	}
+
+	for _, pattern := range whitelist {
+		root, patternRecursive, ok := config.PatternRoot(pattern)
+		if !ok {
+			return nil, false, false
+		}
+		roots = append(roots, root)
+		recursive = recursive || patternRecursive
+	}
+	return roots, recursive, true
+}
+
+// scopedCommand appends the scoping arguments to a list command
+// In test and simulate mode the commands read fixture files and are used unchanged
+func (m *Manager) scopedCommand(cmdArgs []string, args []string) []string {
+	if m.config.Mode == "test" || m.config.Mode == "simulate" {
+		return cmdArgs
+	}
+	return append(append([]string{}, cmdArgs...), args...)
+}
+
+// missingDatasetPattern matches the error zfs list reports for an operand that does not exist
+var missingDatasetPattern = regexp.MustCompile(`^cannot open '([^']+)': dataset does not exist$`)
+
+// runList runs a zfs list command and returns its standard output
+// zfs list still lists the other operands if a whitelisted root does not exist (e.g. a stale
+// whitelist entry or an exported pool), so such roots are skipped with a warning instead of failing the run
+func (m *Manager) runList(cmdArgs []string) ([]byte, error) {
+	m.logCommand(cmdArgs)
+	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	exitCode := 0
+	if err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			exitCode = exitError.ExitCode()
+		}
+	}
+	m.logCommandResult(exitCode, output, stderr.Bytes())
+
+	if err != nil {
+		missing, ok := missingDatasets(stderr.String())
+		if !ok {
+			return nil, fmt.Errorf("command failed: %w, output: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		klog.Warningf(" Skipping whitelisted dataset(s) that do not exist: %s", strings.Join(missing, ", "))
+	}
+	return output, nil
+}
+
+// missingDatasets returns the datasets zfs list could not find
+// ok is false if the output contains any other error
+func missingDatasets(stderr string) (missing []string, ok bool) {
+	for _, line := range strings.Split(stderr, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		match := missingDatasetPattern.FindStringSubmatch(line)
+		if match == nil {
+			return nil, false
+		}
+		missing = append(missing, match[1])
+	}
+	return missing, len(missing) > 0
+}
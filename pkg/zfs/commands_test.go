@@ -0,0 +1,169 @@
+package zfs
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/config"
+)
+
+func TestListCommands(t *testing.T) {
+	tests := []struct {
+		name             string
+		poolWhitelist    []string
+		fsWhitelist      []string
+		honorProperties  bool
+		wantPoolsCmd     string
+		wantSnapshotsCmd string
+	}{
+		{
+			name:             "no whitelists",
+			wantPoolsCmd:     "zfs list -j -t filesystem -o name,used,avail,refer,mountpoint,written",
+			wantSnapshotsCmd: "zfs list -j -t snapshot -p -o name,used,referenced,logicalreferenced,creation,createtxg,userrefs,clones,defer_destroy,written",
+		},
+		{
+			name:             "exact pool whitelist",
+			poolWhitelist:    []string{"tank", "backup"},
+			wantPoolsCmd:     "zfs list -j -t filesystem -o name,used,avail,refer,mountpoint,written -r tank backup",
+			wantSnapshotsCmd: "zfs list -j -t snapshot -p -o name,used,referenced,logicalreferenced,creation,createtxg,userrefs,clones,defer_destroy,written -r tank backup",
+		},
+		{
+			name:             "glob pool whitelist lists everything",
+			poolWhitelist:    []string{"tank*"},
+			wantPoolsCmd:     "zfs list -j -t filesystem -o name,used,avail,refer,mountpoint,written",
+			wantSnapshotsCmd: "zfs list -j -t snapshot -p -o name,used,referenced,logicalreferenced,creation,createtxg,userrefs,clones,defer_destroy,written",
+		},
+		{
+			name:             "exact filesystem whitelist",
+			poolWhitelist:    []string{"tank"},
+			fsWhitelist:      []string{"tank/data", "tank/media"},
+			wantPoolsCmd:     "zfs list -j -t filesystem -o name,used,avail,refer,mountpoint,written -r tank",
+			wantSnapshotsCmd: "zfs list -j -t snapshot -p -o name,used,referenced,logicalreferenced,creation,createtxg,userrefs,clones,defer_destroy,written -d 1 tank/data tank/media",
+		},
+		{
+			name:             "recursive filesystem whitelist",
+			fsWhitelist:      []string{"tank/data", "tank/home/**"},
+			wantPoolsCmd:     "zfs list -j -t filesystem -o name,used,avail,refer,mountpoint,written",
+			wantSnapshotsCmd: "zfs list -j -t snapshot -p -o name,used,referenced,logicalreferenced,creation,createtxg,userrefs,clones,defer_destroy,written -r tank/data tank/home",
+		},
+		{
+			name:             "regex filesystem whitelist falls back to pool whitelist",
+			poolWhitelist:    []string{"tank"},
+			fsWhitelist:      []string{"regex:^tank/home/"},
+			wantPoolsCmd:     "zfs list -j -t filesystem -o name,used,avail,refer,mountpoint,written -r tank",
+			wantSnapshotsCmd: "zfs list -j -t snapshot -p -o name,used,referenced,logicalreferenced,creation,createtxg,userrefs,clones,defer_destroy,written -r tank",
+		},
+		{
+			name:             "user properties",
+			honorProperties:  true,
+			wantPoolsCmd:     "zfs list -j -t filesystem -o name,used,avail,refer,mountpoint,written,com.sun:auto-snapshot,",
+			wantSnapshotsCmd: "zfs list -j -t snapshot -p -o name,used,referenced,logicalreferenced,creation,createtxg,userrefs,clones,defer_destroy,written",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewConfig("direct")
+			cfg.PoolWhitelist = tt.poolWhitelist
+			cfg.FilesystemWhitelist = tt.fsWhitelist
+			cfg.HonorUserProperties = tt.honorProperties
+			manager := NewManager(cfg)
+
+			poolsCmd := strings.Join(manager.scopedCommand(cfg.ZFSListPoolsCmd, manager.listPoolsArgs()), " ")
+			if tt.honorProperties {
+				if !strings.HasPrefix(poolsCmd, tt.wantPoolsCmd) {
+					t.Errorf("pools command = %q, want prefix %q", poolsCmd, tt.wantPoolsCmd)
+				}
+			} else if poolsCmd != tt.wantPoolsCmd {
+				t.Errorf("pools command = %q, want %q", poolsCmd, tt.wantPoolsCmd)
+			}
+
+			snapshotsCmd := strings.Join(manager.scopedCommand(cfg.ZFSListSnapshotsCmd, manager.listSnapshotsArgs()), " ")
+			if snapshotsCmd != tt.wantSnapshotsCmd {
+				t.Errorf("snapshots command = %q, want %q", snapshotsCmd, tt.wantSnapshotsCmd)
+			}
+		})
+	}
+}
+
+func TestListCommandsTestMode(t *testing.T) {
+	cfg := config.NewConfig("test")
+	cfg.PoolWhitelist = []string{"tank"}
+	manager := NewManager(cfg)
+
+	cmd := manager.scopedCommand(cfg.ZFSListPoolsCmd, manager.listPoolsArgs())
+	if strings.Join(cmd, " ") != "cat test/zfs_list_pools.json" {
+		t.Errorf("test mode command = %v, want the fixture command unchanged", cmd)
+	}
+}
+
+func TestListMissingRoot(t *testing.T) {
+	// Other tests read the fixtures relative to the package directory
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd() error = %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	if err := changeToProjectRoot(); err != nil {
+		t.Skipf("Could not change to project root: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		stdout    string
+		stderr    string
+		exitCode  int
+		wantPools int
+		wantErr   bool
+	}{
+		{
+			name:      "missing whitelisted root",
+			stdout:    "test/zfs_list_pools.json",
+			stderr:    "cannot open 'gone': dataset does not exist",
+			exitCode:  1,
+			wantPools: 4,
+		},
+		{
+			name:     "all whitelisted roots missing",
+			stderr:   "cannot open 'gone': dataset does not exist\ncannot open 'exported': dataset does not exist",
+			exitCode: 1,
+		},
+		{
+			name:     "other error",
+			stdout:   "test/zfs_list_pools.json",
+			stderr:   "cannot open 'gone': dataset does not exist\ninternal error: out of memory",
+			exitCode: 1,
+			wantErr:  true,
+		},
+		{
+			name:      "warnings are not parsed as JSON",
+			stdout:    "test/zfs_list_pools.json",
+			stderr:    "some warning",
+			wantPools: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := fmt.Sprintf(`printf '%%s\n' "$1" >&2; exit %d`, tt.exitCode)
+			if tt.stdout != "" {
+				script = "cat " + tt.stdout + "; " + script
+			}
+			cfg := config.NewConfig("direct")
+			cfg.PoolWhitelist = []string{"usbstorage", "gone"}
+			// The list arguments are appended after the stderr text
+			cfg.ZFSListPoolsCmd = []string{"sh", "-c", script, "sh", tt.stderr}
+			manager := NewManager(cfg)
+
+			pools, err := manager.GetPools()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetPools() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(pools) != tt.wantPools {
+				t.Errorf("GetPools() returned %d pool(s), want %d", len(pools), tt.wantPools)
+			}
+		})
+	}
+}
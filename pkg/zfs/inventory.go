@@ -0,0 +1,128 @@
+package zfs
+
+import (
+	"fmt"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+)
+
+// Inventory is an in-memory index of all snapshots, keyed by dataset and frequency
+// It is loaded with a single listing per run and kept up to date with Add and Remove
+// after snapshots are created or deleted, so the pool is not listed again for every lookup
+type Inventory struct {
+	snapshots map[inventoryKey][]*models.Snapshot
+	count     int
+}
+
+type inventoryKey struct {
+	filesystemName string
+	frequency      string
+}
+
+// NewInventory creates an inventory from a list of snapshots
+func NewInventory(snapshots []*models.Snapshot) *Inventory {
+	inventory := &Inventory{snapshots: make(map[inventoryKey][]*models.Snapshot)}
+	for _, snapshot := range snapshots {
+		inventory.Add(snapshot)
+	}
+	return inventory
+}
+
+// LoadInventory lists all snapshots of the backend once and indexes them
+func LoadInventory(backend Backend) (*Inventory, error) {
+	snapshots, err := backend.GetSnapshots("", "", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	return NewInventory(snapshots), nil
+}
+
+// Get returns the snapshots of a dataset and frequency
+// The returned slice is a copy and may be reordered by the caller
+func (i *Inventory) Get(filesystemName, frequency string) []*models.Snapshot {
+	snapshots := i.snapshots[inventoryKey{filesystemName: filesystemName, frequency: frequency}]
+	return append([]*models.Snapshot(nil), snapshots...)
+}
+
+// Add records a created snapshot
+func (i *Inventory) Add(snapshot *models.Snapshot) {
+	key := inventoryKey{filesystemName: snapshot.FilesystemName, frequency: snapshot.Frequency}
+	i.snapshots[key] = append(i.snapshots[key], snapshot)
+	i.count++
+}
+
+// Remove forgets a deleted snapshot (matched by dataset and snapshot name)
+// Like ZFS, the bytes written before the deleted snapshot are added to the written property of the
+// next newer snapshot of the dataset, so later checks for unchanged datasets still see them
+func (i *Inventory) Remove(snapshot *models.Snapshot) {
+	key := inventoryKey{filesystemName: snapshot.FilesystemName, frequency: snapshot.Frequency}
+	snapshots := i.snapshots[key]
+	for j, s := range snapshots {
+		if s.SnapshotName == snapshot.SnapshotName {
+			i.snapshots[key] = append(snapshots[:j:j], snapshots[j+1:]...)
+			i.count--
+			if next := i.next(s); next != nil {
+				next.Written += s.Written
+				next.WrittenKnown = next.WrittenKnown && s.WrittenKnown
+			}
+			return
+		}
+	}
+}
+
+// next returns the oldest snapshot of the dataset of snapshot that was taken after it, or nil
+func (i *Inventory) next(snapshot *models.Snapshot) *models.Snapshot {
+	var next *models.Snapshot
+	for key, snapshots := range i.snapshots {
+		if key.filesystemName != snapshot.FilesystemName {
+			continue
+		}
+		for _, s := range snapshots {
+			if takenBefore(snapshot, s) && (next == nil || takenBefore(s, next)) {
+				next = s
+			}
+		}
+	}
+	return next
+}
+
+// takenBefore checks if snapshot a was taken before b, by transaction group if both are known
+func takenBefore(a, b *models.Snapshot) bool {
+	if a.CreateTxg != 0 && b.CreateTxg != 0 {
+		return a.CreateTxg < b.CreateTxg
+	}
+	return a.DateTime.Before(b.DateTime)
+}
+
+// Newest returns the newest managed snapshot of a dataset across all frequencies
+// Unmanaged snapshots (e.g. manual ones) are left out, the operator never deletes them anyway
+// Snapshots with the same time are ordered by their creation transaction group
+func (i *Inventory) Newest(filesystemName string) *models.Snapshot {
+	var newest *models.Snapshot
+	for key, snapshots := range i.snapshots {
+		if key.filesystemName != filesystemName || key.frequency == "" {
+			continue
+		}
+		for _, s := range snapshots {
+			if newest == nil || s.DateTime.After(newest.DateTime) ||
+				(s.DateTime.Equal(newest.DateTime) && s.CreateTxg > newest.CreateTxg) {
+				newest = s
+			}
+		}
+	}
+	return newest
+}
+
+// All returns every snapshot in the inventory
+func (i *Inventory) All() []*models.Snapshot {
+	snapshots := make([]*models.Snapshot, 0, i.count)
+	for _, s := range i.snapshots {
+		snapshots = append(snapshots, s...)
+	}
+	return snapshots
+}
+
+// Len returns the total number of snapshots in the inventory
+func (i *Inventory) Len() int {
+	return i.count
+}
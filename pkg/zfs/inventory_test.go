@@ -0,0 +1,106 @@
+package zfs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+)
+
+func TestInventory(t *testing.T) {
+	hourly := &models.Snapshot{FilesystemName: "tank/data", SnapshotName: "autosnap_2026-01-25_12:00:00_hourly", Frequency: "hourly"}
+	daily := &models.Snapshot{FilesystemName: "tank/data", SnapshotName: "autosnap_2026-01-25_00:00:00_daily", Frequency: "daily"}
+	other := &models.Snapshot{FilesystemName: "tank/other", SnapshotName: "autosnap_2026-01-25_12:00:00_hourly", Frequency: "hourly"}
+
+	inventory := NewInventory([]*models.Snapshot{hourly, daily, other})
+	if inventory.Len() != 3 {
+		t.Fatalf("Len() = %d, want 3", inventory.Len())
+	}
+
+	if got := inventory.Get("tank/data", "hourly"); len(got) != 1 || got[0] != hourly {
+		t.Errorf("Get(tank/data, hourly) = %v, want [%v]", got, hourly)
+	}
+	if got := inventory.Get("tank/missing", "hourly"); len(got) != 0 {
+		t.Errorf("Get(tank/missing, hourly) returned %d snapshot(s), want 0", len(got))
+	}
+
+	created := &models.Snapshot{FilesystemName: "tank/data", SnapshotName: "autosnap_2026-01-25_13:00:00_hourly", Frequency: "hourly"}
+	inventory.Add(created)
+	if got := inventory.Get("tank/data", "hourly"); len(got) != 2 {
+		t.Errorf("Get() after Add returned %d snapshot(s), want 2", len(got))
+	}
+
+	// Removing matches by name, so a snapshot parsed from a later listing is found as well
+	inventory.Remove(&models.Snapshot{FilesystemName: "tank/data", SnapshotName: hourly.SnapshotName, Frequency: "hourly"})
+	if got := inventory.Get("tank/data", "hourly"); len(got) != 1 || got[0] != created {
+		t.Errorf("Get() after Remove = %v, want [%v]", got, created)
+	}
+	if inventory.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", inventory.Len())
+	}
+}
+
+func TestLoadInventory(t *testing.T) {
+	backend := NewSimulatedBackend(nil, nil, []*models.Snapshot{
+		{FilesystemName: "tank/data", SnapshotName: "autosnap_2026-01-25_12:00:00_hourly", Frequency: "hourly"},
+	})
+
+	inventory, err := LoadInventory(backend)
+	if err != nil {
+		t.Fatalf("LoadInventory() error = %v", err)
+	}
+	if inventory.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", inventory.Len())
+	}
+}
+
+func TestInventoryNewest(t *testing.T) {
+	base := time.Date(2026, 1, 25, 12, 0, 0, 0, time.UTC)
+	hourly := &models.Snapshot{FilesystemName: "tank/data", SnapshotName: "autosnap_2026-01-25_12:00:00_hourly", Frequency: "hourly", DateTime: base, CreateTxg: 10}
+	daily := &models.Snapshot{FilesystemName: "tank/data", SnapshotName: "autosnap_2026-01-25_12:00:00_daily", Frequency: "daily", DateTime: base, CreateTxg: 11}
+	manual := &models.Snapshot{FilesystemName: "tank/data", SnapshotName: "before-upgrade", DateTime: base.Add(-time.Hour)}
+	other := &models.Snapshot{FilesystemName: "tank/other", SnapshotName: "autosnap_2026-01-25_13:00:00_hourly", Frequency: "hourly", DateTime: base.Add(time.Hour)}
+
+	inventory := NewInventory([]*models.Snapshot{hourly, daily, manual, other})
+	// Snapshots taken together are ordered by their transaction group
+	if got := inventory.Newest("tank/data"); got != daily {
+		t.Errorf("Newest(tank/data) = %v, want %v", got, daily)
+	}
+
+	// A newer unmanaged snapshot does not take the place of the newest managed one
+	newer := &models.Snapshot{FilesystemName: "tank/data", SnapshotName: "after-upgrade", DateTime: base.Add(time.Minute)}
+	inventory.Add(newer)
+	if got := inventory.Newest("tank/data"); got != daily {
+		t.Errorf("Newest(tank/data) = %v, want %v", got, daily)
+	}
+
+	if got := inventory.Newest("tank/missing"); got != nil {
+		t.Errorf("Newest(tank/missing) = %v, want nil", got)
+	}
+}
+
+func TestInventoryRemoveKeepsWritten(t *testing.T) {
+	base := time.Date(2026, 1, 25, 12, 0, 0, 0, time.UTC)
+	daily := &models.Snapshot{FilesystemName: "tank/data", SnapshotName: "autosnap_2026-01-25_12:00:00_daily", Frequency: "daily", DateTime: base, WrittenKnown: true}
+	older := &models.Snapshot{FilesystemName: "tank/data", SnapshotName: "autosnap_2026-01-25_13:00:00_hourly", Frequency: "hourly", DateTime: base.Add(time.Hour), Written: 4096, WrittenKnown: true}
+	newer := &models.Snapshot{FilesystemName: "tank/data", SnapshotName: "autosnap_2026-01-25_14:00:00_hourly", Frequency: "hourly", DateTime: base.Add(2 * time.Hour), Written: 512, WrittenKnown: true}
+	unknown := &models.Snapshot{FilesystemName: "tank/data", SnapshotName: "manual", DateTime: base.Add(3 * time.Hour), WrittenKnown: false}
+	other := &models.Snapshot{FilesystemName: "tank/other", SnapshotName: "autosnap_2026-01-25_13:30:00_hourly", Frequency: "hourly", DateTime: base.Add(90 * time.Minute), WrittenKnown: true}
+
+	inventory := NewInventory([]*models.Snapshot{daily, older, newer, unknown, other})
+
+	// The bytes written before the deleted snapshot move to the next newer snapshot of the same dataset
+	inventory.Remove(older)
+	if newer.Written != 4608 || !newer.WrittenKnown {
+		t.Errorf("Written of the next snapshot = %d (known %v), want 4608", newer.Written, newer.WrittenKnown)
+	}
+	if other.Written != 0 || daily.Written != 0 {
+		t.Errorf("Written of other snapshots changed: %d, %d", other.Written, daily.Written)
+	}
+
+	// An unknown written property stays unknown
+	inventory.Remove(newer)
+	if unknown.WrittenKnown {
+		t.Error("Written of the next snapshot is known, want unknown")
+	}
+}
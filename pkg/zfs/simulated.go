@@ -0,0 +1,306 @@
+package zfs
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+)
+
+// SimulatedBackend is an in-memory Backend that keeps a mutable dataset/snapshot tree
+// Creates and destroys are applied to the tree, which makes it suitable for replaying
+// many runs against a virtual clock to preview what a retention policy will do
+// Snapshots are copied when they are stored and returned, so callers cannot change the tree
+type SimulatedBackend struct {
+	mu         sync.Mutex
+	pools      []*models.Pool
+	poolStatus map[string]*models.PoolStatus
+	snapshots  map[string]*models.Snapshot // keyed by filesystem@snapshot
+	holds      map[string][]*models.Hold   // keyed by filesystem@snapshot
+	now        time.Time                   // Virtual clock, e.g. for the time of new holds
+}
+
+// Ensure SimulatedBackend satisfies the Backend interface and all optional interfaces
+var (
+	_ Backend          = (*SimulatedBackend)(nil)
+	_ BatchCreator     = (*SimulatedBackend)(nil)
+	_ ReclaimEstimator = (*SimulatedBackend)(nil)
+	_ HoldManager      = (*SimulatedBackend)(nil)
+	_ PoolStatusReader = (*SimulatedBackend)(nil)
+)
+
+// NewSimulatedBackend creates a simulated backend seeded with the given pools, pool status and snapshots
+func NewSimulatedBackend(pools []*models.Pool, poolStatus map[string]*models.PoolStatus, snapshots []*models.Snapshot) *SimulatedBackend {
+	b := &SimulatedBackend{
+		pools:      pools,
+		poolStatus: poolStatus,
+		snapshots:  make(map[string]*models.Snapshot),
+		holds:      make(map[string][]*models.Hold),
+		now:        time.Now(),
+	}
+	if b.poolStatus == nil {
+		b.poolStatus = make(map[string]*models.PoolStatus)
+	}
+	for _, snapshot := range snapshots {
+		b.snapshots[snapshotPath(snapshot)] = copySnapshot(snapshot)
+	}
+	return b
+}
+
+// NewSimulatedBackendFrom creates a simulated backend seeded with the current state of another backend
+func NewSimulatedBackendFrom(source Backend) (*SimulatedBackend, error) {
+	pools, err := source.GetPools()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pools: %w", err)
+	}
+
+	poolStatus, err := GetPoolStatus(source, pools)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool status: %w", err)
+	}
+
+	snapshots, err := source.GetSnapshots("", "", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshots: %w", err)
+	}
+
+	var held []*models.Snapshot
+	for _, snapshot := range snapshots {
+		if snapshot.UserRefs > 0 {
+			held = append(held, snapshot)
+		}
+	}
+	var holds []*models.Hold
+	if len(held) > 0 {
+		holds, err = GetHolds(source, held)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get holds: %w", err)
+		}
+	}
+
+	b := NewSimulatedBackend(pools, poolStatus, snapshots)
+	for _, hold := range holds {
+		path := hold.FilesystemName + "@" + hold.SnapshotName
+		copied := *hold
+		b.holds[path] = append(b.holds[path], &copied)
+	}
+	return b, nil
+}
+
+// SetTime sets the virtual clock of the simulated backend
+func (b *SimulatedBackend) SetTime(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.now = now
+}
+
+// copySnapshot returns a copy of a snapshot that shares no state with the original
+func copySnapshot(snapshot *models.Snapshot) *models.Snapshot {
+	copied := *snapshot
+	copied.Clones = append([]string(nil), snapshot.Clones...)
+	return &copied
+}
+
+// snapshotPath returns the full ZFS name of a snapshot (e.g., "tank/data@autosnap_...")
+func snapshotPath(snapshot *models.Snapshot) string {
+	return fmt.Sprintf("%s@%s", snapshot.FilesystemName, snapshot.SnapshotName)
+}
+
+// GetVersion returns a fixed version string for the simulated backend
+func (b *SimulatedBackend) GetVersion() (string, string, error) {
+	return "simulated", "simulated", nil
+}
+
+// GetPools returns the simulated pools and filesystems
+func (b *SimulatedBackend) GetPools() ([]*models.Pool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]*models.Pool(nil), b.pools...), nil
+}
+
+// GetSnapshots returns the simulated snapshots filtered by pool, filesystem and frequency
+func (b *SimulatedBackend) GetSnapshots(poolName, filesystemName, frequency string) ([]*models.Snapshot, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var snapshots []*models.Snapshot
+	for _, snapshot := range b.snapshots {
+		if poolName != "" && snapshot.PoolName != poolName {
+			continue
+		}
+		if filesystemName != "" && snapshot.FilesystemName != filesystemName {
+			continue
+		}
+		if frequency != "" && snapshot.Frequency != frequency {
+			continue
+		}
+		snapshots = append(snapshots, copySnapshot(snapshot))
+	}
+
+	sortSnapshots(snapshots)
+	return snapshots, nil
+}
+
+// CreateSnapshot adds a snapshot to the simulated tree
+func (b *SimulatedBackend) CreateSnapshot(snapshot *models.Snapshot) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	path := snapshotPath(snapshot)
+	if _, exists := b.snapshots[path]; exists {
+		return fmt.Errorf("snapshot %s already exists", path)
+	}
+	if !b.hasFilesystem(snapshot.FilesystemName) {
+		return fmt.Errorf("dataset %s does not exist", snapshot.FilesystemName)
+	}
+
+	b.snapshots[path] = copySnapshot(snapshot)
+	return nil
+}
+
+// CreateSnapshots adds several snapshots to the simulated tree; like zfs snapshot with several names,
+// nothing is created if any of them fails
+func (b *SimulatedBackend) CreateSnapshots(snapshots []*models.Snapshot) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	seen := make(map[string]bool)
+	for _, snapshot := range snapshots {
+		path := snapshotPath(snapshot)
+		if _, exists := b.snapshots[path]; exists || seen[path] {
+			return fmt.Errorf("snapshot %s already exists", path)
+		}
+		if !b.hasFilesystem(snapshot.FilesystemName) {
+			return fmt.Errorf("dataset %s does not exist", snapshot.FilesystemName)
+		}
+		seen[path] = true
+	}
+
+	for _, snapshot := range snapshots {
+		b.snapshots[snapshotPath(snapshot)] = copySnapshot(snapshot)
+	}
+	return nil
+}
+
+// DeleteSnapshot removes a snapshot from the simulated tree
+func (b *SimulatedBackend) DeleteSnapshot(snapshot *models.Snapshot) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	path := snapshotPath(snapshot)
+	if _, exists := b.snapshots[path]; !exists {
+		return fmt.Errorf("could not find any snapshots to destroy; check snapshot names (%s)", path)
+	}
+	if len(b.holds[path]) > 0 {
+		return fmt.Errorf("%w: cannot destroy snapshot %s: dataset is busy", ErrSnapshotRetained, path)
+	}
+
+	delete(b.snapshots, path)
+	return nil
+}
+
+// EstimateReclaim sums the used space of the snapshots; the simulated tree does not track shared blocks
+func (b *SimulatedBackend) EstimateReclaim(snapshots []*models.Snapshot) (uint64, error) {
+	var reclaim uint64
+	for _, snapshot := range snapshots {
+		reclaim += snapshot.Used
+	}
+	return reclaim, nil
+}
+
+// HoldSnapshot adds a hold tag to a simulated snapshot
+func (b *SimulatedBackend) HoldSnapshot(snapshot *models.Snapshot, tag string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	path := snapshotPath(snapshot)
+	stored, exists := b.snapshots[path]
+	if !exists {
+		return fmt.Errorf("snapshot %s does not exist", path)
+	}
+	for _, hold := range b.holds[path] {
+		if hold.Tag == tag {
+			return fmt.Errorf("cannot hold snapshot %s: tag already exists on this dataset", path)
+		}
+	}
+
+	b.holds[path] = append(b.holds[path], &models.Hold{
+		FilesystemName: stored.FilesystemName,
+		SnapshotName:   stored.SnapshotName,
+		Tag:            tag,
+		Time:           b.now,
+	})
+	stored.UserRefs = uint64(len(b.holds[path]))
+	return nil
+}
+
+// ReleaseSnapshot removes a hold tag from a simulated snapshot
+func (b *SimulatedBackend) ReleaseSnapshot(snapshot *models.Snapshot, tag string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	path := snapshotPath(snapshot)
+	for i, hold := range b.holds[path] {
+		if hold.Tag == tag {
+			b.holds[path] = append(b.holds[path][:i], b.holds[path][i+1:]...)
+			if stored, exists := b.snapshots[path]; exists {
+				stored.UserRefs = uint64(len(b.holds[path]))
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot release hold from snapshot %s: no such tag on this dataset", path)
+}
+
+// GetHolds returns the hold tags of the given simulated snapshots
+func (b *SimulatedBackend) GetHolds(snapshots []*models.Snapshot) ([]*models.Hold, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var holds []*models.Hold
+	for _, snapshot := range snapshots {
+		for _, hold := range b.holds[snapshotPath(snapshot)] {
+			copied := *hold
+			holds = append(holds, &copied)
+		}
+	}
+	return holds, nil
+}
+
+// GetPoolStatus returns the simulated pool status
+func (b *SimulatedBackend) GetPoolStatus() (map[string]*models.PoolStatus, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := make(map[string]*models.PoolStatus, len(b.poolStatus))
+	for name, ps := range b.poolStatus {
+		status[name] = ps
+	}
+	return status, nil
+}
+
+// hasFilesystem checks if a filesystem exists in the simulated tree
+func (b *SimulatedBackend) hasFilesystem(filesystemName string) bool {
+	for _, pool := range b.pools {
+		if pool.FilesystemName == filesystemName {
+			return true
+		}
+	}
+	return false
+}
+
+// sortSnapshots sorts snapshots by filesystem, then date (oldest first), then name
+func sortSnapshots(snapshots []*models.Snapshot) {
+	sort.Slice(snapshots, func(i, j int) bool {
+		if snapshots[i].FilesystemName != snapshots[j].FilesystemName {
+			return snapshots[i].FilesystemName < snapshots[j].FilesystemName
+		}
+		if !snapshots[i].DateTime.Equal(snapshots[j].DateTime) {
+			return snapshots[i].DateTime.Before(snapshots[j].DateTime)
+		}
+		return snapshots[i].SnapshotName < snapshots[j].SnapshotName
+	})
+}
@@ -0,0 +1,219 @@
+package zfs
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/config"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+)
+
+func TestSimulatedBackendCreateAndDelete(t *testing.T) {
+	backend := NewSimulatedBackend(
+		[]*models.Pool{{PoolName: "tank", FilesystemName: "tank/data"}},
+		nil,
+		nil,
+	)
+
+	snapshot := &models.Snapshot{
+		PoolName:       "tank",
+		FilesystemName: "tank/data",
+		SnapshotName:   "autosnap_2026-01-25_12:00:00_hourly",
+		DateTime:       time.Date(2026, 1, 25, 12, 0, 0, 0, time.UTC),
+		Frequency:      "hourly",
+	}
+
+	if err := backend.CreateSnapshot(snapshot); err != nil {
+		t.Fatalf("CreateSnapshot() error = %v", err)
+	}
+	if err := backend.CreateSnapshot(snapshot); err == nil {
+		t.Error("CreateSnapshot() should fail for an existing snapshot")
+	}
+
+	snapshots, _ := backend.GetSnapshots("tank", "tank/data", "hourly")
+	if len(snapshots) != 1 {
+		t.Fatalf("GetSnapshots() returned %d snapshot(s), want 1", len(snapshots))
+	}
+
+	if err := backend.DeleteSnapshot(snapshot); err != nil {
+		t.Fatalf("DeleteSnapshot() error = %v", err)
+	}
+	if err := backend.DeleteSnapshot(snapshot); err == nil {
+		t.Error("DeleteSnapshot() should fail for a missing snapshot")
+	}
+
+	snapshots, _ = backend.GetSnapshots("", "", "")
+	if len(snapshots) != 0 {
+		t.Errorf("GetSnapshots() returned %d snapshot(s) after delete, want 0", len(snapshots))
+	}
+}
+
+func TestSimulatedBackendCreateSnapshotsAtomic(t *testing.T) {
+	backend := NewSimulatedBackend(
+		[]*models.Pool{
+			{PoolName: "tank", FilesystemName: "tank/db"},
+			{PoolName: "tank", FilesystemName: "tank/db/wal"},
+		},
+		nil,
+		nil,
+	)
+
+	newSnapshot := func(filesystemName string) *models.Snapshot {
+		return &models.Snapshot{
+			PoolName:       "tank",
+			FilesystemName: filesystemName,
+			SnapshotName:   "autosnap_2026-01-25_12:00:00_hourly",
+			DateTime:       time.Date(2026, 1, 25, 12, 0, 0, 0, time.UTC),
+			Frequency:      "hourly",
+		}
+	}
+
+	// One unknown dataset fails the whole batch
+	err := backend.CreateSnapshots([]*models.Snapshot{newSnapshot("tank/db"), newSnapshot("tank/db/missing")})
+	if err == nil {
+		t.Fatal("CreateSnapshots() should fail for an unknown dataset")
+	}
+	if snapshots, _ := backend.GetSnapshots("", "", ""); len(snapshots) != 0 {
+		t.Fatalf("CreateSnapshots() created %d snapshot(s) on failure, want 0", len(snapshots))
+	}
+
+	if err := backend.CreateSnapshots([]*models.Snapshot{newSnapshot("tank/db"), newSnapshot("tank/db/wal")}); err != nil {
+		t.Fatalf("CreateSnapshots() error = %v", err)
+	}
+	if snapshots, _ := backend.GetSnapshots("", "", ""); len(snapshots) != 2 {
+		t.Errorf("GetSnapshots() returned %d snapshot(s), want 2", len(snapshots))
+	}
+}
+
+func TestSimulatedBackendUnknownDataset(t *testing.T) {
+	backend := NewSimulatedBackend(nil, nil, nil)
+
+	err := backend.CreateSnapshot(&models.Snapshot{
+		PoolName:       "tank",
+		FilesystemName: "tank/missing",
+		SnapshotName:   "autosnap_2026-01-25_12:00:00_hourly",
+	})
+	if err == nil {
+		t.Error("CreateSnapshot() should fail for an unknown dataset")
+	}
+}
+
+func TestNewSimulatedBackendFrom(t *testing.T) {
+	cfg := config.NewConfig("simulate")
+	cfg.ZFSListPoolsCmd = []string{"cat", "../../test/zfs_list_pools.json"}
+	cfg.ZFSListSnapshotsCmd = []string{"cat", "../../test/zfs_list_snapshots.json"}
+	cfg.ZPoolStatusCmd = []string{"cat", "../../test/zpool_status.json"}
+
+	backend, err := NewSimulatedBackendFrom(NewManager(cfg))
+	if err != nil {
+		t.Fatalf("NewSimulatedBackendFrom() error = %v", err)
+	}
+
+	pools, _ := backend.GetPools()
+	if len(pools) == 0 {
+		t.Error("Simulated backend should be seeded with pools")
+	}
+
+	snapshots, _ := backend.GetSnapshots("", "", "")
+	if len(snapshots) == 0 {
+		t.Error("Simulated backend should be seeded with snapshots")
+	}
+}
+
+func TestSimulatedBackendHolds(t *testing.T) {
+	snapshot := &models.Snapshot{
+		PoolName:       "tank",
+		FilesystemName: "tank/data",
+		SnapshotName:   "autosnap_2026-01-25_12:00:00_hourly",
+		Frequency:      "hourly",
+	}
+	backend := NewSimulatedBackend([]*models.Pool{{PoolName: "tank", FilesystemName: "tank/data"}}, nil, []*models.Snapshot{snapshot})
+	now := time.Date(2030, 6, 1, 12, 0, 0, 0, time.UTC)
+	backend.SetTime(now)
+
+	if err := backend.HoldSnapshot(snapshot, "zfs-snapshot-operator:replication"); err != nil {
+		t.Fatalf("HoldSnapshot() error = %v", err)
+	}
+	if err := backend.HoldSnapshot(snapshot, "zfs-snapshot-operator:replication"); err == nil {
+		t.Error("HoldSnapshot() should fail for an existing tag")
+	}
+	if stored, _ := backend.GetSnapshots("", "", ""); stored[0].UserRefs != 1 {
+		t.Errorf("UserRefs = %d, want 1", stored[0].UserRefs)
+	}
+
+	holds, _ := backend.GetHolds([]*models.Snapshot{snapshot})
+	if len(holds) != 1 || holds[0].Tag != "zfs-snapshot-operator:replication" {
+		t.Errorf("GetHolds() = %v, want the replication hold", holds)
+	} else if !holds[0].Time.Equal(now) {
+		t.Errorf("Hold time = %s, want the virtual time %s", holds[0].Time, now)
+	}
+
+	if err := backend.DeleteSnapshot(snapshot); !errors.Is(err, ErrSnapshotRetained) {
+		t.Errorf("DeleteSnapshot() error = %v, want ErrSnapshotRetained", err)
+	}
+
+	if err := backend.ReleaseSnapshot(snapshot, "zfs-snapshot-operator:replication"); err != nil {
+		t.Fatalf("ReleaseSnapshot() error = %v", err)
+	}
+	if err := backend.ReleaseSnapshot(snapshot, "zfs-snapshot-operator:replication"); err == nil {
+		t.Error("ReleaseSnapshot() should fail for a released tag")
+	}
+	if stored, _ := backend.GetSnapshots("", "", ""); stored[0].UserRefs != 0 {
+		t.Errorf("UserRefs = %d, want 0", stored[0].UserRefs)
+	}
+	if err := backend.DeleteSnapshot(snapshot); err != nil {
+		t.Errorf("DeleteSnapshot() error = %v", err)
+	}
+}
+
+func TestSimulatedBackendCopiesSnapshots(t *testing.T) {
+	snapshot := &models.Snapshot{
+		PoolName:       "tank",
+		FilesystemName: "tank/data",
+		SnapshotName:   "autosnap_2026-01-25_12:00:00_hourly",
+		Frequency:      "hourly",
+		Clones:         []string{"tank/clone"},
+	}
+	backend := NewSimulatedBackend([]*models.Pool{{PoolName: "tank", FilesystemName: "tank/data"}}, nil, nil)
+	if err := backend.CreateSnapshot(snapshot); err != nil {
+		t.Fatalf("CreateSnapshot() error = %v", err)
+	}
+
+	// Changing the created or the returned snapshot leaves the simulated tree alone
+	snapshot.Frequency = "daily"
+	snapshots, _ := backend.GetSnapshots("", "", "")
+	snapshots[0].UserRefs = 5
+	snapshots[0].Clones[0] = "tank/other"
+
+	snapshots, _ = backend.GetSnapshots("", "", "")
+	if got := snapshots[0]; got.Frequency != "hourly" || got.UserRefs != 0 || got.Clones[0] != "tank/clone" {
+		t.Errorf("Stored snapshot = %+v, want it unchanged", got)
+	}
+}
+
+func TestSimulatedBackendFromSeedsHolds(t *testing.T) {
+	snapshot := &models.Snapshot{
+		PoolName:       "tank",
+		FilesystemName: "tank/data",
+		SnapshotName:   "autosnap_2026-01-25_12:00:00_hourly",
+		Frequency:      "hourly",
+	}
+	source := NewSimulatedBackend([]*models.Pool{{PoolName: "tank", FilesystemName: "tank/data"}}, nil, []*models.Snapshot{snapshot})
+	if err := source.HoldSnapshot(snapshot, "replication"); err != nil {
+		t.Fatalf("HoldSnapshot() error = %v", err)
+	}
+
+	backend, err := NewSimulatedBackendFrom(source)
+	if err != nil {
+		t.Fatalf("NewSimulatedBackendFrom() error = %v", err)
+	}
+
+	holds, _ := backend.GetHolds([]*models.Snapshot{snapshot})
+	if len(holds) != 1 || holds[0].Tag != "replication" {
+		t.Errorf("GetHolds() = %v, want the hold of the source", holds)
+	}
+	if err := backend.DeleteSnapshot(snapshot); !errors.Is(err, ErrSnapshotRetained) {
+		t.Errorf("DeleteSnapshot() error = %v, want ErrSnapshotRetained", err)
+	}
+}
@@ -86,3 +86,37 @@ func TestSnapshotWithinSameHour(t *testing.T) {
 		t.Errorf("Both timestamps should be in same hour: period1=%s, period2=%s", period1, period2)
 	}
 }
+
+// TestGetNextPeriodStart verifies that the next period start is the first instant with a new period key
+func TestGetNextPeriodStart(t *testing.T) {
+	tests := []struct {
+		frequency string
+		now       time.Time
+		want      time.Time
+	}{
+		{"frequently", time.Date(2026, 1, 25, 14, 7, 30, 0, time.UTC), time.Date(2026, 1, 25, 14, 15, 0, 0, time.UTC)},
+		{"frequently", time.Date(2026, 1, 25, 23, 45, 0, 0, time.UTC), time.Date(2026, 1, 26, 0, 0, 0, 0, time.UTC)},
+		{"hourly", time.Date(2026, 1, 25, 14, 59, 59, 0, time.UTC), time.Date(2026, 1, 25, 15, 0, 0, 0, time.UTC)},
+		{"daily", time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC), time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"weekly", time.Date(2026, 1, 25, 12, 0, 0, 0, time.UTC), time.Date(2026, 1, 26, 0, 0, 0, 0, time.UTC)}, // Sunday -> Monday
+		{"weekly", time.Date(2026, 1, 26, 0, 0, 0, 0, time.UTC), time.Date(2026, 2, 2, 0, 0, 0, 0, time.UTC)},   // Monday -> next Monday
+		{"monthly", time.Date(2026, 12, 15, 0, 0, 0, 0, time.UTC), time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"yearly", time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.frequency+" "+tt.now.Format(time.RFC3339), func(t *testing.T) {
+			got := GetNextPeriodStart(tt.now, tt.frequency)
+			if !got.Equal(tt.want) {
+				t.Errorf("GetNextPeriodStart() = %v, want %v", got, tt.want)
+			}
+
+			if GetTimePeriodKey(got, tt.frequency) == GetTimePeriodKey(tt.now, tt.frequency) {
+				t.Errorf("Period key at %v should differ from key at %v", got, tt.now)
+			}
+			if GetTimePeriodKey(got.Add(-time.Nanosecond), tt.frequency) != GetTimePeriodKey(tt.now, tt.frequency) {
+				t.Errorf("Instant before %v should still be in the current period", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package zfs
+
+import (
+	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/config"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+)
+
+// TimeMismatchTolerance is the largest difference between the name timestamp and the creation
+// property that is not reported; snapshot names are truncated to the second and created shortly
+// after the name is generated
+const TimeMismatchTolerance = 15 * time.Minute
+
+// applyTimeSource sets the effective DateTime of every snapshot according to the time source
+// A snapshot without the preferred time falls back to the other one
+// The effective time is converted to loc, the time zone snapshots are bucketed in
+func applyTimeSource(snapshots []*models.Snapshot, source string, loc *time.Location) {
+	for _, snapshot := range snapshots {
+		preferred, fallback := snapshot.NameTime, snapshot.CreationTime
+		if source == config.TimeSourceCreation {
+			preferred, fallback = fallback, preferred
+		}
+		if preferred.IsZero() {
+			preferred = fallback
+		}
+		snapshot.DateTime = preferred.In(loc)
+	}
+}
+
+// TimeMismatches returns the snapshots whose name timestamp differs from their creation property
+// by more than TimeMismatchTolerance (e.g. renamed snapshots or names written in another time zone)
+func TimeMismatches(snapshots []*models.Snapshot) []*models.Snapshot {
+	var mismatches []*models.Snapshot
+	for _, snapshot := range snapshots {
+		if snapshot.NameTime.IsZero() || snapshot.CreationTime.IsZero() {
+			continue
+		}
+		diff := snapshot.NameTime.Sub(snapshot.CreationTime)
+		if diff < -TimeMismatchTolerance || diff > TimeMismatchTolerance {
+			mismatches = append(mismatches, snapshot)
+		}
+	}
+	return mismatches
+}
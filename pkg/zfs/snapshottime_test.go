@@ -0,0 +1,49 @@
+package zfs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/runningman84/zfs-snapshot-operator/pkg/config"
+	"github.com/runningman84/zfs-snapshot-operator/pkg/models"
+)
+
+func TestApplyTimeSource(t *testing.T) {
+	nameTime := time.Date(2026, 1, 25, 12, 0, 0, 0, time.UTC)
+	creationTime := time.Date(2026, 1, 25, 13, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		source   string
+		snapshot models.Snapshot
+		want     time.Time
+	}{
+		{"name source", config.TimeSourceName, models.Snapshot{NameTime: nameTime, CreationTime: creationTime}, nameTime},
+		{"creation source", config.TimeSourceCreation, models.Snapshot{NameTime: nameTime, CreationTime: creationTime}, creationTime},
+		{"name source without name time", config.TimeSourceName, models.Snapshot{CreationTime: creationTime}, creationTime},
+		{"creation source without creation time", config.TimeSourceCreation, models.Snapshot{NameTime: nameTime}, nameTime},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot := tt.snapshot
+			applyTimeSource([]*models.Snapshot{&snapshot}, tt.source, time.UTC)
+			if !snapshot.DateTime.Equal(tt.want) {
+				t.Errorf("DateTime = %v, want %v", snapshot.DateTime, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeMismatches(t *testing.T) {
+	base := time.Date(2026, 1, 25, 12, 0, 0, 0, time.UTC)
+	matching := &models.Snapshot{SnapshotName: "matching", NameTime: base, CreationTime: base.Add(2 * time.Second)}
+	shifted := &models.Snapshot{SnapshotName: "shifted", NameTime: base, CreationTime: base.Add(-time.Hour)}
+	renamed := &models.Snapshot{SnapshotName: "renamed", CreationTime: base}
+	unknown := &models.Snapshot{SnapshotName: "unknown", NameTime: base}
+
+	got := TimeMismatches([]*models.Snapshot{matching, shifted, renamed, unknown})
+	if len(got) != 1 || got[0] != shifted {
+		t.Errorf("TimeMismatches() = %v, want [%v]", got, shifted)
+	}
+}
@@ -1,9 +1,12 @@
 package zfs
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os/exec"
+	"strings"
 	"time"
 
 	"github.com/runningman84/zfs-snapshot-operator/pkg/config"
@@ -12,11 +15,23 @@ import (
 	"k8s.io/klog/v2"
 )
 
+// ErrSnapshotRetained is returned by DeleteSnapshot if a hold or a dependent clone keeps the snapshot
+var ErrSnapshotRetained = errors.New("snapshot is retained by a hold or clone")
+
 // Manager handles ZFS operations
 type Manager struct {
 	config *config.Config
 }
 
+// Ensure Manager satisfies the Backend interface and all optional interfaces
+var (
+	_ Backend          = (*Manager)(nil)
+	_ BatchCreator     = (*Manager)(nil)
+	_ ReclaimEstimator = (*Manager)(nil)
+	_ HoldManager      = (*Manager)(nil)
+	_ PoolStatusReader = (*Manager)(nil)
+)
+
 // NewManager creates a new ZFS manager
 func NewManager(cfg *config.Config) *Manager {
 	return &Manager{
@@ -80,18 +95,14 @@ func (m *Manager) GetVersion() (string, string, error) {
 
 // GetPools retrieves all ZFS pools
 func (m *Manager) GetPools() ([]*models.Pool, error) {
-	m.logCommand(m.config.ZFSListPoolsCmd)
-	cmd := exec.Command(m.config.ZFSListPoolsCmd[0], m.config.ZFSListPoolsCmd[1:]...)
-	output, err := cmd.CombinedOutput()
-	exitCode := 0
+	output, err := m.runList(m.scopedCommand(m.config.ZFSListPoolsCmd, m.listPoolsArgs()))
 	if err != nil {
-		if exitError, ok := err.(*exec.ExitError); ok {
-			exitCode = exitError.ExitCode()
-		}
-		m.logCommandResult(exitCode, output, nil)
-		return nil, fmt.Errorf("command failed: %w", err)
+		return nil, err
+	}
+	if len(bytes.TrimSpace(output)) == 0 {
+		// None of the whitelisted roots exist
+		return nil, nil
 	}
-	m.logCommandResult(0, output, nil)
 
 	pools, err := parser.ParsePoolsJSON(output)
 	if err != nil {
@@ -103,23 +114,26 @@ func (m *Manager) GetPools() ([]*models.Pool, error) {
 
 // GetSnapshots retrieves snapshots for a pool/filesystem
 func (m *Manager) GetSnapshots(poolName, filesystemName, frequency string) ([]*models.Snapshot, error) {
-	m.logCommand(m.config.ZFSListSnapshotsCmd)
-	cmd := exec.Command(m.config.ZFSListSnapshotsCmd[0], m.config.ZFSListSnapshotsCmd[1:]...)
-	output, err := cmd.CombinedOutput()
-	exitCode := 0
+	output, err := m.runList(m.scopedCommand(m.config.ZFSListSnapshotsCmd, m.listSnapshotsArgs()))
 	if err != nil {
-		if exitError, ok := err.(*exec.ExitError); ok {
-			exitCode = exitError.ExitCode()
-		}
-		m.logCommandResult(exitCode, output, nil)
-		return nil, fmt.Errorf("command failed: %w", err)
+		return nil, err
+	}
+	if len(bytes.TrimSpace(output)) == 0 {
+		// None of the whitelisted roots exist
+		return nil, nil
+	}
+
+	template, err := m.config.NameTemplate()
+	if err != nil {
+		return nil, err
 	}
-	m.logCommandResult(0, output, nil)
 
-	allSnapshots, err := parser.ParseSnapshotsJSON(output, m.config.SnapshotPrefix)
+	allSnapshots, err := parser.ParseSnapshotsJSON(output, template, m.config.Location())
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse snapshots JSON: %w", err)
 	}
+	recognizeToolSnapshots(allSnapshots, m.config.IsToolAdopted, m.config.Location())
+	applyTimeSource(allSnapshots, m.config.SnapshotTimeSource, m.config.Location())
 
 	// Filter snapshots by pool, filesystem, and frequency
 	var snapshots []*models.Snapshot
@@ -155,7 +169,12 @@ func (m *Manager) DeleteSnapshot(snapshot *models.Snapshot) error {
 		cmdArgs = m.config.ZFSDeleteSnapshotCmd
 		cmd = exec.Command(cmdArgs[0], cmdArgs[1:]...)
 	} else {
-		cmdArgs = append(m.config.ZFSDeleteSnapshotCmd, snapshotPath)
+		cmdArgs = append([]string{}, m.config.ZFSDeleteSnapshotCmd...)
+		if m.config.DeferDestroy {
+			// Held or cloned snapshots are marked and destroyed once the last hold or clone is gone
+			cmdArgs = append(cmdArgs, "-d")
+		}
+		cmdArgs = append(cmdArgs, snapshotPath)
 		cmd = exec.Command(cmdArgs[0], cmdArgs[1:]...)
 	}
 	m.logCommand(cmdArgs)
@@ -167,6 +186,10 @@ func (m *Manager) DeleteSnapshot(snapshot *models.Snapshot) error {
 			exitCode = exitError.ExitCode()
 		}
 		m.logCommandResult(exitCode, output, nil)
+		// A hold or clone may have been added after the snapshots were listed
+		if isRetainedError(string(output)) {
+			return fmt.Errorf("%w: %s", ErrSnapshotRetained, strings.TrimSpace(string(output)))
+		}
 		return fmt.Errorf("command failed: %w, output: %s", err, string(output))
 	}
 	m.logCommandResult(0, output, nil)
@@ -174,6 +197,11 @@ func (m *Manager) DeleteSnapshot(snapshot *models.Snapshot) error {
 	return nil
 }
 
+// isRetainedError checks if zfs destroy failed because of a hold ("dataset is busy") or a dependent clone
+func isRetainedError(output string) bool {
+	return strings.Contains(output, "dataset is busy") || strings.Contains(output, "has dependent clones")
+}
+
 // CreateSnapshot creates a new ZFS snapshot
 func (m *Manager) CreateSnapshot(snapshot *models.Snapshot) error {
 	klog.Infof("Creating snapshot %s", snapshot.SnapshotName)
@@ -206,50 +234,252 @@ func (m *Manager) CreateSnapshot(snapshot *models.Snapshot) error {
 	return nil
 }
 
+// CreateSnapshots creates several ZFS snapshots atomically with a single multi-name zfs snapshot command
+// Either all snapshots are created or none of them
+func (m *Manager) CreateSnapshots(snapshots []*models.Snapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	snapshotPaths := make([]string, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		snapshotPaths = append(snapshotPaths, fmt.Sprintf("%s@%s", snapshot.FilesystemName, snapshot.SnapshotName))
+	}
+	klog.Infof("Creating %d snapshot(s) atomically: %v", len(snapshots), snapshotPaths)
+
+	var cmdArgs []string
+	if m.config.Mode == "test" {
+		cmdArgs = m.config.ZFSCreateSnapshotCmd
+	} else {
+		cmdArgs = append(append([]string{}, m.config.ZFSCreateSnapshotCmd...), snapshotPaths...)
+	}
+	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	m.logCommand(cmdArgs)
+
+	output, err := cmd.CombinedOutput()
+	exitCode := 0
+	if err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			exitCode = exitError.ExitCode()
+		}
+		m.logCommandResult(exitCode, output, nil)
+		return fmt.Errorf("command failed: %w, output: %s", err, string(output))
+	}
+	m.logCommandResult(0, output, nil)
+
+	return nil
+}
+
+// EstimateReclaim returns the space destroying the given snapshots of one dataset would reclaim
+// It runs zfs destroy -nvp with all snapshot names, so space shared only among them is counted too
+// A list longer than commandArgBytes is estimated in batches and summed, which leaves out the
+// space shared only by snapshots of different batches
+func (m *Manager) EstimateReclaim(snapshots []*models.Snapshot) (uint64, error) {
+	if len(snapshots) == 0 {
+		return 0, nil
+	}
+
+	names := make([]string, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		if snapshot.FilesystemName != snapshots[0].FilesystemName {
+			return 0, fmt.Errorf("snapshots of %s and %s cannot be estimated together", snapshots[0].FilesystemName, snapshot.FilesystemName)
+		}
+		names = append(names, snapshot.SnapshotName)
+	}
+
+	if m.config.Mode == "test" {
+		return m.runDestroyDryRun(m.config.ZFSDestroyDryRunCmd)
+	}
+
+	var reclaim uint64
+	for _, batch := range batchArgs(names, commandArgBytes-len(snapshots[0].FilesystemName)-1) {
+		snapshotPath := fmt.Sprintf("%s@%s", snapshots[0].FilesystemName, strings.Join(batch, ","))
+		batchReclaim, err := m.runDestroyDryRun(append(append([]string{}, m.config.ZFSDestroyDryRunCmd...), snapshotPath))
+		if err != nil {
+			return 0, err
+		}
+		reclaim += batchReclaim
+	}
+	return reclaim, nil
+}
+
+// runDestroyDryRun runs a zfs destroy -nvp command and returns the space it would reclaim
+func (m *Manager) runDestroyDryRun(cmdArgs []string) (uint64, error) {
+	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	m.logCommand(cmdArgs)
+
+	output, err := cmd.CombinedOutput()
+	exitCode := 0
+	if err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			exitCode = exitError.ExitCode()
+		}
+		m.logCommandResult(exitCode, output, nil)
+		return 0, fmt.Errorf("command failed: %w, output: %s", err, string(output))
+	}
+	m.logCommandResult(0, output, nil)
+
+	return parser.ParseDestroyDryRun(output)
+}
+
+// commandArgBytes is the size up to which snapshot names are passed to a single command
+// Linux limits a single argument to 128 KiB and all arguments together to ARG_MAX, so long
+// snapshot lists are split into several commands
+var commandArgBytes = 64 * 1024
+
+// batchArgs splits values into batches whose lengths, with one separator each, stay within maxBytes
+// A value longer than maxBytes gets a batch of its own
+func batchArgs(values []string, maxBytes int) [][]string {
+	var batches [][]string
+	var batch []string
+	size := 0
+	for _, value := range values {
+		if len(batch) > 0 && size+len(value)+1 > maxBytes {
+			batches = append(batches, batch)
+			batch, size = nil, 0
+		}
+		batch = append(batch, value)
+		size += len(value) + 1
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// HoldSnapshot places a hold with the given tag on a snapshot (zfs hold)
+func (m *Manager) HoldSnapshot(snapshot *models.Snapshot, tag string) error {
+	klog.Infof("Placing hold %s on snapshot %s", tag, snapshot.SnapshotName)
+	return m.runHoldCommand(m.config.ZFSHoldCmd, tag, snapshot)
+}
+
+// ReleaseSnapshot releases the hold with the given tag from a snapshot (zfs release)
+func (m *Manager) ReleaseSnapshot(snapshot *models.Snapshot, tag string) error {
+	klog.Infof("Releasing hold %s from snapshot %s", tag, snapshot.SnapshotName)
+	return m.runHoldCommand(m.config.ZFSReleaseCmd, tag, snapshot)
+}
+
+// runHoldCommand runs zfs hold or zfs release with a tag for a snapshot
+func (m *Manager) runHoldCommand(baseCmd []string, tag string, snapshot *models.Snapshot) error {
+	var cmdArgs []string
+	if m.config.Mode == "test" {
+		cmdArgs = baseCmd
+	} else {
+		snapshotPath := fmt.Sprintf("%s@%s", snapshot.FilesystemName, snapshot.SnapshotName)
+		cmdArgs = append(append([]string{}, baseCmd...), tag, snapshotPath)
+	}
+	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	m.logCommand(cmdArgs)
+
+	output, err := cmd.CombinedOutput()
+	exitCode := 0
+	if err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			exitCode = exitError.ExitCode()
+		}
+		m.logCommandResult(exitCode, output, nil)
+		return fmt.Errorf("command failed: %w, output: %s", err, string(output))
+	}
+	m.logCommandResult(0, output, nil)
+
+	return nil
+}
+
+// GetHolds lists the hold tags of the given snapshots with zfs holds -H -p
+// Long snapshot lists are split into several commands (see commandArgBytes)
+func (m *Manager) GetHolds(snapshots []*models.Snapshot) ([]*models.Hold, error) {
+	if len(snapshots) == 0 {
+		return nil, nil
+	}
+
+	if m.config.Mode == "test" {
+		return m.runHolds(m.config.ZFSHoldsCmd)
+	}
+
+	snapshotPaths := make([]string, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		snapshotPaths = append(snapshotPaths, fmt.Sprintf("%s@%s", snapshot.FilesystemName, snapshot.SnapshotName))
+	}
+
+	var holds []*models.Hold
+	for _, batch := range batchArgs(snapshotPaths, commandArgBytes) {
+		batchHolds, err := m.runHolds(append(append([]string{}, m.config.ZFSHoldsCmd...), batch...))
+		if err != nil {
+			return nil, err
+		}
+		holds = append(holds, batchHolds...)
+	}
+	return holds, nil
+}
+
+// runHolds runs a zfs holds command and parses the holds it lists
+func (m *Manager) runHolds(cmdArgs []string) ([]*models.Hold, error) {
+	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	m.logCommand(cmdArgs)
+
+	output, err := cmd.CombinedOutput()
+	exitCode := 0
+	if err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			exitCode = exitError.ExitCode()
+		}
+		m.logCommandResult(exitCode, output, nil)
+		return nil, fmt.Errorf("command failed: %w, output: %s", err, string(output))
+	}
+	m.logCommandResult(0, output, nil)
+
+	holds, err := parser.ParseHolds(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse holds: %w", err)
+	}
+	return holds, nil
+}
+
 // IsSnapshotRecent checks if a snapshot is from the current time period for the given frequency
 // This ensures we create one snapshot per period (hour, day, week, etc.) regardless of exact timing
 func (m *Manager) IsSnapshotRecent(snapshot *models.Snapshot, frequency string, now time.Time) bool {
-	if snapshot.Frequency == "" || snapshot.Frequency != frequency {
+	return IsSnapshotRecent(snapshot, frequency, now)
+}
+
+// IsSnapshotRecent checks if a snapshot is from the current time period for the given frequency
+// It does not depend on a backend and can be used with any Backend implementation
+// Only the built-in frequencies are known here, use IsSnapshotInPeriod for custom tiers
+func IsSnapshotRecent(snapshot *models.Snapshot, frequency string, now time.Time) bool {
+	tier, ok := config.BuiltinTier(frequency)
+	if !ok {
+		tier = config.Tier{Name: frequency}
+	}
+	return IsSnapshotInPeriod(snapshot, tier, now)
+}
+
+// IsSnapshotInPeriod checks if a snapshot of the tier is from the period containing now
+func IsSnapshotInPeriod(snapshot *models.Snapshot, tier config.Tier, now time.Time) bool {
+	if snapshot.Frequency == "" || snapshot.Frequency != tier.Name {
 		return false
 	}
 
 	// Check if the snapshot is from the same time period as "now"
 	// This is more reliable than duration-based checks which can skip periods
 	// due to timing variations in cronjob execution
-	snapshotPeriod := GetTimePeriodKey(snapshot.DateTime, frequency)
-	currentPeriod := GetTimePeriodKey(now, frequency)
+	// Both times are bucketed in the time zone of now
+	snapshotPeriod := tier.PeriodKey(snapshot.DateTime.In(now.Location()))
+	currentPeriod := tier.PeriodKey(now)
 
 	return snapshotPeriod == currentPeriod
 }
 
-// GetTimePeriodKey returns a unique key for the time period based on frequency
+// GetTimePeriodKey returns a unique key for the time period of a built-in frequency (see config.Tier.PeriodKey)
+// Periods are calendar periods in the location of t, so callers convert t to the configured time zone first
 func GetTimePeriodKey(t time.Time, frequency string) string {
-	switch frequency {
-	case "frequently":
-		// Group by 15-minute intervals: "2026-01-25 14:00" (rounds down to nearest 15 min)
-		year, month, day := t.Date()
-		hour := t.Hour()
-		minute := (t.Minute() / 15) * 15
-		return fmt.Sprintf("%d-%02d-%02d %02d:%02d", year, month, day, hour, minute)
-	case "hourly":
-		// Group by hour: "2026-01-25 14"
-		return t.Format("2006-01-02 15")
-	case "daily":
-		// Group by day: "2026-01-25"
-		return t.Format("2006-01-02")
-	case "weekly":
-		// Group by ISO week: "2026-W04"
-		year, week := t.ISOWeek()
-		return fmt.Sprintf("%d-W%02d", year, week)
-	case "monthly":
-		// Group by month: "2026-01"
-		return t.Format("2006-01")
-	case "yearly":
-		// Group by year: "2026"
-		return t.Format("2006")
-	default:
-		return t.Format("2006-01-02 15:04:05")
-	}
+	tier, _ := config.BuiltinTier(frequency)
+	return tier.PeriodKey(t)
+}
+
+// GetNextPeriodStart returns the start of the time period of a built-in frequency following the one containing t
+// The returned time is the first instant for which GetTimePeriodKey yields a new key
+func GetNextPeriodStart(t time.Time, frequency string) time.Time {
+	tier, _ := config.BuiltinTier(frequency)
+	return tier.NextPeriodStart(t)
 }
 
 // CanSnapshotBeDeleted checks if a snapshot can be deleted based on frequency and age
@@ -287,6 +517,12 @@ func (m *Manager) GetPoolStatus() (map[string]*models.PoolStatus, error) {
 
 // IsPoolHealthy checks if a pool is healthy and safe for operations
 func (m *Manager) IsPoolHealthy(poolName string, poolStatus map[string]*models.PoolStatus) bool {
+	return IsPoolHealthy(poolName, poolStatus)
+}
+
+// IsPoolHealthy checks if a pool is healthy and safe for operations
+// It does not depend on a backend and can be used with any Backend implementation
+func IsPoolHealthy(poolName string, poolStatus map[string]*models.PoolStatus) bool {
 	status, exists := poolStatus[poolName]
 	if !exists {
 		klog.Infof("Warning: No status found for pool %s", poolName)
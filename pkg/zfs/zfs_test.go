@@ -1,10 +1,12 @@
 package zfs
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
@@ -693,14 +695,24 @@ func TestSnapshotDeletionSafety(t *testing.T) {
 			description: "Weekly snapshot from current week must be kept",
 		},
 		{
-			name: "snapshot at exact retention boundary - MUST keep",
+			name: "snapshot in oldest retained period - MUST keep",
 			snapshot: &models.Snapshot{
-				DateTime:  now.Add(-time.Duration(cfg.MaxHourlySnapshots) * time.Hour),
+				DateTime:  now.Add(-time.Duration(cfg.MaxHourlySnapshots-1) * time.Hour),
 				Frequency: "hourly",
 			},
 			frequency:   "hourly",
 			shouldKeep:  true,
-			description: "Snapshot at retention boundary should be kept",
+			description: "Snapshot in the oldest of the last N periods should be kept",
+		},
+		{
+			name: "snapshot one period beyond retention - can delete",
+			snapshot: &models.Snapshot{
+				DateTime:  now.Add(-time.Duration(cfg.MaxHourlySnapshots) * time.Hour),
+				Frequency: "hourly",
+			},
+			frequency:   "hourly",
+			shouldKeep:  false,
+			description: "Retention keeps exactly N periods including the current one",
 		},
 		{
 			name: "snapshot beyond retention period - can delete",
@@ -815,6 +827,25 @@ func TestCreateSnapshot(t *testing.T) {
 	}
 }
 
+func TestCreateSnapshots(t *testing.T) {
+	cfg := config.NewConfig("test")
+	manager := NewManager(cfg)
+
+	snapshots := []*models.Snapshot{
+		{PoolName: "tank", FilesystemName: "tank/db", SnapshotName: "autosnap_2026-01-25_15:00:00_hourly", Frequency: "hourly"},
+		{PoolName: "tank", FilesystemName: "tank/db/wal", SnapshotName: "autosnap_2026-01-25_15:00:00_hourly", Frequency: "hourly"},
+	}
+
+	if err := manager.CreateSnapshots(snapshots); err != nil {
+		t.Errorf("CreateSnapshots() failed: %v", err)
+	}
+
+	cfg.ZFSCreateSnapshotCmd = []string{"false"}
+	if err := manager.CreateSnapshots(snapshots); err == nil {
+		t.Error("CreateSnapshots() should fail when the command fails")
+	}
+}
+
 func TestDeleteSnapshot(t *testing.T) {
 	cfg := config.NewConfig("test")
 	manager := NewManager(cfg)
@@ -871,6 +902,199 @@ func TestDeleteSnapshotWithInvalidCommand(t *testing.T) {
 	}
 }
 
+func TestEstimateReclaim(t *testing.T) {
+	if err := changeToProjectRoot(); err != nil {
+		t.Skipf("Could not change to project root: %v", err)
+	}
+
+	manager := NewManager(config.NewConfig("test"))
+	snapshots := []*models.Snapshot{
+		{FilesystemName: "tank/data", SnapshotName: "autosnap_2026-01-25_10:00:00_hourly"},
+		{FilesystemName: "tank/data", SnapshotName: "autosnap_2026-01-25_11:00:00_hourly"},
+	}
+
+	reclaim, err := manager.EstimateReclaim(snapshots)
+	if err != nil {
+		t.Fatalf("EstimateReclaim() error = %v", err)
+	}
+	if reclaim != 1048576 {
+		t.Errorf("EstimateReclaim() = %d, want 1048576", reclaim)
+	}
+
+	// zfs destroy only accepts a snapshot list of a single dataset
+	snapshots = append(snapshots, &models.Snapshot{FilesystemName: "tank/other", SnapshotName: "autosnap_2026-01-25_11:00:00_hourly"})
+	if _, err := manager.EstimateReclaim(snapshots); err == nil {
+		t.Error("EstimateReclaim() expected an error for snapshots of different datasets")
+	}
+}
+
+// TestEstimateReclaimBatches tests that a long snapshot list is estimated with several commands and summed
+func TestEstimateReclaimBatches(t *testing.T) {
+	limit := commandArgBytes
+	commandArgBytes = 100
+	t.Cleanup(func() { commandArgBytes = limit })
+
+	cfg := config.NewConfig("direct")
+	// Every command reclaims the number of snapshots it was given and fails for a too long argument
+	cfg.ZFSDestroyDryRunCmd = []string{"sh", "-c", `test ${#0} -le 100 && echo "$0" | awk -F, '{print "reclaim", NF}'`}
+	manager := NewManager(cfg)
+
+	var snapshots []*models.Snapshot
+	for hour := 10; hour < 15; hour++ {
+		snapshots = append(snapshots, &models.Snapshot{
+			FilesystemName: "tank/data",
+			SnapshotName:   fmt.Sprintf("autosnap_2026-01-25_%d:00:00_hourly", hour),
+		})
+	}
+
+	reclaim, err := manager.EstimateReclaim(snapshots)
+	if err != nil {
+		t.Fatalf("EstimateReclaim() error = %v", err)
+	}
+	if reclaim != 5 {
+		t.Errorf("EstimateReclaim() = %d, want 5", reclaim)
+	}
+}
+
+func TestBatchArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []string
+		maxBytes int
+		want     [][]string
+	}{
+		{name: "empty", maxBytes: 10},
+		{name: "single batch", values: []string{"aa", "bb", "cc"}, maxBytes: 9, want: [][]string{{"aa", "bb", "cc"}}},
+		{name: "split", values: []string{"aa", "bb", "cc"}, maxBytes: 8, want: [][]string{{"aa", "bb"}, {"cc"}}},
+		{name: "value longer than the limit", values: []string{"aaaa", "b"}, maxBytes: 3, want: [][]string{{"aaaa"}, {"b"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := batchArgs(tt.values, tt.maxBytes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("batchArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteSnapshotRetained(t *testing.T) {
+	snapshot := &models.Snapshot{
+		PoolName:       "tank",
+		FilesystemName: "tank/data",
+		SnapshotName:   "autosnap_2020-01-01_00:00:00_yearly",
+		Frequency:      "yearly",
+	}
+
+	tests := []struct {
+		name         string
+		output       string
+		wantRetained bool
+	}{
+		{"hold", "cannot destroy snapshot tank/data@autosnap_2020-01-01_00:00:00_yearly: dataset is busy", true},
+		{"clone", "cannot destroy 'tank/data@autosnap_2020-01-01_00:00:00_yearly': snapshot has dependent clones", true},
+		{"other error", "cannot open 'tank/data@autosnap_2020-01-01_00:00:00_yearly': dataset does not exist", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewConfig("test")
+			cfg.ZFSDeleteSnapshotCmd = []string{"sh", "-c", "echo \"$0\" >&2; exit 1", tt.output}
+			manager := NewManager(cfg)
+
+			err := manager.DeleteSnapshot(snapshot)
+			if err == nil {
+				t.Fatal("DeleteSnapshot() should have failed")
+			}
+			if errors.Is(err, ErrSnapshotRetained) != tt.wantRetained {
+				t.Errorf("DeleteSnapshot() error = %v, want retained %v", err, tt.wantRetained)
+			}
+		})
+	}
+}
+
+func TestHolds(t *testing.T) {
+	if err := changeToProjectRoot(); err != nil {
+		t.Skipf("Could not change to project root: %v", err)
+	}
+
+	manager := NewManager(config.NewConfig("test"))
+	snapshot := &models.Snapshot{FilesystemName: "usbstorage/private", SnapshotName: "autosnap_2024-01-15_00:00:00_daily"}
+
+	if err := manager.HoldSnapshot(snapshot, "zfs-snapshot-operator:replication"); err != nil {
+		t.Errorf("HoldSnapshot() error = %v", err)
+	}
+	if err := manager.ReleaseSnapshot(snapshot, "zfs-snapshot-operator:replication"); err != nil {
+		t.Errorf("ReleaseSnapshot() error = %v", err)
+	}
+
+	holds, err := manager.GetHolds([]*models.Snapshot{snapshot})
+	if err != nil {
+		t.Fatalf("GetHolds() error = %v", err)
+	}
+	if len(holds) != 1 {
+		t.Fatalf("GetHolds() returned %d hold(s), want 1", len(holds))
+	}
+	if holds[0].Tag != "zfs-snapshot-operator:replication" || holds[0].SnapshotName != snapshot.SnapshotName {
+		t.Errorf("GetHolds() = %+v, want the replication hold of %s", holds[0], snapshot.SnapshotName)
+	}
+	if !holds[0].Time.Equal(time.Unix(1705276800, 0)) {
+		t.Errorf("hold time = %s, want %s", holds[0].Time, time.Unix(1705276800, 0))
+	}
+
+	// Nothing to list without held snapshots
+	if holds, err := manager.GetHolds(nil); err != nil || holds != nil {
+		t.Errorf("GetHolds(nil) = %v, %v, want nil, nil", holds, err)
+	}
+}
+
+// TestGetHoldsBatches tests that the holds of a long snapshot list are listed with several commands
+func TestGetHoldsBatches(t *testing.T) {
+	limit := commandArgBytes
+	commandArgBytes = 100
+	t.Cleanup(func() { commandArgBytes = limit })
+
+	cfg := config.NewConfig("direct")
+	// Every command lists one hold per snapshot and fails for too long arguments
+	cfg.ZFSHoldsCmd = []string{"sh", "-c", `test $(echo "$0 $*" | wc -c) -le 100 && for s in "$0" "$@"; do printf '%s\tzfs-snapshot-operator:replication\t1705276800\n' "$s"; done`}
+	manager := NewManager(cfg)
+
+	var snapshots []*models.Snapshot
+	for hour := 10; hour < 15; hour++ {
+		snapshots = append(snapshots, &models.Snapshot{
+			FilesystemName: "tank/data",
+			SnapshotName:   fmt.Sprintf("autosnap_2026-01-25_%d:00:00_hourly", hour),
+		})
+	}
+
+	holds, err := manager.GetHolds(snapshots)
+	if err != nil {
+		t.Fatalf("GetHolds() error = %v", err)
+	}
+	if len(holds) != len(snapshots) {
+		t.Fatalf("GetHolds() returned %d hold(s), want %d", len(holds), len(snapshots))
+	}
+	for i, hold := range holds {
+		if hold.SnapshotName != snapshots[i].SnapshotName {
+			t.Errorf("holds[%d] is on %s, want %s", i, hold.SnapshotName, snapshots[i].SnapshotName)
+		}
+	}
+}
+
+func TestHoldSnapshotArgs(t *testing.T) {
+	cfg := config.NewConfig("direct")
+	// zfs hold expects the tag before the snapshot
+	cfg.ZFSHoldCmd = []string{"sh", "-c", `test "$0 $1" = "zfs-snapshot-operator:replication tank/data@snap"`}
+	manager := NewManager(cfg)
+
+	snapshot := &models.Snapshot{FilesystemName: "tank/data", SnapshotName: "snap"}
+	if err := manager.HoldSnapshot(snapshot, "zfs-snapshot-operator:replication"); err != nil {
+		t.Errorf("HoldSnapshot() error = %v", err)
+	}
+	if err := manager.HoldSnapshot(snapshot, "other"); err == nil {
+		t.Error("HoldSnapshot() expected the command to fail for another tag")
+	}
+}
+
 // changeToProjectRoot changes to the project root directory for tests
 func changeToProjectRoot() error {
 	// Get current working directory